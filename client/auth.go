@@ -0,0 +1,60 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// An Authenticator adds credentials to requests sent to the jwtblock API.
+type Authenticator interface {
+	Authenticate(r *http.Request) error
+}
+
+// AuthFunc adapts a function into an Authenticator.
+type AuthFunc func(r *http.Request) error
+
+// Authenticate calls f(r).
+func (f AuthFunc) Authenticate(r *http.Request) error {
+	return f(r)
+}
+
+// ApiKey authenticates with an admin API key in the default API key header.
+func ApiKey(key string) Authenticator {
+	return ApiKeyHeader(DefaultHeaderApiKey, key)
+}
+
+// ApiKeyHeader authenticates with an admin API key in the named header.
+func ApiKeyHeader(header, key string) Authenticator {
+	return AuthFunc(func(r *http.Request) error {
+		r.Header.Set(header, key)
+		return nil
+	})
+}
+
+// BearerToken authenticates with a bearer token in the Proxy-Authorization header, for a proxy in front of the jwtblock service.
+//
+// The Authorization header is left to the token being checked, blocked or
+// unblocked, which the service reads before the hash header.
+func BearerToken(token string) Authenticator {
+	return BearerTokenHeader(DefaultHeaderProxyAuth, token)
+}
+
+// BearerTokenHeader authenticates with a bearer token in the named header, for a proxy in front of the jwtblock service.
+func BearerTokenHeader(header, token string) Authenticator {
+	return AuthFunc(func(r *http.Request) error {
+		r.Header.Set(header, fmt.Sprintf("Bearer %s", token))
+		return nil
+	})
+}
+
+// MultiAuth combines authenticators, applied in order.
+func MultiAuth(auths ...Authenticator) Authenticator {
+	return AuthFunc(func(r *http.Request) error {
+		for _, auth := range auths {
+			if err := auth.Authenticate(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Check if a token is blocked.
+func (c *Client) Check(ctx context.Context, tokenString string) (CheckResult, error) {
+	var result CheckResult
+	err := c.do(ctx, http.MethodGet, "/blocklist/check", c.tokenHeaders(tokenString), &result)
+	return result, err
+}
+
+// CheckSha256 checks if the hash of a token is blocked.
+func (c *Client) CheckSha256(ctx context.Context, sha256 string) (CheckResult, error) {
+	var result CheckResult
+	err := c.do(ctx, http.MethodGet, "/blocklist/check", c.sha256Headers(sha256), &result)
+	return result, err
+}
+
+// Block adds a token to the blocklist, with the TTL determined by the service.
+func (c *Client) Block(ctx context.Context, tokenString string) (*BlockResult, error) {
+	return c.BlockWithTTL(ctx, tokenString, -1)
+}
+
+// BlockWithTTL adds a token to the blocklist with an explicit TTL in seconds.
+//
+// A TTL below zero lets the service determine it. Explicit TTLs require admin authentication.
+//
+// Blocking is not retried, since a retry after a lost response would report
+// the token as already blocked, and audit the block twice.
+func (c *Client) BlockWithTTL(ctx context.Context, tokenString string, ttlSeconds int) (*BlockResult, error) {
+	result := &BlockResult{}
+	err := c.withRetryPolicy(NoRetry()).do(ctx, http.MethodPost, "/blocklist/block", c.ttlHeaders(c.tokenHeaders(tokenString), ttlSeconds), result)
+	return result, err
+}
+
+// BlockSha256 adds the hash of a token to the blocklist, with an explicit TTL in seconds.
+//
+// A TTL below zero uses the default TTL of the service. Requires admin authentication.
+// Like BlockWithTTL, it is not retried.
+func (c *Client) BlockSha256(ctx context.Context, sha256 string, ttlSeconds int) (*BlockResult, error) {
+	result := &BlockResult{}
+	err := c.withRetryPolicy(NoRetry()).do(ctx, http.MethodPost, "/blocklist/block", c.ttlHeaders(c.sha256Headers(sha256), ttlSeconds), result)
+	return result, err
+}
+
+// Unblock removes a token from the blocklist. Requires admin authentication.
+//
+// Unblocking is not retried, since a retry after a lost response would report
+// the token as not blocked, and audit the unblock twice.
+func (c *Client) Unblock(ctx context.Context, tokenString string) (*UnblockResult, error) {
+	result := &UnblockResult{}
+	err := c.withRetryPolicy(NoRetry()).do(ctx, http.MethodPost, "/blocklist/unblock", c.tokenHeaders(tokenString), result)
+	return result, err
+}
+
+// UnblockSha256 removes the hash of a token from the blocklist. Requires admin authentication.
+// Like Unblock, it is not retried.
+func (c *Client) UnblockSha256(ctx context.Context, sha256 string) (*UnblockResult, error) {
+	result := &UnblockResult{}
+	err := c.withRetryPolicy(NoRetry()).do(ctx, http.MethodPost, "/blocklist/unblock", c.sha256Headers(sha256), result)
+	return result, err
+}
+
+// List the hashes of all blocked tokens. Requires admin authentication.
+func (c *Client) List(ctx context.Context) (*ListResult, error) {
+	result := &ListResult{}
+	err := c.do(ctx, http.MethodGet, "/blocklist/list", nil, result)
+	return result, err
+}
+
+// Flush empties the blocklist. Requires admin authentication.
+//
+// Flushing is not retried, since a retry after a lost response would report a count of zero.
+func (c *Client) Flush(ctx context.Context) (*FlushResult, error) {
+	result := &FlushResult{}
+	err := c.withRetryPolicy(NoRetry()).do(ctx, http.MethodPost, "/blocklist/flush", nil, result)
+	return result, err
+}
+
+// Status gets the status of the blocklist. Requires admin authentication.
+func (c *Client) Status(ctx context.Context) (*StatusResult, error) {
+	result := &StatusResult{}
+	err := c.do(ctx, http.MethodGet, "/blocklist/status", nil, result)
+	return result, err
+}
+
+func (c *Client) tokenHeaders(tokenString string) http.Header {
+	headers := make(http.Header)
+	headers.Set("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	return headers
+}
+
+func (c *Client) sha256Headers(sha256 string) http.Header {
+	headers := make(http.Header)
+	headers.Set(c.headerSha256, sha256)
+	return headers
+}
+
+func (c *Client) ttlHeaders(headers http.Header, ttlSeconds int) http.Header {
+	if ttlSeconds >= 0 {
+		headers.Set(c.headerTTL, strconv.Itoa(ttlSeconds))
+	}
+	return headers
+}
+
+// Get a copy of the client with a different retry policy.
+func (c *Client) withRetryPolicy(retry RetryPolicy) *Client {
+	clone := *c
+	clone.retry = retry
+	return &clone
+}
+
+// Send a request, with retries, and decode the JSON response body into out.
+func (c *Client) do(ctx context.Context, method, path string, headers http.Header, out interface{}) error {
+	var err error
+
+	attempts := c.retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			if waitErr := c.retry.wait(ctx, attempt-1); waitErr != nil {
+				return waitErr
+			}
+		}
+
+		var retryable bool
+		retryable, err = c.doOnce(ctx, method, path, headers, out)
+		if err == nil || !retryable || ctx.Err() != nil {
+			return err
+		}
+	}
+
+	return fmt.Errorf("%w: %w", ErrRetryAttemptsReached, err)
+}
+
+// Send a single request attempt. Returns whether a failed attempt can be retried.
+func (c *Client) doOnce(ctx context.Context, method, path string, headers http.Header, out interface{}) (bool, error) {
+	request, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
+	if err != nil {
+		return false, err
+	}
+	for name, values := range c.headers {
+		request.Header[name] = values
+	}
+	for name, values := range headers {
+		request.Header[name] = values
+	}
+	if c.auth != nil {
+		if err = c.auth.Authenticate(request); err != nil {
+			return false, err
+		}
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return true, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return true, err
+	}
+
+	// Error responses share the message and error fields of every result.
+	var standard struct {
+		Message string `json:"message"`
+		IsError bool   `json:"error"`
+	}
+	if err = json.Unmarshal(body, &standard); err != nil {
+		err = fmt.Errorf("%w: status=%d", ErrUnexpectedResponse, response.StatusCode)
+		return isRetryableStatus(response.StatusCode), err
+	}
+	if standard.IsError || isRetryableStatus(response.StatusCode) {
+		err = &Error{StatusCode: response.StatusCode, Message: standard.Message}
+		return isRetryableStatus(response.StatusCode), err
+	}
+
+	return false, json.Unmarshal(body, out)
+}
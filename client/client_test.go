@@ -0,0 +1,292 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"go/build"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/blocklist"
+	"github.com/divergentcodes/jwtblock/internal/cache"
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+	"github.com/divergentcodes/jwtblock/web"
+)
+
+func Test_Client_Imports_NoInternalPackages(t *testing.T) {
+	const module = "github.com/divergentcodes/jwtblock"
+	seen := map[string]bool{}
+	pending := []string{module + "/client"}
+	for len(pending) > 0 {
+		path := pending[0]
+		pending = pending[1:]
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		if strings.HasPrefix(path, module+"/internal/") {
+			t.Fatalf("Expected no internal packages, got %s", path)
+		}
+
+		pkg, err := build.Import(path, ".", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, imported := range pkg.Imports {
+			if strings.HasPrefix(imported, module+"/") {
+				pending = append(pending, imported)
+			}
+		}
+	}
+}
+
+func Test_Client_BlockCheckUnblock_Success(t *testing.T) {
+	server := setupServer(t)
+	ctx := context.Background()
+
+	c, err := New(server.URL, WithAuth(ApiKey("foo")))
+	if err != nil {
+		t.Fatalf("Failed to create client: err=%s", err)
+	}
+	tokenString := generateTokenStringHS256(60)
+
+	// Allowed before blocking.
+	checkResult, err := c.Check(ctx, tokenString)
+	if err != nil || checkResult.IsBlocked || checkResult.Message != blocklist.SuccessTokenIsAllowed {
+		t.Errorf("Expected token to be allowed: result=%+v, err=%s", checkResult, err)
+	}
+
+	// Block.
+	blockResult, err := c.Block(ctx, tokenString)
+	if err != nil || !blockResult.IsNew || blockResult.Message != blocklist.SuccessTokenBlocked {
+		t.Errorf("Expected token to be blocked: result=%+v, err=%s", blockResult, err)
+	}
+
+	// Blocked, by token and by hash.
+	checkResult, err = c.Check(ctx, tokenString)
+	if err != nil || !checkResult.IsBlocked {
+		t.Errorf("Expected token to be blocked: result=%+v, err=%s", checkResult, err)
+	}
+	checkResult, err = c.CheckSha256(ctx, crypto.Sha256FromString(tokenString))
+	if err != nil || !checkResult.IsBlocked {
+		t.Errorf("Expected hash to be blocked: result=%+v, err=%s", checkResult, err)
+	}
+
+	// Status and list.
+	statusResult, err := c.Status(ctx)
+	if err != nil || statusResult.Size != 1 {
+		t.Errorf("Expected blocklist size of 1: result=%+v, err=%s", statusResult, err)
+	}
+	listResult, err := c.List(ctx)
+	if err != nil || len(listResult.TokenHashes) != 1 {
+		t.Errorf("Expected one listed hash: result=%+v, err=%s", listResult, err)
+	}
+
+	// Unblock.
+	unblockResult, err := c.Unblock(ctx, tokenString)
+	if err != nil || !unblockResult.IsUnblocked {
+		t.Errorf("Expected token to be unblocked: result=%+v, err=%s", unblockResult, err)
+	}
+	checkResult, err = c.Check(ctx, tokenString)
+	if err != nil || checkResult.IsBlocked {
+		t.Errorf("Expected token to be allowed: result=%+v, err=%s", checkResult, err)
+	}
+}
+
+func Test_Client_Sha256WithBearerToken_HashOperated(t *testing.T) {
+	server := setupServer(t)
+	ctx := context.Background()
+
+	c, _ := New(server.URL, WithAuth(MultiAuth(ApiKey("foo"), BearerToken("proxy-credential"))))
+	sha256 := crypto.Sha256FromString(generateTokenStringHS256(60))
+
+	// The proxy credential is not read as the token being operated on.
+	blockResult, err := c.BlockSha256(ctx, sha256, 60)
+	if err != nil || !blockResult.IsNew {
+		t.Errorf("Expected hash to be blocked: result=%+v, err=%s", blockResult, err)
+	}
+	checkResult, err := c.CheckSha256(ctx, sha256)
+	if err != nil || !checkResult.IsBlocked {
+		t.Errorf("Expected hash to be blocked: result=%+v, err=%s", checkResult, err)
+	}
+	unblockResult, err := c.UnblockSha256(ctx, sha256)
+	if err != nil || !unblockResult.IsUnblocked {
+		t.Errorf("Expected hash to be unblocked: result=%+v, err=%s", unblockResult, err)
+	}
+}
+
+func Test_BearerToken_Request_ProxyAuthorizationSet(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/blocklist/check", nil)
+	if err := BearerToken("proxy-credential").Authenticate(request); err != nil {
+		t.Fatal(err)
+	}
+	if actual := request.Header.Get("Proxy-Authorization"); actual != "Bearer proxy-credential" {
+		t.Errorf("Expected the proxy credential in Proxy-Authorization, got %q", actual)
+	}
+	if actual := request.Header.Get("Authorization"); actual != "" {
+		t.Errorf("Expected no Authorization header, got %q", actual)
+	}
+}
+
+func Test_Client_AdminWithoutApiKey_Error(t *testing.T) {
+	server := setupServer(t)
+
+	c, _ := New(server.URL)
+	_, err := c.Status(context.Background())
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected unauthorized API error: err=%s", err)
+	}
+}
+
+func Test_Client_InvalidToken_Error(t *testing.T) {
+	server := setupServer(t)
+
+	c, _ := New(server.URL)
+	_, err := c.Block(context.Background(), "foobar")
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected bad request API error: err=%s", err)
+	}
+}
+
+func Test_Client_RetryServerError_Success(t *testing.T) {
+	// Fail the first two attempts.
+	var attempts int32
+	server := setupServerWithMiddleware(t, func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&attempts, 1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		})
+	})
+
+	retry := RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	c, _ := New(server.URL, WithRetryPolicy(retry))
+	_, err := c.Check(context.Background(), generateTokenStringHS256(60))
+	if err != nil || atomic.LoadInt32(&attempts) != 3 {
+		t.Errorf("Expected success on the third attempt: attempts=%d, err=%s", attempts, err)
+	}
+
+	// Exhaust the attempts.
+	atomic.StoreInt32(&attempts, -10)
+	_, err = c.Check(context.Background(), generateTokenStringHS256(60))
+	if !errors.Is(err, ErrRetryAttemptsReached) {
+		t.Errorf("Expected ErrRetryAttemptsReached: err=%s", err)
+	}
+}
+
+func Test_Client_BlockServerError_NotRetried(t *testing.T) {
+	var attempts int32
+	server := setupServerWithMiddleware(t, func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+	})
+
+	retry := RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	c, _ := New(server.URL, WithRetryPolicy(retry))
+	if _, err := c.Block(context.Background(), generateTokenStringHS256(60)); err == nil {
+		t.Error("Expected the block to fail")
+	}
+	if _, err := c.UnblockSha256(context.Background(), crypto.Sha256FromString("foo")); err == nil {
+		t.Error("Expected the unblock to fail")
+	}
+	if actual := atomic.LoadInt32(&attempts); actual != 2 {
+		t.Errorf("Expected one attempt per mutation, got %d", actual)
+	}
+}
+
+func Test_Client_ContextCanceled_Error(t *testing.T) {
+	server := setupServerWithMiddleware(t, func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(50 * time.Millisecond)
+			handler.ServeHTTP(w, r)
+		})
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	c, _ := New(server.URL)
+	_, err := c.Check(ctx, generateTokenStringHS256(60))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context deadline error: err=%s", err)
+	}
+}
+
+func Test_New_InvalidBaseURL_Error(t *testing.T) {
+	_, err := New("localhost:4474")
+	if !errors.Is(err, ErrInvalidBaseURL) {
+		t.Errorf("Expected ErrInvalidBaseURL: err=%s", err)
+	}
+}
+
+// Run the jwtblock web handlers against an in-memory Redis.
+func setupServer(t *testing.T) *httptest.Server {
+	return setupServerWithMiddleware(t, func(handler http.Handler) http.Handler { return handler })
+}
+
+// Run the jwtblock web handlers wrapped by a middleware against an in-memory Redis.
+func setupServerWithMiddleware(t *testing.T, middleware func(http.Handler) http.Handler) *httptest.Server {
+	core.InitConfigDefaults()
+	viper.Set(core.OptStr_JwtParseEnabled, true)
+	viper.Set(core.OptStr_JwtValidateEnabled, true)
+	viper.Set(core.OptStr_JwtVerifyEnabled, false)
+	viper.Set(core.OptStr_HttpAdminEnabled, true)
+	viper.Set(core.OptStr_HttpAdminApiKeys, "foo")
+
+	redisServer := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: redisServer.Addr(),
+	})
+	cache.SetRedisClient(redisClient)
+
+	server := httptest.NewServer(middleware(web.Handler()))
+	t.Cleanup(func() {
+		server.Close()
+		redisClient.Close()
+	})
+	return server
+}
+
+func generateTokenStringHS256(ttlSeconds int) string {
+	// Generate the token headers.
+	tokenHeaders := jws.NewHeaders()
+	tokenHeaders.Set("typ", "JWT")
+
+	// Generate the token body, with the given EXP claim.
+	ttl := time.Duration(ttlSeconds) * time.Second
+	tokenBody, _ := jwt.NewBuilder().
+		Issuer(`some-issuer`).
+		Expiration(time.Now().Add(ttl)).
+		JwtID(time.Now().String()).
+		Build()
+	tokenBodyBytes, _ := jwt.NewSerializer().Serialize(tokenBody)
+
+	// Generate the signed, finished HS256 token.
+	key, _ := jwk.FromRaw([]byte(`foobar`))
+	tokenBytes, _ := jws.Sign(
+		tokenBodyBytes,
+		jws.WithKey(jwa.HS256, key, jws.WithProtectedHeaders(tokenHeaders)),
+	)
+	return string(tokenBytes)
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// A RetryPolicy controls how failed requests are retried.
+//
+// Requests are retried on network errors, 429 and 5xx responses, with
+// exponential backoff and full jitter between attempts. Only the read
+// operations, Check, List and Status, are retried. Mutations are sent once.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts, including the first. Values below 1 mean one attempt.
+	MinBackoff  time.Duration // backoff before the first retry.
+	MaxBackoff  time.Duration // upper bound of the backoff between attempts.
+}
+
+// DefaultRetryPolicy returns the retry policy of a new client.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  100 * time.Millisecond,
+		MaxBackoff:  2 * time.Second,
+	}
+}
+
+// NoRetry returns a retry policy that sends each request once.
+func NoRetry() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+// Check if a response status code should be retried.
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// Get the jittered backoff before the given retry attempt, starting at 1.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.MinBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+// Wait for the backoff before the given retry attempt, or until the context is done.
+func (p RetryPolicy) wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(p.backoff(attempt))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
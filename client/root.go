@@ -0,0 +1,179 @@
+// Package client is a Go client for the jwtblock HTTP API.
+//
+// Results have the same fields and JSON encoding as the API responses. The
+// package has no dependencies on the jwtblock service, so importing it never
+// reads the jwtblock configuration.
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Default settings for a new client, matching the jwtblock service defaults.
+const (
+	DefaultTimeout      = 10 * time.Second
+	DefaultHeaderSha256 = "x-jwtblock-sha256"
+	DefaultHeaderTTL    = "x-jwtblock-ttl"
+	DefaultHeaderApiKey = "x-jwtblock-api-key"
+
+	DefaultHeaderProxyAuth = "Proxy-Authorization"
+)
+
+// General error messages returned by the client.
+var (
+	ErrInvalidBaseURL       = errors.New("invalid jwtblock base URL")
+	ErrUnexpectedResponse   = errors.New("unexpected response from jwtblock server")
+	ErrRetryAttemptsReached = errors.New("maximum retry attempts reached")
+)
+
+// A CheckResult is the result of looking up a token in the blocklist.
+type CheckResult struct {
+	Message   string `json:"message"`       // message summarizing the result.
+	IsBlocked bool   `json:"blocked"`       // whether or not the token is blocked (present in the blocklist).
+	TTL       int    `json:"block_ttl_sec"` // remaining time-to-live of the token in the blocklist.
+	TTLString string `json:"block_ttl_str"` // human readable remaining time-to-live.
+	IsError   bool   `json:"error"`         // whether or not the result was an error.
+
+	IsFailOpen bool `json:"fail_open,omitempty"` // whether or not the token was allowed because the store is unavailable.
+}
+
+// A BlockResult is the result of adding a token to the blocklist.
+type BlockResult struct {
+	Message   string `json:"message"`       // message summarizing the result.
+	TTL       int    `json:"block_ttl_sec"` // remaining time-to-live of the token in the blocklist.
+	TTLString string `json:"block_ttl_str"` // human readable remaining time-to-live.
+	IsNew     bool   `json:"is_new"`        // whether or not the token is newly added to the blocklist.
+	IsError   bool   `json:"error"`         // whether or not the result was an error.
+}
+
+// An UnblockResult is the result of removing a token from the blocklist.
+type UnblockResult struct {
+	Message     string `json:"message"`   // message summarizing the result.
+	IsUnblocked bool   `json:"unblocked"` // whether or not the token was unblocked (removed from the blocklist).
+	IsError     bool   `json:"error"`     // whether or not the result was an error.
+}
+
+// A ListResult contains the hashes of the blocked tokens.
+type ListResult struct {
+	TokenHashes []string `json:"token_hashes"` // hashes of blocked tokens.
+	Size        int64    `json:"size"`         // the number of blocked tokens.
+	IsError     bool     `json:"error"`        // whether or not the result was an error.
+}
+
+// A FlushResult is the result of emptying the blocklist.
+type FlushResult struct {
+	Message string `json:"message"` // message summarizing the result.
+	Count   int64  `json:"count"`   // number of records flushed from the blocklist.
+	IsError bool   `json:"error"`   // whether or not the result was an error.
+}
+
+// A StatusResult contains the status of the blocklist.
+type StatusResult struct {
+	Size int64 `json:"size"` // the number of blocked tokens.
+}
+
+// An Error is an error response from the jwtblock API.
+type Error struct {
+	StatusCode int    // HTTP status code of the response.
+	Message    string // error message from the response body.
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// A Client calls the jwtblock HTTP API.
+//
+// A Client is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	auth       Authenticator
+	retry      RetryPolicy
+
+	headerSha256 string
+	headerTTL    string
+	headers      http.Header
+}
+
+// An Option configures a Client.
+type Option func(*Client)
+
+// New creates a client for the jwtblock service at the base URL.
+func New(baseURL string, options ...Option) (*Client, error) {
+	parsedURL, err := url.Parse(baseURL)
+	if err != nil || parsedURL.Scheme == "" || parsedURL.Host == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidBaseURL, baseURL)
+	}
+
+	c := &Client{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		httpClient:   &http.Client{Timeout: DefaultTimeout},
+		retry:        DefaultRetryPolicy(),
+		headerSha256: DefaultHeaderSha256,
+		headerTTL:    DefaultHeaderTTL,
+		headers:      make(http.Header),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c, nil
+}
+
+// WithHTTPClient sets the HTTP client used to send requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout sets the timeout of each HTTP request attempt.
+//
+// The overall time of a call, including retries, is bounded by its context.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		httpClient := *c.httpClient
+		httpClient.Timeout = timeout
+		c.httpClient = &httpClient
+	}
+}
+
+// WithAuth sets how requests are authenticated.
+func WithAuth(auth Authenticator) Option {
+	return func(c *Client) {
+		c.auth = auth
+	}
+}
+
+// WithRetryPolicy sets how failed requests are retried.
+func WithRetryPolicy(retry RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = retry
+	}
+}
+
+// WithHeaderSha256 sets the name of the header used to pass token hashes.
+func WithHeaderSha256(name string) Option {
+	return func(c *Client) {
+		c.headerSha256 = name
+	}
+}
+
+// WithHeaderTTL sets the name of the header used to pass an explicit TTL.
+func WithHeaderTTL(name string) Option {
+	return func(c *Client) {
+		c.headerTTL = name
+	}
+}
+
+// WithHeader sets a header sent with every request, e.g. the reason recorded in the audit log.
+func WithHeader(name, value string) Option {
+	return func(c *Client) {
+		c.headers.Set(name, value)
+	}
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/audit"
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+var (
+	// Used for flags.
+	auditSince  string
+	auditUntil  string
+	auditActor  string
+	auditHash   string
+	auditLimit  int
+	auditReason string
+
+	auditCmd = &cobra.Command{
+		Use:   "audit [--since <TIME>] [--until <TIME>] [--actor <ACTOR>] [--hash <HASH>] [--limit <N>]",
+		Short: "Query the audit log",
+		Long: `Query the audit log of blocklist mutations from the configured audit sink.
+
+TIME is an RFC 3339 timestamp (e.g. 2024-06-01T00:00:00Z), or a duration before now (e.g. 24h).`,
+		Args: cobra.NoArgs,
+		Run:  auditQuery,
+	}
+)
+
+func init() {
+	auditCmd.Flags().StringVar(&auditSince, "since", "", "Only show events at or after this time")
+	auditCmd.Flags().StringVar(&auditUntil, "until", "", "Only show events before this time")
+	auditCmd.Flags().StringVar(&auditActor, "actor", "", "Only show events by this actor")
+	auditCmd.Flags().StringVar(&auditHash, "hash", "", "Only show events for this token hash")
+	auditCmd.Flags().IntVar(&auditLimit, "limit", 100, "Show at most this many of the most recent events (0 for no limit)")
+
+	rootCmd.AddCommand(auditCmd)
+}
+
+func auditQuery(cmd *cobra.Command, args []string) {
+	ShowBanner()
+
+	filter := audit.Filter{
+		Actor: auditActor,
+		Hash:  auditHash,
+		Limit: auditLimit,
+	}
+	var err error
+	if filter.Since, err = parseAuditTime(auditSince); err != nil {
+		fmt.Printf("Error: invalid --since: %s\n", err.Error())
+		return
+	}
+	if filter.Until, err = parseAuditTime(auditUntil); err != nil {
+		fmt.Printf("Error: invalid --until: %s\n", err.Error())
+		return
+	}
+
+	events, err := audit.Query(filter)
+	if err != nil {
+		fmt.Printf("Error querying the audit log: %s\n", err.Error())
+		return
+	}
+
+	if viper.GetBool(core.OptStr_OutJSON) {
+		if events == nil {
+			events = []audit.Event{}
+		}
+		_ = json.NewEncoder(os.Stdout).Encode(events)
+		return
+	}
+	if len(events) == 0 {
+		fmt.Println("No events in the audit log")
+		return
+	}
+	for _, event := range events {
+		fmt.Printf(
+			"%s %s %s hash=%s actor=%s source_ip=%s reason=%q",
+			event.Time.Format(time.RFC3339),
+			event.Operation,
+			event.Result,
+			event.Hash,
+			event.Actor,
+			event.SourceIP,
+			event.Reason,
+		)
+		if event.Count > 0 {
+			fmt.Printf(" count=%d", event.Count)
+		}
+		if event.Error != "" {
+			fmt.Printf(" error=%q", event.Error)
+		}
+		fmt.Println()
+	}
+}
+
+// Parse an RFC 3339 timestamp, or a duration before now. Empty is the zero time.
+func parseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-duration), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// Add the --reason flag of a command that changes the blocklist.
+func addReasonFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&auditReason, "reason", "", "Reason recorded in the audit log")
+}
+
+// Start an audit event for a change to the blocklist by the local CLI.
+func newAuditEvent(hashString string) audit.Event {
+	return audit.Event{
+		Hash:   hashString,
+		Actor:  audit.CLIActor(),
+		Reason: auditReason,
+	}
+}
@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/blocklist"
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+)
+
+// Batch outcomes for each processed line.
+const (
+	BatchOutcomeNew        = "new"
+	BatchOutcomeExisting   = "existing"
+	BatchOutcomeBlocked    = "blocked"
+	BatchOutcomeAllowed    = "allowed"
+	BatchOutcomeUnblocked  = "unblocked"
+	BatchOutcomeNotBlocked = "not_blocked"
+	BatchOutcomeInvalid    = "invalid"
+	BatchOutcomeError      = "error"
+)
+
+// Value passed as the argument or file name to read from stdin.
+const batchStdin = "-"
+
+// Default number of concurrent workers for batch processing.
+const batchDefaultWorkers = 8
+
+// Errors from batch argument handling.
+var (
+	ErrBatchNoInput       = errors.New("a value argument, '-', or --file is required")
+	ErrBatchAmbiguousArgs = errors.New("pass either a value argument or --file, not both")
+)
+
+// A BatchLineResult contains the result of processing one line of batch input.
+type BatchLineResult struct {
+	Line      int    `json:"line"`          // line number of the value in the input.
+	Sha256    string `json:"sha256"`        // hash of the processed value. Tokens are never echoed back.
+	Outcome   string `json:"outcome"`       // outcome of the operation on the value.
+	Message   string `json:"message"`       // message summarizing the result.
+	TTL       int    `json:"block_ttl_sec"` // remaining time-to-live of the token in the blocklist.
+	TTLString string `json:"block_ttl_str"` // human readable remaining time-to-live.
+	IsError   bool   `json:"error"`         // whether or not the result was an error.
+}
+
+// A BatchSummary contains the totals of a batch run, counted by outcome.
+type BatchSummary struct {
+	Total  int            `json:"total"`  // number of values processed.
+	Counts map[string]int `json:"counts"` // number of values per outcome.
+}
+
+// Failed returns the number of values that were invalid or failed.
+func (s *BatchSummary) Failed() int {
+	return s.Counts[BatchOutcomeInvalid] + s.Counts[BatchOutcomeError]
+}
+
+// Batch settings shared by the block, unblock and check commands.
+type batchFlags struct {
+	file    string
+	workers int
+}
+
+// A batchFunc processes a single value from batch input.
+type batchFunc func(value string) BatchLineResult
+
+type batchLine struct {
+	number int
+	value  string
+}
+
+// Add the batch flags to a command.
+func addBatchFlags(cmd *cobra.Command, flags *batchFlags) {
+	cmd.Flags().StringVarP(&flags.file, "file", "f", "", "Read newline-delimited values from a file ('-' for stdin)")
+	cmd.Flags().IntVar(&flags.workers, "workers", batchDefaultWorkers, "Number of concurrent workers for batch input")
+}
+
+// Determine if the command runs in batch mode, and where its input comes from.
+//
+// Returns the batch input path, or an empty string for a single value argument.
+func batchInputPath(args []string, flags *batchFlags) (string, error) {
+	if flags.file != "" && len(args) > 0 {
+		return "", ErrBatchAmbiguousArgs
+	}
+	if flags.file != "" {
+		return flags.file, nil
+	}
+	if len(args) == 0 {
+		return "", ErrBatchNoInput
+	}
+	if args[0] == batchStdin {
+		return batchStdin, nil
+	}
+	return "", nil
+}
+
+// Open the batch input, where "-" is stdin.
+func openBatchInput(path string) (io.ReadCloser, error) {
+	if path == batchStdin {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// Process newline-delimited values from the batch input with a bounded worker pool.
+//
+// Results are streamed to stdout as each value completes, followed by a summary.
+func runBatch(path string, workers int, fn batchFunc) (*BatchSummary, error) {
+	input, err := openBatchInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	lines := make(chan batchLine)
+	results := make(chan BatchLineResult)
+
+	// Workers.
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for line := range lines {
+				result := fn(line.value)
+				result.Line = line.number
+				results <- result
+			}
+		}()
+	}
+
+	// Reader.
+	var readErr error
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(input)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		number := 0
+		for scanner.Scan() {
+			number++
+			value := strings.TrimSpace(scanner.Text())
+			if value == "" {
+				continue
+			}
+			lines <- batchLine{number: number, value: value}
+		}
+		readErr = scanner.Err()
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Stream the results.
+	summary := &BatchSummary{Counts: make(map[string]int)}
+	for result := range results {
+		summary.Total++
+		summary.Counts[result.Outcome]++
+		showBatchLineResult(result)
+	}
+	showBatchSummary(summary)
+
+	return summary, readErr
+}
+
+// Exit with status 1 when the batch input could not be read, or any value failed.
+func exitOnBatchFailure(summary *BatchSummary, err error) {
+	if err != nil {
+		fmt.Printf("Error reading batch input: %s\n", err.Error())
+		os.Exit(1)
+	}
+	if summary.Failed() > 0 {
+		os.Exit(1)
+	}
+}
+
+// Print a single batch line result.
+func showBatchLineResult(result BatchLineResult) {
+	if viper.GetBool(core.OptStr_OutJSON) {
+		resultJSON, _ := json.Marshal(result)
+		fmt.Println(string(resultJSON))
+	} else {
+		fmt.Printf("%d: [%s] %s %s\n", result.Line, result.Outcome, result.Sha256, result.Message)
+	}
+}
+
+// Print the batch summary.
+func showBatchSummary(summary *BatchSummary) {
+	if viper.GetBool(core.OptStr_OutJSON) {
+		summaryJSON, _ := json.Marshal(map[string]*BatchSummary{"summary": summary})
+		fmt.Println(string(summaryJSON))
+		return
+	}
+
+	outcomes := make([]string, 0, len(summary.Counts))
+	for outcome := range summary.Counts {
+		outcomes = append(outcomes, outcome)
+	}
+	sort.Strings(outcomes)
+
+	counts := make([]string, 0, len(outcomes))
+	for _, outcome := range outcomes {
+		counts = append(counts, fmt.Sprintf("%s=%d", outcome, summary.Counts[outcome]))
+	}
+	fmt.Printf("Processed %d values: %s\n", summary.Total, strings.Join(counts, " "))
+}
+
+// Start a line result for a value, validating it as a hash.
+//
+// Returns false if the value is invalid, with the result already filled in.
+// Tokens are only checked by the operation on them, locally or by the remote
+// server with its own configuration.
+func newBatchLineResult(value string, useSha256 bool) (BatchLineResult, bool) {
+	result := BatchLineResult{TTL: -1}
+
+	if useSha256 {
+		result.Sha256 = value
+		if err := crypto.IsValidSha256(value); err != nil {
+			result.Outcome = BatchOutcomeInvalid
+			result.Message = err.Error()
+			result.IsError = true
+			return result, false
+		}
+		return result, true
+	}
+
+	result.Sha256 = crypto.TokenHash(value)
+	return result, true
+}
+
+// Set the outcome of a line result from an operation error.
+//
+// Errors of the blocklist store are failures, and any other local error is
+// an invalid value, e.g. a token that fails the JWT checks.
+func setBatchLineError(result *BatchLineResult, err error) {
+	result.Outcome = BatchOutcomeError
+	if isRemoteMode() {
+		if isRemoteInvalidValue(err) {
+			result.Outcome = BatchOutcomeInvalid
+		}
+	} else if !errors.Is(err, blocklist.ErrStoreUnavailable) {
+		result.Outcome = BatchOutcomeInvalid
+	}
+	result.Message = err.Error()
+	result.IsError = true
+}
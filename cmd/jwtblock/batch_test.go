@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/divergentcodes/jwtblock/internal/blocklist"
+)
+
+func Test_batchInputPath_Args_Path(t *testing.T) {
+	tests := map[string]struct {
+		args     []string
+		file     string
+		expected string
+		err      error
+	}{
+		"value":     {args: []string{"token"}, expected: ""},
+		"stdin":     {args: []string{"-"}, expected: batchStdin},
+		"file":      {file: "tokens.txt", expected: "tokens.txt"},
+		"stdinFile": {file: "-", expected: batchStdin},
+		"none":      {err: ErrBatchNoInput},
+		"ambiguous": {args: []string{"token"}, file: "tokens.txt", err: ErrBatchAmbiguousArgs},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			path, err := batchInputPath(test.args, &batchFlags{file: test.file})
+			if !errors.Is(err, test.err) {
+				t.Errorf("Expected error %v, got %v", test.err, err)
+			}
+			if path != test.expected {
+				t.Errorf("Expected path %q, got %q", test.expected, path)
+			}
+		})
+	}
+}
+
+func Test_runBatch_Input_Summarized(t *testing.T) {
+	outcomes := map[string]string{
+		"good": BatchOutcomeNew,
+		"seen": BatchOutcomeExisting,
+		"bad":  BatchOutcomeInvalid,
+		"down": BatchOutcomeError,
+	}
+	tests := map[string]struct {
+		input    string
+		workers  int
+		lines    []string
+		counts   map[string]int
+		failures int
+	}{
+		"empty": {
+			input:  "",
+			counts: map[string]int{},
+		},
+		"allSucceeded": {
+			input:   "good\nseen\n",
+			workers: 2,
+			lines:   []string{"1: [new]", "2: [existing]"},
+			counts:  map[string]int{BatchOutcomeNew: 1, BatchOutcomeExisting: 1},
+		},
+		"blankLinesSkipped": {
+			input:   "\n  good  \n\n\ngood\n",
+			workers: 1,
+			lines:   []string{"2: [new]", "5: [new]"},
+			counts:  map[string]int{BatchOutcomeNew: 2},
+		},
+		"someFailed": {
+			input:    "good\nbad\ndown\n",
+			workers:  0,
+			lines:    []string{"1: [new]", "2: [invalid]", "3: [error]"},
+			counts:   map[string]int{BatchOutcomeNew: 1, BatchOutcomeInvalid: 1, BatchOutcomeError: 1},
+			failures: 2,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "values.txt")
+			if err := os.WriteFile(path, []byte(test.input), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			var summary *BatchSummary
+			var err error
+			output := captureStdout(t, func() {
+				summary, err = runBatch(path, test.workers, func(value string) BatchLineResult {
+					return BatchLineResult{Outcome: outcomes[value]}
+				})
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, line := range test.lines {
+				if !strings.Contains(output, line) {
+					t.Errorf("Expected the result %q, got %q", line, output)
+				}
+			}
+			total := 0
+			for outcome, count := range test.counts {
+				total += count
+				if summary.Counts[outcome] != count {
+					t.Errorf("Expected %d %s values, got %d", count, outcome, summary.Counts[outcome])
+				}
+			}
+			if summary.Total != total {
+				t.Errorf("Expected %d values, got %d", total, summary.Total)
+			}
+			if summary.Failed() != test.failures {
+				t.Errorf("Expected %d failed values, got %d", test.failures, summary.Failed())
+			}
+		})
+	}
+}
+
+func Test_runBatch_MissingFile_Error(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.txt")
+
+	called := false
+	summary, err := runBatch(path, 1, func(value string) BatchLineResult {
+		called = true
+		return BatchLineResult{}
+	})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected os.ErrNotExist, got %v", err)
+	}
+	if summary != nil || called {
+		t.Errorf("Expected no values processed, got %+v", summary)
+	}
+}
+
+func Test_setBatchLineError_LocalError_Outcome(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		expected string
+	}{
+		"invalidToken":     {err: errors.New("invalid JWT"), expected: BatchOutcomeInvalid},
+		"storeUnavailable": {err: fmt.Errorf("%w: connection refused", blocklist.ErrStoreUnavailable), expected: BatchOutcomeError},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := BatchLineResult{}
+			setBatchLineError(&result, test.err)
+			if result.Outcome != test.expected || !result.IsError {
+				t.Errorf("Expected the outcome %s, got %+v", test.expected, result)
+			}
+		})
+	}
+}
+
+// Capture what fn prints to stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		content, _ := io.ReadAll(reader)
+		output <- string(content)
+	}()
+
+	fn()
+	writer.Close()
+	return <-output
+}
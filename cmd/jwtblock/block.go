@@ -7,17 +7,23 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/divergentcodes/jwtblock/internal/audit"
 	"github.com/divergentcodes/jwtblock/internal/blocklist"
 	"github.com/divergentcodes/jwtblock/internal/cache"
 	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
 )
 
 var (
+	// Used for flags.
+	blockUseSha256 bool
+	blockBatch     batchFlags
+
 	blockCmd = &cobra.Command{
-		Use:   "block <JWT>",
+		Use:   "block [<JWT> | -] [--sha256] [--file <FILE>]",
 		Short: "Block a JWT",
-		Long:  `Block a JWT by adding it to the blocklist`,
-		Args:  cobra.ExactArgs(1),
+		Long:  `Block a JWT by adding it to the blocklist, or block newline-delimited tokens or hashes from stdin or a file`,
+		Args:  cobra.MaximumNArgs(1),
 		Run:   block,
 	}
 )
@@ -27,29 +33,56 @@ func init() {
 	// jwt.ttl.sec_specified
 	specifiedTTL := viper.GetInt(core.OptStr_JwtTTLSpecifiedSeconds)
 	blockCmd.Flags().IntP("ttl", "t", specifiedTTL, "TTL for token blocking in seconds")
-	err := viper.BindPFlag(core.OptStr_JwtTTLSpecifiedSeconds, blockCmd.Flags().Lookup("ttl"))
+	err := core.BindPFlag(core.OptStr_JwtTTLSpecifiedSeconds, blockCmd.Flags().Lookup("ttl"))
 	if err != nil {
 		panic(err)
 	}
 
+	blockCmd.Flags().BoolVar(&blockUseSha256, "sha256", false, "Block by SHA256 of token instead")
+	addBatchFlags(blockCmd, &blockBatch)
+	addReasonFlag(blockCmd)
+
 	rootCmd.AddCommand(blockCmd)
 }
 
 func block(cmd *cobra.Command, args []string) {
 	ShowBanner()
 
-	tokenString := args[0]
-	var result *blocklist.BlockResult
-	var err error
-	redisDB := cache.GetRedisClient()
+	batchPath, err := batchInputPath(args, &blockBatch)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
 
-	// Add to blocklist.
 	ttl := viper.GetInt(core.OptStr_JwtTTLSpecifiedSeconds)
-	if ttl < 0 {
-		result, err = blocklist.Block(redisDB, tokenString)
-	} else {
-		result, err = blocklist.BlockWithTTL(redisDB, tokenString, ttl)
+
+	// Block every value from the batch input.
+	if batchPath != "" {
+		summary, err := runBatch(batchPath, blockBatch.workers, func(value string) BatchLineResult {
+			lineResult, ok := newBatchLineResult(value, blockUseSha256)
+			if !ok {
+				return lineResult
+			}
+			result, err := blockValue(value, ttl)
+			if err != nil {
+				setBatchLineError(&lineResult, err)
+				return lineResult
+			}
+			lineResult.Outcome = BatchOutcomeNew
+			if !result.IsNew {
+				lineResult.Outcome = BatchOutcomeExisting
+			}
+			lineResult.Message = result.Message
+			lineResult.TTL = result.TTL
+			lineResult.TTLString = result.TTLString
+			return lineResult
+		})
+		exitOnBatchFailure(summary, err)
+		return
 	}
+
+	// Add to blocklist.
+	result, err := blockValue(args[0], ttl)
 	if err != nil {
 		fmt.Printf("Failed to add token to blocklist: err=%s\n", err.Error())
 		return
@@ -67,3 +100,22 @@ func block(cmd *cobra.Command, args []string) {
 		fmt.Printf("%s [New: %t] [TTL: %s]\n", msg, result.IsNew, result.TTLString)
 	}
 }
+
+// Block a single token or hash, with the configured TTL.
+func blockValue(value string, ttl int) (*blocklist.BlockResult, error) {
+	if isRemoteMode() {
+		return remoteBlock(value, blockUseSha256, ttl)
+	}
+
+	var result *blocklist.BlockResult
+	var err error
+	redisDB := cache.GetRedisClient()
+	if blockUseSha256 {
+		result, err = blocklist.BlockBySha256WithTTL(redisDB, value, ttl)
+		audit.Block(newAuditEvent(value), result, err)
+	} else {
+		result, err = blocklist.BlockWithTTL(redisDB, value, ttl)
+		audit.Block(newAuditEvent(crypto.TokenHash(value)), result, err)
+	}
+	return result, err
+}
@@ -15,37 +15,60 @@ import (
 var (
 	// Used for flags.
 	checkUseSha256 bool
+	checkBatch     batchFlags
 
 	checkCmd = &cobra.Command{
-		Use:   "check [<JWT>] [--sha256 <HASH>]",
+		Use:   "check [<JWT> | -] [--sha256 <HASH>] [--file <FILE>]",
 		Short: "Check if a JWT is blocked",
-		Long:  "Check if a JWT is blocked",
-		Args:  cobra.ExactArgs(1),
+		Long:  "Check if a JWT is blocked, or check newline-delimited tokens or hashes from stdin or a file",
+		Args:  cobra.MaximumNArgs(1),
 		Run:   check,
 	}
 )
 
 func init() {
 	checkCmd.Flags().BoolVar(&checkUseSha256, "sha256", false, "Check by SHA256 of token instead")
+	addBatchFlags(checkCmd, &checkBatch)
 
 	rootCmd.AddCommand(checkCmd)
 }
 
 func check(cmd *cobra.Command, args []string) {
 	ShowBanner()
-	redisDB := cache.GetRedisClient()
 
-	var checkResult blocklist.CheckResult
-	var err error
-	value := args[0]
+	batchPath, err := batchInputPath(args, &checkBatch)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
 
-	// Hash or token.
-	if checkUseSha256 {
-		checkResult, err = blocklist.CheckBySha256(redisDB, value)
-	} else {
-		checkResult, err = blocklist.CheckByJwt(redisDB, value)
+	// Check every value from the batch input.
+	if batchPath != "" {
+		summary, err := runBatch(batchPath, checkBatch.workers, func(value string) BatchLineResult {
+			lineResult, ok := newBatchLineResult(value, checkUseSha256)
+			if !ok {
+				return lineResult
+			}
+			result, err := checkValue(value)
+			if err != nil {
+				setBatchLineError(&lineResult, err)
+				return lineResult
+			}
+			lineResult.Outcome = BatchOutcomeAllowed
+			if result.IsBlocked {
+				lineResult.Outcome = BatchOutcomeBlocked
+			}
+			lineResult.Message = result.Message
+			lineResult.TTL = result.TTL
+			lineResult.TTLString = result.TTLString
+			return lineResult
+		})
+		exitOnBatchFailure(summary, err)
+		return
 	}
 
+	checkResult, err := checkValue(args[0])
+
 	// Error handling.
 	if err != nil {
 		fmt.Printf("Error: %s", err.Error())
@@ -66,3 +89,16 @@ func check(cmd *cobra.Command, args []string) {
 	}
 
 }
+
+// Check a single token or hash.
+func checkValue(value string) (blocklist.CheckResult, error) {
+	if isRemoteMode() {
+		return remoteCheck(value, checkUseSha256)
+	}
+
+	redisDB := cache.GetRedisClient()
+	if checkUseSha256 {
+		return blocklist.CheckBySha256(redisDB, value)
+	}
+	return blocklist.CheckByJwt(redisDB, value)
+}
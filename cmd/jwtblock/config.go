@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+var (
+	// Used for flags.
+	configInitOutput string
+	configInitForce  bool
+
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Show, validate or create the configuration",
+		Long:  "Show, validate or create the configuration, merged from the config file, environment variables and flags",
+	}
+
+	configShowCmd = &cobra.Command{
+		Use:   "show",
+		Short: "Show the effective configuration, with secrets redacted",
+		Args:  cobra.NoArgs,
+		Run:   configShow,
+	}
+
+	configValidateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the configuration",
+		Long:  "Validate the configuration, reporting every invalid option, and exit with status 1 if it is invalid",
+		Args:  cobra.NoArgs,
+		Run:   configValidate,
+	}
+
+	configInitCmd = &cobra.Command{
+		Use:   "init [--output <FILE>] [--force]",
+		Short: "Write a commented example config file",
+		Args:  cobra.NoArgs,
+		Run:   configInit,
+	}
+)
+
+func init() {
+	configInitCmd.Flags().StringVarP(&configInitOutput, "output", "o", "./.jwtblock.yaml", "Path of the config file to write")
+	configInitCmd.Flags().BoolVar(&configInitForce, "force", false, "Overwrite an existing config file")
+
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configInitCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func configShow(cmd *cobra.Command, args []string) {
+	settings := core.RedactSettings(viper.AllSettings())
+
+	if viper.GetBool(core.OptStr_OutJSON) {
+		_ = json.NewEncoder(os.Stdout).Encode(settings)
+		return
+	}
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(settings); err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+	_ = encoder.Close()
+}
+
+func configValidate(cmd *cobra.Command, args []string) {
+	err := core.ValidateConfig()
+	if err == nil {
+		fmt.Println("Configuration is valid")
+		return
+	}
+
+	fmt.Printf("Error: %s\n", err.Error())
+	os.Exit(1)
+}
+
+func configInit(cmd *cobra.Command, args []string) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if configInitForce {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(configInitOutput, flags, 0600)
+	if errors.Is(err, os.ErrExist) {
+		fmt.Printf("Error: %s already exists, use --force to overwrite it\n", configInitOutput)
+		os.Exit(1)
+	}
+	if err == nil {
+		_, err = file.WriteString(core.ExampleConfig())
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Printf("Wrote an example config file to %s\n", configInitOutput)
+}
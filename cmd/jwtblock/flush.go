@@ -7,6 +7,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/divergentcodes/jwtblock/internal/audit"
 	"github.com/divergentcodes/jwtblock/internal/blocklist"
 	"github.com/divergentcodes/jwtblock/internal/cache"
 	"github.com/divergentcodes/jwtblock/internal/core"
@@ -20,15 +21,23 @@ var flushCmd = &cobra.Command{
 }
 
 func init() {
+	addReasonFlag(flushCmd)
+
 	rootCmd.AddCommand(flushCmd)
 }
 
 func flush(cmd *cobra.Command, args []string) {
 	ShowBanner()
 
-	redisDB := cache.GetRedisClient()
-
-	result, err := blocklist.Flush(redisDB)
+	var result *blocklist.FlushResult
+	var err error
+	if isRemoteMode() {
+		result, err = remoteFlush()
+	} else {
+		redisDB := cache.GetRedisClient()
+		result, err = blocklist.Flush(redisDB)
+		audit.Flush(newAuditEvent(""), result, err)
+	}
 	if err != nil {
 		fmt.Printf("Error flushing the blocklist: %s", err.Error())
 		return
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+)
+
+// A HashResult contains the blocklist keys of a token.
+type HashResult struct {
+	Hash   string   `json:"sha256"`           // current blocklist key, for use with --sha256.
+	Hashes []string `json:"hashes,omitempty"` // every key the token may be stored under, the current one first.
+}
+
+var (
+	// Used for flags.
+	hashAll bool
+
+	hashCmd = &cobra.Command{
+		Use:   "hash [<JWT> | -] [--all]",
+		Short: "Compute the blocklist hash of a JWT",
+		Long:  "Compute the blocklist hash of a JWT for the --sha256 flags, keyed with the configured HMAC secret, or of newline-delimited tokens from stdin",
+		Args:  cobra.ExactArgs(1),
+		Run:   hash,
+	}
+)
+
+func init() {
+	hashCmd.Flags().BoolVar(&hashAll, "all", false, "Show every key the token may be stored under during secret rotation")
+
+	rootCmd.AddCommand(hashCmd)
+}
+
+func hash(cmd *cobra.Command, args []string) {
+	// Hashes from stdin are printed bare, so they can be piped into --sha256 -.
+	if args[0] == batchStdin {
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			value := strings.TrimSpace(scanner.Text())
+			if value == "" {
+				continue
+			}
+			printHashResult(hashToken(value))
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading input: %s\n", err.Error())
+		}
+		return
+	}
+
+	ShowBanner()
+	printHashResult(hashToken(args[0]))
+}
+
+// Compute the blocklist keys of a token.
+func hashToken(tokenString string) HashResult {
+	result := HashResult{
+		Hash: crypto.TokenHash(tokenString),
+	}
+	if hashAll {
+		result.Hashes = crypto.TokenHashes(tokenString)
+	}
+	return result
+}
+
+// Show the blocklist keys of a token.
+func printHashResult(result HashResult) {
+	if viper.GetBool(core.OptStr_OutJSON) {
+		hashJSON, _ := json.Marshal(result)
+		fmt.Println(string(hashJSON))
+		return
+	}
+
+	if len(result.Hashes) == 0 {
+		fmt.Println(result.Hash)
+		return
+	}
+	for _, key := range result.Hashes {
+		fmt.Println(key)
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/cache"
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/serverless/awslambda"
+)
+
+// Stores of the lambda-invoke command.
+const (
+	lambdaInvokeStoreRedis  = "redis"  // the configured Redis.
+	lambdaInvokeStoreMemory = "memory" // an in-memory Redis, empty on every run.
+)
+
+var (
+	// Used for flags.
+	lambdaInvokeEvents []string
+	lambdaInvokeStore  string
+
+	lambdaInvokeCmd = &cobra.Command{
+		Use:   "lambda-invoke --event <FILE> [--event <FILE>...] [--store redis|memory]",
+		Short: "Invoke the Lambda handler locally with events",
+		Long:  "Invoke the Lambda handler locally with events from files or stdin (-), in order, and print the response of each event as the Lambda runtime would return it",
+		Args:  cobra.NoArgs,
+		Run:   lambdaInvoke,
+	}
+)
+
+func init() {
+	lambdaInvokeCmd.Flags().StringArrayVar(&lambdaInvokeEvents, "event", nil, "Lambda event JSON file, or - for stdin (repeatable)")
+	lambdaInvokeCmd.Flags().StringVar(&lambdaInvokeStore, "store", lambdaInvokeStoreRedis, "Blocklist store: redis (configured Redis), or memory (in-memory, shared by the events of the run)")
+	_ = lambdaInvokeCmd.MarkFlagRequired("event")
+
+	rootCmd.AddCommand(lambdaInvokeCmd)
+}
+
+func lambdaInvoke(cmd *cobra.Command, args []string) {
+	switch lambdaInvokeStore {
+	case lambdaInvokeStoreRedis:
+	case lambdaInvokeStoreMemory:
+		redisServer, err := miniredis.Run()
+		if err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+			return
+		}
+		defer redisServer.Close()
+		cache.SetRedisClient(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+	default:
+		fmt.Printf("Error: unknown store %q, must be redis or memory\n", lambdaInvokeStore)
+		return
+	}
+
+	for _, eventPath := range lambdaInvokeEvents {
+		event, err := readLambdaEvent(eventPath)
+		if err != nil {
+			fmt.Printf("Error reading event %s: %s\n", eventPath, err.Error())
+			return
+		}
+
+		result := awslambda.Invoke(context.Background(), event)
+		var resultJSON []byte
+		if viper.GetBool(core.OptStr_OutJSON) {
+			resultJSON, _ = json.Marshal(result)
+		} else {
+			resultJSON, _ = json.MarshalIndent(result, "", "  ")
+		}
+		fmt.Println(string(resultJSON))
+	}
+}
+
+// Read a Lambda event from a file, or from stdin with "-".
+func readLambdaEvent(eventPath string) ([]byte, error) {
+	if eventPath == batchStdin {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(eventPath)
+}
@@ -28,11 +28,21 @@ func list(cmd *cobra.Command, args []string) {
 	ShowBanner()
 	logger := core.GetLogger()
 
-	redisDB := cache.GetRedisClient()
-	result, _ := blocklist.List(redisDB)
+	var result *blocklist.ListResult
+	var err error
+	if isRemoteMode() {
+		result, err = remoteList()
+	} else {
+		redisDB := cache.GetRedisClient()
+		result, err = blocklist.List(redisDB)
+	}
+	if err != nil {
+		fmt.Printf("Error listing the blocklist: %s\n", err.Error())
+		return
+	}
 
 	if viper.GetBool(core.OptStr_OutJSON) {
-		err := json.NewEncoder(os.Stdout).Encode(result)
+		err = json.NewEncoder(os.Stdout).Encode(result)
 		if err != nil {
 			logger.Errorw(
 				"failed to JSON encode response data",
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/client"
+	"github.com/divergentcodes/jwtblock/internal/blocklist"
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+var remoteOnce sync.Once
+var remote *client.Client
+var remoteErr error
+
+// Check if the CLI is configured to use a remote jwtblock server.
+func isRemoteMode() bool {
+	return viper.GetString(core.OptStr_RemoteServer) != ""
+}
+
+// Check if an error is the remote server rejecting an invalid token or hash.
+func isRemoteInvalidValue(err error) bool {
+	var apiErr *client.Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest
+}
+
+// Get a singleton of the remote client, configured from the remote options.
+func getRemoteClient() (*client.Client, error) {
+	remoteOnce.Do(func() {
+		var auths []client.Authenticator
+		if apiKey := core.GetSecret(core.OptStr_RemoteApiKey); apiKey != "" {
+			auths = append(auths, client.ApiKeyHeader(viper.GetString(core.OptStr_HttpHeaderApiKey), apiKey))
+		}
+		if bearerToken := core.GetSecret(core.OptStr_RemoteBearerToken); bearerToken != "" {
+			auths = append(auths, client.BearerTokenHeader(viper.GetString(core.OptStr_RemoteBearerHeader), bearerToken))
+		}
+
+		options := []client.Option{
+			client.WithTimeout(time.Duration(viper.GetInt(core.OptStr_RemoteTimeoutSec)) * time.Second),
+			client.WithAuth(client.MultiAuth(auths...)),
+			client.WithHeaderSha256(viper.GetString(core.OptStr_HttpHeaderSha256)),
+			client.WithHeaderTTL(viper.GetString(core.OptStr_HttpHeaderTTL)),
+		}
+		// The remote server records the reason in its audit log.
+		if auditReason != "" {
+			options = append(options, client.WithHeader(viper.GetString(core.OptStr_HttpHeaderReason), auditReason))
+		}
+
+		remote, remoteErr = client.New(viper.GetString(core.OptStr_RemoteServer), options...)
+	})
+	return remote, remoteErr
+}
+
+// Block a token or hash with the remote server.
+func remoteBlock(value string, useSha256 bool, ttl int) (*blocklist.BlockResult, error) {
+	rc, err := getRemoteClient()
+	if err != nil {
+		return nil, err
+	}
+	var result *client.BlockResult
+	if useSha256 {
+		result, err = rc.BlockSha256(context.Background(), value, ttl)
+	} else {
+		result, err = rc.BlockWithTTL(context.Background(), value, ttl)
+	}
+	return (*blocklist.BlockResult)(result), err
+}
+
+// Unblock a token or hash with the remote server.
+func remoteUnblock(value string, useSha256 bool) (*blocklist.UnblockResult, error) {
+	rc, err := getRemoteClient()
+	if err != nil {
+		return nil, err
+	}
+	var result *client.UnblockResult
+	if useSha256 {
+		result, err = rc.UnblockSha256(context.Background(), value)
+	} else {
+		result, err = rc.Unblock(context.Background(), value)
+	}
+	return (*blocklist.UnblockResult)(result), err
+}
+
+// Check a token or hash with the remote server.
+func remoteCheck(value string, useSha256 bool) (blocklist.CheckResult, error) {
+	rc, err := getRemoteClient()
+	if err != nil {
+		return blocklist.CheckResult{}, err
+	}
+	var result client.CheckResult
+	if useSha256 {
+		result, err = rc.CheckSha256(context.Background(), value)
+	} else {
+		result, err = rc.Check(context.Background(), value)
+	}
+	return blocklist.CheckResult(result), err
+}
+
+// List the blocked token hashes on the remote server.
+func remoteList() (*blocklist.ListResult, error) {
+	rc, err := getRemoteClient()
+	if err != nil {
+		return nil, err
+	}
+	result, err := rc.List(context.Background())
+	return (*blocklist.ListResult)(result), err
+}
+
+// Flush the blocklist on the remote server.
+func remoteFlush() (*blocklist.FlushResult, error) {
+	rc, err := getRemoteClient()
+	if err != nil {
+		return nil, err
+	}
+	result, err := rc.Flush(context.Background())
+	return (*blocklist.FlushResult)(result), err
+}
+
+// Get the blocklist status from the remote server.
+func remoteStatus() (*blocklist.StatusResult, error) {
+	rc, err := getRemoteClient()
+	if err != nil {
+		return &blocklist.StatusResult{}, err
+	}
+	result, err := rc.Status(context.Background())
+	return (*blocklist.StatusResult)(result), err
+}
@@ -7,45 +7,61 @@ Usage:
 
 Available Commands:
 
-	block       Block a JWT
-	check       Check if a JWT is blocked
-	completion  Generate the autocompletion script for the specified shell
-	flush       Empty the blocklist
-	help        Help about any command
-	list        List blocked JWT hashes
-	serve       Serve the web API
-	status      Get status of the blocklist
-	unblock     Unblock a JWT
-	version     Print the version of jwtblock
+	audit         Query the audit log
+	block         Block a JWT
+	check         Check if a JWT is blocked
+	completion    Generate the autocompletion script for the specified shell
+	config        Show, validate or create the configuration
+	flush         Empty the blocklist
+	hash          Compute the blocklist hash of a JWT
+	help          Help about any command
+	lambda-invoke Invoke the Lambda handler locally with events
+	list          List blocked JWT hashes
+	serve         Serve the web API
+	status        Get status of the blocklist
+	unblock       Unblock a JWT
+	version       Print the version of jwtblock
 
 Flags:
 
-	    --config string       config file (default is ./jwtblock.yaml)
-	    --debug               Enable debug mode
-	-h, --help                help for jwtblock
-	    --json                Use JSON output
-	-q, --quiet               Quiet CLI output
-	    --redis-dbnum int     Redis DB number
-	    --redis-host string   Redis host (default "localhost")
-	    --redis-noverify      Skip Redis TLS certificate verification
-	    --redis-pass string   Redis password
-	    --redis-port int      Redis port (default 6379)
-	    --redis-tls           Connect to Redis over TLS (default true)
-	    --redis-user string   Redis username
-	    --verbose             Verbose CLI output
+	    --config string                  config file (default is ./.jwtblock.yaml)
+	    --debug                          Enable debug mode
+	-h, --help                           help for jwtblock
+	    --json                           Use JSON output
+	-q, --quiet                          Quiet CLI output
+	    --redis-cluster-addrs string     Comma-separated Redis Cluster seed node addresses
+	    --redis-dbnum int                Redis DB number
+	    --redis-host string              Redis host (default "localhost")
+	    --redis-noverify                 Skip Redis TLS certificate verification
+	    --redis-pass string              Redis password
+	    --redis-port int                 Redis port (default 6379)
+	    --redis-sentinel-addrs string    Comma-separated Redis Sentinel addresses
+	    --redis-sentinel-master string   Redis Sentinel master name
+	    --redis-tls                      Connect to Redis over TLS (default true)
+	    --redis-user string              Redis username
+	    --server string                  URL of a jwtblock server to use instead of Redis
+	    --server-api-key string          API key for the jwtblock server admin API
+	    --server-token string            Bearer token for a proxy in front of the jwtblock server
+	    --verbose                        Verbose CLI output
 */
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/secrets"
 )
 
 var (
+	// Used for flags.
+	cfgFile string
+
 	rootCmd = &cobra.Command{
 		Use:   "jwtblock",
 		Short: "A JWT blocklist & auth proxy service",
@@ -57,23 +73,49 @@ var (
 func init() {
 	initRootFlags()
 	initRedisFlags()
+	initRemoteFlags()
+
+	cobra.OnInitialize(initConfigFile, initLogger, initSecrets)
+}
+
+// Read the config file of the --config flag, instead of the default config file.
+func initConfigFile() {
+	if cfgFile == "" {
+		return
+	}
+	viper.SetConfigFile(cfgFile)
+	if err := viper.ReadInConfig(); err != nil {
+		fmt.Printf("Error: reading config file: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+// Rebuild the logger once the config file and CLI flags are read.
+func initLogger() {
+	if err := core.ReloadLogger(); err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+// Resolve the secret references of the configuration, once the config file and CLI flags are read.
+func initSecrets() {
+	if err := secrets.Init(context.Background()); err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		os.Exit(1)
+	}
 }
 
 func initRootFlags() {
 	var err error
 
 	// config
-	var cfgFile string
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./jwtblock.yaml)")
-	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	}
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./.jwtblock.yaml)")
 
 	// debug
 	defaultDebug := viper.GetBool(core.OptStr_Debug)
 	rootCmd.PersistentFlags().Bool("debug", defaultDebug, "Enable debug mode")
-	err = viper.BindPFlag(core.OptStr_Debug, rootCmd.PersistentFlags().Lookup("debug"))
+	err = core.BindPFlag(core.OptStr_Debug, rootCmd.PersistentFlags().Lookup("debug"))
 	if err != nil {
 		panic(err)
 	}
@@ -81,7 +123,7 @@ func initRootFlags() {
 	// json
 	defaultOutJSON := viper.GetBool(core.OptStr_OutJSON)
 	rootCmd.PersistentFlags().Bool("json", defaultOutJSON, "Use JSON log output")
-	err = viper.BindPFlag(core.OptStr_OutJSON, rootCmd.PersistentFlags().Lookup("json"))
+	err = core.BindPFlag(core.OptStr_OutJSON, rootCmd.PersistentFlags().Lookup("json"))
 	if err != nil {
 		panic(err)
 	}
@@ -89,7 +131,7 @@ func initRootFlags() {
 	// quiet
 	defaultQuiet := viper.GetBool(core.OptStr_Quiet)
 	rootCmd.PersistentFlags().BoolP("quiet", "q", defaultQuiet, "Quiet CLI output")
-	err = viper.BindPFlag(core.OptStr_Quiet, rootCmd.PersistentFlags().Lookup("quiet"))
+	err = core.BindPFlag(core.OptStr_Quiet, rootCmd.PersistentFlags().Lookup("quiet"))
 	if err != nil {
 		panic(err)
 	}
@@ -97,7 +139,7 @@ func initRootFlags() {
 	// verbose
 	defaultVerbose := viper.GetBool(core.OptStr_Verbose)
 	rootCmd.PersistentFlags().Bool("verbose", defaultVerbose, "Verbose CLI output")
-	err = viper.BindPFlag(core.OptStr_Verbose, rootCmd.PersistentFlags().Lookup("verbose"))
+	err = core.BindPFlag(core.OptStr_Verbose, rootCmd.PersistentFlags().Lookup("verbose"))
 	if err != nil {
 		panic(err)
 	}
@@ -109,7 +151,7 @@ func initRedisFlags() {
 	// redis.host
 	defaultHost := viper.GetString(core.OptStr_RedisHost)
 	rootCmd.PersistentFlags().String("redis-host", defaultHost, "Redis host")
-	err = viper.BindPFlag(core.OptStr_RedisHost, rootCmd.PersistentFlags().Lookup("redis-host"))
+	err = core.BindPFlag(core.OptStr_RedisHost, rootCmd.PersistentFlags().Lookup("redis-host"))
 	if err != nil {
 		panic(err)
 	}
@@ -117,7 +159,7 @@ func initRedisFlags() {
 	// redis.port
 	defaultPort := viper.GetInt(core.OptStr_RedisPort)
 	rootCmd.PersistentFlags().Int("redis-port", defaultPort, "Redis port")
-	err = viper.BindPFlag(core.OptStr_RedisPort, rootCmd.PersistentFlags().Lookup("redis-port"))
+	err = core.BindPFlag(core.OptStr_RedisPort, rootCmd.PersistentFlags().Lookup("redis-port"))
 	if err != nil {
 		panic(err)
 	}
@@ -125,7 +167,7 @@ func initRedisFlags() {
 	// redis.db_num
 	defaultDbNum := viper.GetInt(core.OptStr_RedisDbnum)
 	rootCmd.PersistentFlags().Int("redis-dbnum", defaultDbNum, "Redis DB number")
-	err = viper.BindPFlag(core.OptStr_RedisDbnum, rootCmd.PersistentFlags().Lookup("redis-dbnum"))
+	err = core.BindPFlag(core.OptStr_RedisDbnum, rootCmd.PersistentFlags().Lookup("redis-dbnum"))
 	if err != nil {
 		panic(err)
 	}
@@ -133,7 +175,7 @@ func initRedisFlags() {
 	// redis.username
 	defaultUser := viper.GetString(core.OptStr_RedisUsername)
 	rootCmd.PersistentFlags().String("redis-user", defaultUser, "Redis username")
-	err = viper.BindPFlag(core.OptStr_RedisUsername, rootCmd.PersistentFlags().Lookup("redis-user"))
+	err = core.BindPFlag(core.OptStr_RedisUsername, rootCmd.PersistentFlags().Lookup("redis-user"))
 	if err != nil {
 		panic(err)
 	}
@@ -141,7 +183,7 @@ func initRedisFlags() {
 	// redis.password
 	defaultPassword := viper.GetString(core.OptStr_RedisPassword)
 	rootCmd.PersistentFlags().String("redis-pass", defaultPassword, "Redis password")
-	err = viper.BindPFlag(core.OptStr_RedisPassword, rootCmd.PersistentFlags().Lookup("redis-pass"))
+	err = core.BindPFlag(core.OptStr_RedisPassword, rootCmd.PersistentFlags().Lookup("redis-pass"))
 	if err != nil {
 		panic(err)
 	}
@@ -149,7 +191,7 @@ func initRedisFlags() {
 	// redis.tls.enabled
 	defaultTlsEnabled := viper.GetBool(core.OptStr_RedisTlsEnabled)
 	rootCmd.PersistentFlags().Bool("redis-tls", defaultTlsEnabled, "Connect to Redis over TLS")
-	err = viper.BindPFlag(core.OptStr_RedisTlsEnabled, rootCmd.PersistentFlags().Lookup("redis-tls"))
+	err = core.BindPFlag(core.OptStr_RedisTlsEnabled, rootCmd.PersistentFlags().Lookup("redis-tls"))
 	if err != nil {
 		panic(err)
 	}
@@ -157,7 +199,59 @@ func initRedisFlags() {
 	// redis.tls.noverify
 	defaultTlsNoverify := viper.GetBool(core.OptStr_RedisTlsNoverify)
 	rootCmd.PersistentFlags().Bool("redis-noverify", defaultTlsNoverify, "Skip Redis TLS certificate verification")
-	err = viper.BindPFlag(core.OptStr_RedisTlsNoverify, rootCmd.PersistentFlags().Lookup("redis-noverify"))
+	err = core.BindPFlag(core.OptStr_RedisTlsNoverify, rootCmd.PersistentFlags().Lookup("redis-noverify"))
+	if err != nil {
+		panic(err)
+	}
+
+	// redis.sentinel.master_name
+	defaultSentinelMaster := viper.GetString(core.OptStr_RedisSentinelMasterName)
+	rootCmd.PersistentFlags().String("redis-sentinel-master", defaultSentinelMaster, "Redis Sentinel master name")
+	err = core.BindPFlag(core.OptStr_RedisSentinelMasterName, rootCmd.PersistentFlags().Lookup("redis-sentinel-master"))
+	if err != nil {
+		panic(err)
+	}
+
+	// redis.sentinel.addrs
+	defaultSentinelAddrs := viper.GetString(core.OptStr_RedisSentinelAddrs)
+	rootCmd.PersistentFlags().String("redis-sentinel-addrs", defaultSentinelAddrs, "Comma-separated Redis Sentinel addresses")
+	err = core.BindPFlag(core.OptStr_RedisSentinelAddrs, rootCmd.PersistentFlags().Lookup("redis-sentinel-addrs"))
+	if err != nil {
+		panic(err)
+	}
+
+	// redis.cluster.addrs
+	defaultClusterAddrs := viper.GetString(core.OptStr_RedisClusterAddrs)
+	rootCmd.PersistentFlags().String("redis-cluster-addrs", defaultClusterAddrs, "Comma-separated Redis Cluster seed node addresses")
+	err = core.BindPFlag(core.OptStr_RedisClusterAddrs, rootCmd.PersistentFlags().Lookup("redis-cluster-addrs"))
+	if err != nil {
+		panic(err)
+	}
+}
+
+func initRemoteFlags() {
+	var err error
+
+	// remote.server
+	defaultServer := viper.GetString(core.OptStr_RemoteServer)
+	rootCmd.PersistentFlags().String("server", defaultServer, "URL of a jwtblock server to use instead of Redis")
+	err = core.BindPFlag(core.OptStr_RemoteServer, rootCmd.PersistentFlags().Lookup("server"))
+	if err != nil {
+		panic(err)
+	}
+
+	// remote.api_key
+	defaultApiKey := viper.GetString(core.OptStr_RemoteApiKey)
+	rootCmd.PersistentFlags().String("server-api-key", defaultApiKey, "API key for the jwtblock server admin API")
+	err = core.BindPFlag(core.OptStr_RemoteApiKey, rootCmd.PersistentFlags().Lookup("server-api-key"))
+	if err != nil {
+		panic(err)
+	}
+
+	// remote.bearer_token
+	defaultBearerToken := viper.GetString(core.OptStr_RemoteBearerToken)
+	rootCmd.PersistentFlags().String("server-token", defaultBearerToken, "Bearer token for a proxy in front of the jwtblock server")
+	err = core.BindPFlag(core.OptStr_RemoteBearerToken, rootCmd.PersistentFlags().Lookup("server-token"))
 	if err != nil {
 		panic(err)
 	}
@@ -1,13 +1,22 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/divergentcodes/jwtblock/internal/blocklist"
 	"github.com/divergentcodes/jwtblock/internal/cache"
 	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/tracing"
 	"github.com/divergentcodes/jwtblock/web"
 )
 
@@ -27,7 +36,7 @@ func init() {
 	// http.hostname
 	defaultHost := viper.GetString(core.OptStr_HttpHostname)
 	serveCmd.Flags().String("hostname", defaultHost, "Hostname to listen on")
-	err = viper.BindPFlag(core.OptStr_HttpHostname, serveCmd.Flags().Lookup("hostname"))
+	err = core.BindPFlag(core.OptStr_HttpHostname, serveCmd.Flags().Lookup("hostname"))
 	if err != nil {
 		panic(err)
 	}
@@ -35,7 +44,7 @@ func init() {
 	// http.port
 	defaultPort := viper.GetInt(core.OptStr_HttpPort)
 	serveCmd.Flags().IntP("port", "p", defaultPort, "TCP port to listen on")
-	err = viper.BindPFlag(core.OptStr_HttpPort, serveCmd.Flags().Lookup("port"))
+	err = core.BindPFlag(core.OptStr_HttpPort, serveCmd.Flags().Lookup("port"))
 	if err != nil {
 		panic(err)
 	}
@@ -43,7 +52,7 @@ func init() {
 	// http.status_on_allowed
 	defaultStatusAllowed := viper.GetInt(core.OptStr_HttpStatusOnAllowed)
 	serveCmd.Flags().Int("status-on-allowed", defaultStatusAllowed, "HTTP response code when token is allowed")
-	err = viper.BindPFlag(core.OptStr_HttpStatusOnAllowed, serveCmd.Flags().Lookup("status-on-allowed"))
+	err = core.BindPFlag(core.OptStr_HttpStatusOnAllowed, serveCmd.Flags().Lookup("status-on-allowed"))
 	if err != nil {
 		panic(err)
 	}
@@ -51,7 +60,7 @@ func init() {
 	// http.status_on_blocked
 	defaultStatusBlocked := viper.GetInt(core.OptStr_HttpStatusOnBlocked)
 	serveCmd.Flags().Int("status-on-blocked", defaultStatusBlocked, "HTTP response code when token is blocked")
-	err = viper.BindPFlag(core.OptStr_HttpStatusOnBlocked, serveCmd.Flags().Lookup("status-on-blocked"))
+	err = core.BindPFlag(core.OptStr_HttpStatusOnBlocked, serveCmd.Flags().Lookup("status-on-blocked"))
 	if err != nil {
 		panic(err)
 	}
@@ -64,6 +73,12 @@ func serve(cmd *cobra.Command, args []string) {
 
 	ShowBanner()
 
+	// Serve with a fixed snapshot of the settings, replaced on reloads.
+	if err := core.ActivateConfig(); err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+
 	logger.Debugw(
 		"JWT parsing config",
 		"func", "cmd.serve",
@@ -80,8 +95,133 @@ func serve(cmd *cobra.Command, args []string) {
 		panic(err)
 	}
 
+	err = tracing.Init(context.Background())
+	if err != nil {
+		logger.Errorw(
+			"failed to initialize tracing",
+			"func", "cmd.serve",
+			"err", err.Error(),
+		)
+	}
+
+	err = blocklist.SubscribeChanges(context.Background(), cache.GetRedisClient())
+	if err != nil {
+		panic(err)
+	}
+
+	go watchConfig(context.Background())
+
 	host := viper.GetString(core.OptStr_HttpHostname)
 	port := viper.GetInt(core.OptStr_HttpPort)
 	fmt.Printf("Serving the jwtblock web API on %s:%d\n", host, port)
 	web.HandleRequests(host, port)
 }
+
+// Reload the configuration when the config file changes or on SIGHUP, without restarting.
+//
+// Invalid configurations are rejected, keeping the previous one. Only
+// core.ReloadConfig reads the changed file, so it is validated before it
+// replaces the current settings.
+func watchConfig(ctx context.Context) {
+	if configFile := viper.ConfigFileUsed(); configFile != "" {
+		go watchConfigFile(ctx, configFile)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			reloadConfig("SIGHUP")
+		}
+	}
+}
+
+// How long the config file must be unchanged before it is reloaded.
+//
+// Writing a file truncates it first, and an empty config file is valid, so
+// it is only read once the write is done.
+var configFileSettleDelay = 100 * time.Millisecond
+
+// Reload the configuration when the config file is written or replaced, until the context is done.
+//
+// The directory is watched, since editors and Kubernetes volumes replace the
+// file, or the target of its symlink, instead of writing it.
+func watchConfigFile(ctx context.Context, configFile string) {
+	logger := core.GetLogger()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = watcher.Add(filepath.Dir(configFile))
+	}
+	if err != nil {
+		logger.Errorw(
+			"failed to watch the config file",
+			"func", "cmd.watchConfigFile",
+			"file", configFile,
+			"err", err.Error(),
+		)
+		if watcher != nil {
+			watcher.Close()
+		}
+		return
+	}
+	defer watcher.Close()
+
+	configFile = filepath.Clean(configFile)
+	target, _ := filepath.EvalSymlinks(configFile)
+	settled := time.NewTimer(configFileSettleDelay)
+	settled.Stop()
+	defer settled.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-settled.C:
+			reloadConfig("file change")
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			written := filepath.Clean(event.Name) == configFile &&
+				event.Op&(fsnotify.Write|fsnotify.Create) != 0
+			current, _ := filepath.EvalSymlinks(configFile)
+			replaced := current != "" && current != target
+			if written || replaced {
+				target = current
+				settled.Reset(configFileSettleDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Warnw(
+				"config file watch failed",
+				"func", "cmd.watchConfigFile",
+				"file", configFile,
+				"err", err.Error(),
+			)
+		}
+	}
+}
+
+func reloadConfig(trigger string) {
+	if err := core.ReloadConfig(); err != nil {
+		core.GetLogger().Errorw(
+			"rejected invalid configuration, keeping the previous one",
+			"func", "cmd.reloadConfig",
+			"trigger", trigger,
+			"err", err.Error(),
+		)
+		return
+	}
+	core.GetLogger().Infow(
+		"configuration reloaded",
+		"func", "cmd.reloadConfig",
+		"trigger", trigger,
+		"level", core.LogLevel(),
+	)
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+)
+
+func Test_watchConfigFile_InvalidFile_NotApplied(t *testing.T) {
+	core.InitConfigDefaults()
+	configPath := filepath.Join(t.TempDir(), "jwtblock.yaml")
+	writeConfigFile(t, configPath, "http:\n  status:\n    on_blocked: 403\n")
+	viper.SetConfigFile(configPath)
+	t.Cleanup(func() {
+		viper.SetConfigFile("")
+		_ = viper.ReadConfig(strings.NewReader(""))
+	})
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.ActivateConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchConfigFile(ctx, configPath)
+
+	// Write until the watcher rejects the file, since it starts asynchronously.
+	failures := metrics.Int("config_reload_failures_total").Value()
+	deadline := time.Now().Add(2 * time.Second)
+	for metrics.Int("config_reload_failures_total").Value() == failures {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the config file to be rejected")
+		}
+		writeConfigFile(t, configPath, "http:\n  status:\n    on_blocked: 9999\n")
+		time.Sleep(2 * configFileSettleDelay)
+	}
+
+	if status := viper.GetInt(core.OptStr_HttpStatusOnBlocked); status != 403 {
+		t.Errorf("Expected the previous status 403 in the settings, got %d", status)
+	}
+	if status := core.GetConfig().HTTP.StatusOnBlocked; status != 403 {
+		t.Errorf("Expected the previous status 403 in the snapshot, got %d", status)
+	}
+}
+
+func writeConfigFile(t *testing.T, path string, content string) {
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -19,26 +19,24 @@ var statusCmd = &cobra.Command{
 	Run:   status,
 }
 
-// A StatusData contains the status of the blocklist.
-type StatusData struct {
-	Size int64 `json:"size"`
-}
-
 func init() {
 	rootCmd.AddCommand(statusCmd)
 }
 
 func status(cmd *cobra.Command, args []string) {
 	ShowBanner()
-	redisDB := cache.GetRedisClient()
 
-	size, err := blocklist.Size(redisDB)
+	var result *blocklist.StatusResult
+	var err error
+	if isRemoteMode() {
+		result, err = remoteStatus()
+	} else {
+		redisDB := cache.GetRedisClient()
+		result, err = blocklist.Status(redisDB)
+	}
 
 	if viper.GetBool(core.OptStr_OutJSON) {
-		statusData := StatusData{
-			Size: size,
-		}
-		statusJSON, _ := json.Marshal(statusData)
+		statusJSON, _ := json.Marshal(result)
 
 		fmt.Println(string(statusJSON))
 	} else {
@@ -47,6 +45,6 @@ func status(cmd *cobra.Command, args []string) {
 			return
 		}
 
-		fmt.Printf("Blocklist size: %d\n", size)
+		fmt.Printf("Blocklist size: %d\n", result.Size)
 	}
 }
@@ -7,51 +7,71 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/divergentcodes/jwtblock/internal/audit"
 	"github.com/divergentcodes/jwtblock/internal/blocklist"
 	"github.com/divergentcodes/jwtblock/internal/cache"
 	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
 )
 
 var (
 	// Used for flags.
 	unblockUseSha256 bool
+	unblockBatch     batchFlags
 
 	unblockCmd = &cobra.Command{
-		Use:   "unblock [<JWT>] [--sha256 <HASH>]",
+		Use:   "unblock [<JWT> | -] [--sha256 <HASH>] [--file <FILE>]",
 		Short: "Unblock a JWT",
-		Long:  "Unblock a JWT by deleting it from the blocklist",
-		Args:  cobra.ExactArgs(1),
+		Long:  "Unblock a JWT by deleting it from the blocklist, or unblock newline-delimited tokens or hashes from stdin or a file",
+		Args:  cobra.MaximumNArgs(1),
 		Run:   unblock,
 	}
 )
 
 func init() {
 	unblockCmd.Flags().BoolVar(&unblockUseSha256, "sha256", false, "Unblock by SHA256 of token instead")
+	addBatchFlags(unblockCmd, &unblockBatch)
+	addReasonFlag(unblockCmd)
 
 	rootCmd.AddCommand(unblockCmd)
 }
 
 func unblock(cmd *cobra.Command, args []string) {
 	ShowBanner()
-	redisDB := cache.GetRedisClient()
 
-	var result *blocklist.UnblockResult
-	var err error
-	value := args[0]
+	batchPath, err := batchInputPath(args, &unblockBatch)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
 
-	// Hash or token.
-	if unblockUseSha256 {
-		result, err = blocklist.UnblockBySha256(redisDB, value)
-		if err != nil {
-			fmt.Printf("Error: %s\n", err.Error())
-			return
-		}
-	} else {
-		result, err = blocklist.UnblockByJwt(redisDB, value)
-		if err != nil {
-			fmt.Printf("Error: %s\n", err.Error())
-			return
-		}
+	// Unblock every value from the batch input.
+	if batchPath != "" {
+		summary, err := runBatch(batchPath, unblockBatch.workers, func(value string) BatchLineResult {
+			lineResult, ok := newBatchLineResult(value, unblockUseSha256)
+			if !ok {
+				return lineResult
+			}
+			result, err := unblockValue(value)
+			if err != nil {
+				setBatchLineError(&lineResult, err)
+				return lineResult
+			}
+			lineResult.Outcome = BatchOutcomeNotBlocked
+			if result.IsUnblocked {
+				lineResult.Outcome = BatchOutcomeUnblocked
+			}
+			lineResult.Message = result.Message
+			return lineResult
+		})
+		exitOnBatchFailure(summary, err)
+		return
+	}
+
+	result, err := unblockValue(args[0])
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
 	}
 
 	// Show output.
@@ -62,3 +82,22 @@ func unblock(cmd *cobra.Command, args []string) {
 		fmt.Println(result.Message)
 	}
 }
+
+// Unblock a single token or hash.
+func unblockValue(value string) (*blocklist.UnblockResult, error) {
+	if isRemoteMode() {
+		return remoteUnblock(value, unblockUseSha256)
+	}
+
+	var result *blocklist.UnblockResult
+	var err error
+	redisDB := cache.GetRedisClient()
+	if unblockUseSha256 {
+		result, err = blocklist.UnblockBySha256(redisDB, value)
+		audit.Unblock(newAuditEvent(value), result, err)
+	} else {
+		result, err = blocklist.UnblockByJwt(redisDB, value)
+		audit.Unblock(newAuditEvent(crypto.TokenHash(value)), result, err)
+	}
+	return result, err
+}
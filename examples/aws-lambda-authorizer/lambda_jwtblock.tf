@@ -106,6 +106,9 @@ resource "aws_lambda_function" "jwtblock" {
       JWTBLOCK_REDIS_HOST                = aws_elasticache_cluster.redis.cache_nodes[0].address
       JWTBLOCK_REDIS_PORT                = var.redis_port
       JWTBLOCK_HTTP_CORS_ALLOWED_ORIGINS = "https://${aws_cloudfront_distribution.static_assets.domain_name}"
+
+      JWTBLOCK_LAMBDA_AUTHORIZER_POLICY_RESOURCE = "wildcard"
+      JWTBLOCK_LAMBDA_AUTHORIZER_CACHE_TTL_SEC   = 60
     }
   }
 
@@ -147,7 +150,7 @@ resource "aws_apigatewayv2_integration" "jwtblock" {
 
 resource "aws_apigatewayv2_route" "jwtblock" {
   api_id    = aws_apigatewayv2_api.main.id
-  route_key = "ANY /blocklist"
+  route_key = "ANY /blocklist/{proxy+}"
   target    = "integrations/${aws_apigatewayv2_integration.jwtblock.id}"
 }
 
@@ -158,4 +161,5 @@ resource "aws_apigatewayv2_authorizer" "jwtblock" {
   authorizer_type                   = "REQUEST"
   identity_sources                  = ["$request.header.Authorization"]
   authorizer_payload_format_version = "2.0"
+  authorizer_result_ttl_in_seconds  = 60 # matches JWTBLOCK_LAMBDA_AUTHORIZER_CACHE_TTL_SEC.
 }
\ No newline at end of file
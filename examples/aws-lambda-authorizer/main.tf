@@ -14,7 +14,7 @@ locals {
   oidc_authorize_url      = "${local.idp_origin}/oauth2/authorize"
   oidc_token_url          = "${local.idp_origin}/oauth2/token"
   oidc_callback_urls      = ["${local.ui_origin}/oauth2-callback.html"]
-  oidc_logout_urls        = ["${local.apigw_base_url}/blocklist"]
+  oidc_logout_urls        = ["${local.apigw_base_url}/blocklist/block"]
   scopes                  = ["openid", "profile", "email"]
 
   protected_url = "${local.apigw_base_url}/protected"
@@ -0,0 +1,254 @@
+// Package audit implements an append-only audit trail of blocklist mutations.
+//
+// Every block, unblock and flush is recorded as an Event when "audit.enabled"
+// is set, to the sink configured by "audit.sink": a rotated JSON lines file,
+// syslog, or a Redis stream.
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"os/user"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/divergentcodes/jwtblock/internal/blocklist"
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+)
+
+// Operations recorded in the audit log.
+const (
+	OperationBlock   = "block"
+	OperationUnblock = "unblock"
+	OperationFlush   = "flush"
+)
+
+// Results of the operations recorded in the audit log.
+const (
+	ResultBlocked        = "blocked"
+	ResultAlreadyBlocked = "already_blocked"
+	ResultUnblocked      = "unblocked"
+	ResultNotBlocked     = "not_blocked"
+	ResultFlushed        = "flushed"
+	ResultError          = "error"
+)
+
+// General error messages from the audit log.
+var (
+	ErrUnknownSink       = errors.New("unknown audit sink, expected file, syslog or redis")
+	ErrQueryUnsupported  = errors.New("the audit sink does not support queries")
+	ErrSyslogUnsupported = errors.New("the syslog audit sink is not supported on this platform")
+	ErrRedisClusterSink  = errors.New("the redis audit sink is not supported with Redis Cluster")
+	ErrRedisSameDbnum    = errors.New("audit.redis.dbnum must differ from redis.dbnum, which is flushed with the blocklist")
+)
+
+// An Event is a single blocklist mutation in the audit log.
+type Event struct {
+	Time      time.Time `json:"time"`                 // when the mutation happened, in UTC.
+	Operation string    `json:"operation"`            // block, unblock or flush.
+	Hash      string    `json:"hash,omitempty"`       // hash of the token, empty for a flush.
+	Actor     string    `json:"actor"`                // identity of the caller.
+	SourceIP  string    `json:"source_ip,omitempty"`  // address of the caller, for remote callers.
+	Reason    string    `json:"reason,omitempty"`     // reason given by the caller.
+	Result    string    `json:"result"`               // outcome of the mutation.
+	Count     int64     `json:"count,omitempty"`      // number of tokens flushed.
+	Error     string    `json:"error,omitempty"`      // error message, when the result is an error.
+	RequestID string    `json:"request_id,omitempty"` // ID of the web request.
+}
+
+// A Filter selects events from the audit log. Zero values match everything.
+type Filter struct {
+	Since time.Time // events at or after this time.
+	Until time.Time // events before this time.
+	Actor string    // events by this actor.
+	Hash  string    // events for this token hash.
+	Limit int       // the most recent events, up to this number.
+}
+
+// Match reports whether an event is selected by the filter, ignoring the limit.
+func (f Filter) Match(event Event) bool {
+	if !f.Since.IsZero() && event.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !event.Time.Before(f.Until) {
+		return false
+	}
+	if f.Actor != "" && event.Actor != f.Actor {
+		return false
+	}
+	if f.Hash != "" && event.Hash != f.Hash {
+		return false
+	}
+	return true
+}
+
+// Keep the most recent events up to the limit.
+func (f Filter) limit(events []Event) []Event {
+	if f.Limit > 0 && len(events) > f.Limit {
+		return events[len(events)-f.Limit:]
+	}
+	return events
+}
+
+// A Sink stores audit events.
+type Sink interface {
+	// Write appends an event to the audit log.
+	Write(event Event) error
+	// Query returns the events selected by the filter, oldest first.
+	Query(filter Filter) ([]Event, error)
+	// Close releases the resources of the sink.
+	Close() error
+}
+
+var once sync.Once
+var sink Sink
+var sinkErr error
+
+// Create the sink from the configuration.
+func newSink() (Sink, error) {
+	config := core.GetConfig().Audit
+
+	switch name := config.Sink; name {
+	case "file":
+		return newFileSink(
+			config.FilePath,
+			int64(config.FileMaxSizeMB)*1024*1024,
+			config.FileMaxBackups,
+		), nil
+	case "syslog":
+		return newSyslogSink(
+			config.SyslogNetwork,
+			config.SyslogAddress,
+			config.SyslogTag,
+		)
+	case "redis":
+		return newRedisSinkFromConfig()
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSink, name)
+	}
+}
+
+// GetSink returns a singleton of the configured sink.
+func GetSink() (Sink, error) {
+	once.Do(func() {
+		if sink == nil {
+			sink, sinkErr = newSink()
+		}
+	})
+	return sink, sinkErr
+}
+
+// SetSink overrides and explicitly sets the sink singleton.
+func SetSink(s Sink) {
+	sink = s
+	sinkErr = nil
+}
+
+// Record writes an event to the audit log, if it is enabled.
+//
+// The mutation has already happened, so a failure to record it is logged and
+// counted in the "audit_errors" metric rather than returned.
+func Record(event Event) {
+	logger := core.GetLogger()
+
+	if !core.GetConfig().Audit.Enabled {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	s, err := GetSink()
+	if err == nil {
+		err = s.Write(event)
+	}
+	if err != nil {
+		logger.Errorw(
+			"Failed to write audit event",
+			"func", "audit.Record",
+			"operation", event.Operation,
+			"hash", event.Hash,
+			"err", err.Error(),
+		)
+		metrics.Int("audit_errors").Add(1)
+		return
+	}
+	metrics.Int("audit_events").Add(1)
+}
+
+// Query returns the events selected by the filter from the configured sink, oldest first.
+func Query(filter Filter) ([]Event, error) {
+	s, err := GetSink()
+	if err != nil {
+		return nil, err
+	}
+	return s.Query(filter)
+}
+
+// Block records the block of a token hash.
+func Block(event Event, result *blocklist.BlockResult, err error) {
+	event.Operation = OperationBlock
+	event.Result = ResultBlocked
+	if result != nil && !result.IsNew {
+		event.Result = ResultAlreadyBlocked
+	}
+	setError(&event, err)
+	Record(event)
+}
+
+// Unblock records the unblock of a token hash.
+func Unblock(event Event, result *blocklist.UnblockResult, err error) {
+	event.Operation = OperationUnblock
+	event.Result = ResultUnblocked
+	if result != nil && !result.IsUnblocked {
+		event.Result = ResultNotBlocked
+	}
+	setError(&event, err)
+	Record(event)
+}
+
+// Flush records a flush of the blocklist.
+func Flush(event Event, result *blocklist.FlushResult, err error) {
+	event.Operation = OperationFlush
+	event.Result = ResultFlushed
+	if result != nil && result.Count > 0 {
+		event.Count = result.Count
+	}
+	setError(&event, err)
+	Record(event)
+}
+
+func setError(event *Event, err error) {
+	if err != nil {
+		event.Result = ResultError
+		event.Error = err.Error()
+	}
+}
+
+// TokenActor returns the actor for a token blocking itself, from its subject claim.
+//
+// The subject is not verified here, it is only as trustworthy as the blocklist checks of the token.
+func TokenActor(tokenString string) string {
+	token, err := jwt.ParseInsecure([]byte(tokenString))
+	if err != nil || token.Subject() == "" {
+		return "token"
+	}
+	return "subject:" + token.Subject()
+}
+
+// ApiKeyActor returns the actor for an admin API key, identified by a prefix of its hash.
+func ApiKeyActor(apiKey string) string {
+	return "api_key:" + crypto.Sha256FromString(apiKey)[:12]
+}
+
+// CLIActor returns the actor for the local CLI, from the current OS user.
+func CLIActor() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return "cli:" + u.Username
+	}
+	return "cli"
+}
@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/cache"
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+// Field of the stream entries holding the JSON event.
+const redisStreamField = "event"
+
+// A redisSink appends events to a Redis stream.
+//
+// Stream entry IDs start with a millisecond timestamp, so time ranges are
+// queried on the server, and the stream can be trimmed to an approximate
+// maximum length.
+type redisSink struct {
+	mu     sync.RWMutex
+	client redis.UniversalClient
+	stream string
+	maxLen int64
+
+	removeHook func() // stops reconnecting with the Redis client singleton.
+}
+
+// Create the sink with its own client, on a different database than the blocklist.
+//
+// The client is replaced whenever the Redis client singleton reconnects,
+// since its connections went stale the same way.
+func newRedisSinkFromConfig() (Sink, error) {
+	if viper.GetString(core.OptStr_RedisClusterAddrs) != "" {
+		return nil, ErrRedisClusterSink
+	}
+	config := core.GetConfig().Audit
+	if config.RedisDbnum == viper.GetInt(core.OptStr_RedisDbnum) {
+		return nil, ErrRedisSameDbnum
+	}
+	s := newRedisSink(
+		cache.NewRedisClient(config.RedisDbnum),
+		config.RedisStream,
+		config.RedisMaxLen,
+	)
+	s.removeHook = cache.OnReconnect(func(_ redis.UniversalClient, _ redis.UniversalClient) {
+		s.reconnect(cache.NewRedisClient(config.RedisDbnum))
+	})
+	return s, nil
+}
+
+func newRedisSink(client redis.UniversalClient, stream string, maxLen int64) *redisSink {
+	return &redisSink{
+		client: client,
+		stream: stream,
+		maxLen: maxLen,
+	}
+}
+
+// Replace the client of the sink, and close the old one.
+func (s *redisSink) reconnect(client redis.UniversalClient) {
+	s.mu.Lock()
+	oldClient := s.client
+	s.client = client
+	s.mu.Unlock()
+
+	_ = oldClient.Close()
+}
+
+func (s *redisSink) getClient() redis.UniversalClient {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.client
+}
+
+func (s *redisSink) Write(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	args := &redis.XAddArgs{
+		Stream: s.stream,
+		Values: map[string]interface{}{redisStreamField: string(data)},
+	}
+	if s.maxLen > 0 {
+		args.MaxLen = s.maxLen
+		args.Approx = true
+	}
+	return s.getClient().XAdd(context.Background(), args).Err()
+}
+
+func (s *redisSink) Query(filter Filter) ([]Event, error) {
+	start, end := "-", "+"
+	if !filter.Since.IsZero() {
+		start = strconv.FormatInt(filter.Since.UnixMilli(), 10)
+	}
+	if !filter.Until.IsZero() {
+		end = strconv.FormatInt(filter.Until.UnixMilli()-1, 10)
+	}
+
+	messages, err := s.getClient().XRange(context.Background(), s.stream, start, end).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for _, message := range messages {
+		data, _ := message.Values[redisStreamField].(string)
+		var event Event
+		if json.Unmarshal([]byte(data), &event) != nil {
+			continue
+		}
+		if filter.Match(event) {
+			events = append(events, event)
+		}
+	}
+	return filter.limit(events), nil
+}
+
+func (s *redisSink) Close() error {
+	if s.removeHook != nil {
+		s.removeHook()
+	}
+	return s.getClient().Close()
+}
@@ -0,0 +1,40 @@
+//go:build !windows && !plan9
+
+package audit
+
+import (
+	"encoding/json"
+	"log/syslog"
+)
+
+// A syslogSink sends events as JSON messages to syslog, with the auth facility.
+//
+// Syslog is write-only, so the events cannot be queried back.
+type syslogSink struct {
+	writer *syslog.Writer
+}
+
+// Connect to syslog. An empty network connects to the local syslog server.
+func newSyslogSink(network string, address string, tag string) (Sink, error) {
+	writer, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{writer: writer}, nil
+}
+
+func (s *syslogSink) Write(event Event) error {
+	message, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.writer.Info(string(message))
+}
+
+func (s *syslogSink) Query(filter Filter) ([]Event, error) {
+	return nil, ErrQueryUnsupported
+}
+
+func (s *syslogSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,8 @@
+//go:build windows || plan9
+
+package audit
+
+// Syslog is not available on this platform.
+func newSyslogSink(network string, address string, tag string) (Sink, error) {
+	return nil, ErrSyslogUnsupported
+}
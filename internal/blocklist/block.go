@@ -1,11 +1,11 @@
 package blocklist
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/lestrrat-go/jwx/v2/jwt"
 	"github.com/redis/go-redis/v9"
-	"github.com/spf13/viper"
 
 	"github.com/divergentcodes/jwtblock/internal/core"
 	"github.com/divergentcodes/jwtblock/internal/crypto"
@@ -21,7 +21,7 @@ type BlockResult struct {
 }
 
 // Block adds a token to the blocklist without an explicit TTL, and returns whether the added value is new or not..
-func Block(redisDB *redis.Client, tokenString string) (*BlockResult, error) {
+func Block(redisDB redis.UniversalClient, tokenString string) (*BlockResult, error) {
 	// Add token to blocklist without an explicitly passed TTL.
 	return BlockWithTTL(redisDB, tokenString, -1)
 }
@@ -33,7 +33,7 @@ func Block(redisDB *redis.Client, tokenString string) (*BlockResult, error) {
 //	<0: Default TTL.
 //	0: Infinite TTL.
 //	>0: Expiring TTL.
-func BlockWithTTL(redisDB *redis.Client, tokenString string, explicitTTLSeconds int) (*BlockResult, error) {
+func BlockWithTTL(redisDB redis.UniversalClient, tokenString string, explicitTTLSeconds int) (*BlockResult, error) {
 	logger := core.GetLogger()
 	result := &BlockResult{
 		TTL:     -1,
@@ -47,14 +47,14 @@ func BlockWithTTL(redisDB *redis.Client, tokenString string, explicitTTLSeconds
 		return result, err
 	}
 
-	// Hash the JWT for storage.
-	cacheKey := crypto.Sha256FromString(tokenString)
+	// Hash the JWT for storage, keyed with the newest HMAC secret.
+	cacheKeys := crypto.TokenHashes(tokenString)
 
 	// Determine the TTL.
-	ttlDefaultSeconds := viper.GetViper().GetInt(core.OptStr_JwtTTLDefaultSeconds)
-	ttlDefault := time.Duration(ttlDefaultSeconds) * time.Second
+	config := core.GetConfig().JWT
+	ttlDefault := time.Duration(config.TTLDefaultSec) * time.Second
 	ttl := ttlDefault
-	useTokenExp := viper.GetViper().GetBool(core.OptStr_JwtTTLUseTokenExp)
+	useTokenExp := config.UseTokenExp
 
 	if explicitTTLSeconds >= 0 {
 		// Get TTL from function argument.
@@ -65,7 +65,7 @@ func BlockWithTTL(redisDB *redis.Client, tokenString string, explicitTTLSeconds
 		ttlFromExpSeconds, err := calculateTokenTTLFromExp(token)
 		if err == nil {
 			// Determine TTL padding.
-			ttlPaddingSeconds := viper.GetInt(core.OptStr_JwtTTLExpPaddingSeconds)
+			ttlPaddingSeconds := config.TTLPaddingSec
 			ttl = time.Duration(ttlFromExpSeconds) * time.Second
 			if ttlPaddingSeconds > 0 {
 				ttl += (time.Duration(ttlPaddingSeconds) * time.Second)
@@ -95,13 +95,71 @@ func BlockWithTTL(redisDB *redis.Client, tokenString string, explicitTTLSeconds
 		)
 	}
 
-	// Zero expiration means the key has no expiration time.
-	isNewValue, err := redisDB.SetNX(redisContext, cacheKey, true, ttl).Result()
-	if err != nil {
-		logger.Errorw("Redis SetNX error when adding new JWT", "error", err.Error())
+	return blockKeys(redisDB, cacheKeys, ttl, result)
+}
+
+// BlockBySha256WithTTL adds a token hash to the blocklist, and returns whether the added value is new or not.
+//
+// The token itself is not available, so the TTL cannot be derived from its EXP claim.
+// explicitTTLSeconds behavior is the same as BlockWithTTL, where <0 uses the default TTL.
+func BlockBySha256WithTTL(redisDB redis.UniversalClient, sha256 string, explicitTTLSeconds int) (*BlockResult, error) {
+	result := &BlockResult{
+		TTL:     -1,
+		IsError: false,
+	}
+
+	// Check SHA256 validity.
+	if err := crypto.IsValidSha256(sha256); err != nil {
 		result.IsError = true
-		result.Message = err.Error()
-		return result, err
+		result.Message = crypto.ErrMalformedSha256.Error()
+		return result, crypto.ErrMalformedSha256
+	}
+
+	ttl := time.Duration(core.GetConfig().JWT.TTLDefaultSec) * time.Second
+	if explicitTTLSeconds >= 0 {
+		ttl = time.Duration(explicitTTLSeconds) * time.Second
+	}
+
+	return blockKeys(redisDB, []string{sha256}, ttl, result)
+}
+
+// Store the current cache key with the given TTL, and fill in the result.
+//
+// The cache keys are every key the value may be stored under, the current one
+// first. With HMAC secret rotation, a value already stored under an older key
+// is not stored again.
+func blockKeys(redisDB redis.UniversalClient, cacheKeys []string, ttl time.Duration, result *BlockResult) (*BlockResult, error) {
+	logger := core.GetLogger()
+	cacheKey := cacheKeys[0]
+
+	// Check the older keys, one at a time since they may be in different Redis Cluster slots.
+	isNewValue := true
+	for _, olderKey := range cacheKeys[1:] {
+		exists, err := redisDB.Exists(redisContext, olderKey).Result()
+		if err != nil {
+			logger.Errorw("Redis Exists error when adding new JWT", "error", err.Error())
+			err = fmt.Errorf("%w: %w", ErrStoreUnavailable, err)
+			result.IsError = true
+			result.Message = err.Error()
+			return result, err
+		}
+		if exists > 0 {
+			isNewValue = false
+			break
+		}
+	}
+
+	// Zero expiration means the key has no expiration time.
+	if isNewValue {
+		var err error
+		isNewValue, err = redisDB.SetNX(redisContext, cacheKey, true, ttl).Result()
+		if err != nil {
+			logger.Errorw("Redis SetNX error when adding new JWT", "error", err.Error())
+			err = fmt.Errorf("%w: %w", ErrStoreUnavailable, err)
+			result.IsError = true
+			result.Message = err.Error()
+			return result, err
+		}
 	}
 
 	// Assemble result.
@@ -110,6 +168,9 @@ func BlockWithTTL(redisDB *redis.Client, tokenString string, explicitTTLSeconds
 		result.Message = SuccessTokenExists
 	}
 	result.IsNew = isNewValue
+	if isNewValue {
+		publishChange(redisDB, cacheKey)
+	}
 	result.TTL = int(ttl.Seconds())
 	if ttl.Seconds() == 0 {
 		result.TTLString = "Inf"
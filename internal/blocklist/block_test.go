@@ -173,6 +173,55 @@ func Test_BlockWithTTL_ValidToken_FullVerify_Success(t *testing.T) {
 	}
 }
 
+func Test_BlockBySha256WithTTL_ValidHash_Success(t *testing.T) {
+	var err error
+
+	// Values.
+	tokenHash := crypto.Sha256FromString("foobar")
+	ttlSeconds := 60
+
+	// Set the config.
+	core.InitConfigDefaults()
+
+	// Setup mock cache.
+	ttl := time.Duration(ttlSeconds) * time.Second
+	redisDB, redisMock := redismock.NewClientMock()
+
+	// Add the hash and check.
+	redisMock.ExpectSetNX(tokenHash, true, ttl).SetVal(true)
+	result, err := BlockBySha256WithTTL(redisDB, tokenHash, ttlSeconds)
+	if err != nil || !result.IsNew {
+		t.Errorf("Adding hash to blocklist failed: err=%s", err)
+	}
+
+	// Add the hash again with the default TTL, which isn't set when it already exists.
+	ttlDefault := time.Duration(viper.GetInt(core.OptStr_JwtTTLDefaultSeconds)) * time.Second
+	redisMock.ExpectSetNX(tokenHash, true, ttlDefault).SetVal(false)
+	result, err = BlockBySha256WithTTL(redisDB, tokenHash, -1)
+	if err != nil || result.IsNew || result.Message != SuccessTokenExists {
+		t.Errorf("Re-adding hash to blocklist failed: err=%s", err)
+	}
+
+	// Verify all expected Redis commands and results happened.
+	if err = redisMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_BlockBySha256WithTTL_InvalidHash_Error(t *testing.T) {
+	redisDB, redisMock := redismock.NewClientMock()
+
+	_, err := BlockBySha256WithTTL(redisDB, "foobar", 60)
+	if !errors.Is(err, crypto.ErrMalformedSha256) {
+		t.Errorf("Expected error ErrMalformedSha256: err=%s", err)
+	}
+
+	// Verify all expected Redis commands and results happened.
+	if err = redisMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
 func Test_Block_TTLFromExp_CorrectTTL_Success(t *testing.T) {
 
 	// Setup to use TTL from EXP, but don't verify signature.
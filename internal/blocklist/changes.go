@@ -0,0 +1,269 @@
+package blocklist
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/divergentcodes/jwtblock/internal/cache"
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+)
+
+// Change message for a flush, which affects every token hash.
+const changeAll = "*"
+
+// How long the change subscription can be idle before its connection is checked.
+var changesHealthCheckInterval = 30 * time.Second
+
+// Delay before resubscribing after the change subscription fails, and before the first resync retry.
+var changesRetryDelay = time.Second
+
+// Longest delay between resync retries, which back off from changesRetryDelay.
+var changesMaxRetryDelay = 30 * time.Second
+
+// The Redis client of the change subscription.
+//
+// It follows the Redis client singleton when it reconnects, so the
+// subscription is restored on the new client instead of the closed one.
+type changesClient struct {
+	mu     sync.RWMutex
+	client redis.UniversalClient
+}
+
+func (c *changesClient) get() redis.UniversalClient {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.client
+}
+
+// Replace the client, if it is the one that was reconnected.
+func (c *changesClient) reconnected(old redis.UniversalClient, client redis.UniversalClient) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == old {
+		c.client = client
+	}
+}
+
+// Apply a change to a token hash (or "*" for everything) to the in-process caches.
+func applyChange(key string) {
+	if c := getLocalCache(); c != nil {
+		if key == changeAll {
+			c.purge()
+		} else {
+			c.remove(key)
+		}
+	}
+	if f := getBlockedFilter(); f != nil && key != changeAll {
+		f.add(key)
+	}
+}
+
+// Publish a change to a token hash (or "*" for everything) to every replica.
+//
+// The in-process caches are updated immediately, and the other replicas
+// through the Redis change channel. A failure to publish is not returned,
+// since the change itself already succeeded, but counted in the
+// "changes_publish_errors_total" metric. It also requests an early rebuild of
+// the blocked filter, since changes from other replicas may be missed on the
+// same failing connection.
+func publishChange(redisDB redis.UniversalClient, key string) {
+	logger := core.GetLogger()
+
+	applyChange(key)
+
+	channel := core.GetConfig().Cache.Channel
+	if channel == "" {
+		return
+	}
+	if err := redisDB.Publish(redisContext, channel, key).Err(); err != nil {
+		metrics.Int("changes_publish_errors_total").Add(1)
+		if f := getBlockedFilter(); f != nil {
+			f.requestRebuild()
+		}
+		logger.Warnw(
+			"Failed to publish blocklist change",
+			"func", "blocklist.publishChange",
+			"channel", channel,
+			"err", err.Error(),
+		)
+	}
+}
+
+// SubscribeChanges keeps the in-process caches in sync with blocklist changes from other processes.
+//
+// Does nothing when neither the local cache nor the blocked filter are
+// enabled. The blocked filter is built before returning, and both run until
+// the context is done. Changes are received during the build, so they do not
+// back up on the subscription, and replayed on the filter after the build.
+//
+// While the subscription is down, the blocked filter is not used, and stale
+// local cache entries live at most until their TTL. Both are resynced when
+// the subscription is restored, on the new client if the Redis client
+// singleton was reconnected meanwhile.
+func SubscribeChanges(ctx context.Context, redisDB redis.UniversalClient) error {
+	logger := core.GetLogger()
+
+	c := getLocalCache()
+	f := getBlockedFilter()
+	if c == nil && f == nil {
+		return nil
+	}
+	channel := core.GetConfig().Cache.Channel
+	if channel == "" {
+		return ErrNoChangeChannel
+	}
+
+	client := &changesClient{client: redisDB}
+	removeHook := cache.OnReconnect(client.reconnected)
+
+	// Subscribe before building the filter, so no change is missed.
+	pubsub := redisDB.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		removeHook()
+		return err
+	}
+	receiveCtx, stopReceiving := context.WithCancel(ctx)
+	go func() {
+		defer removeHook()
+		defer stopReceiving()
+		receiveChanges(receiveCtx, client, pubsub, channel)
+	}()
+	if f != nil {
+		if err := f.resync(ctx, redisDB); err != nil {
+			stopReceiving()
+			return err
+		}
+		go f.rebuildPeriodically(ctx, client)
+	}
+
+	logger.Infow(
+		"Subscribed to blocklist changes",
+		"func", "blocklist.SubscribeChanges",
+		"channel", channel,
+	)
+	metrics.Int("changes_subscribed").Set(1)
+
+	return nil
+}
+
+// Receive changes until the context is done, resubscribing and resyncing after failures.
+func receiveChanges(ctx context.Context, client *changesClient, pubsub *redis.PubSub, channel string) {
+	logger := core.GetLogger()
+
+	inSync := true
+	awaitingPong := false
+	for {
+		message, err := pubsub.ReceiveTimeout(ctx, changesHealthCheckInterval)
+		if ctx.Err() != nil {
+			pubsub.Close()
+			return
+		}
+
+		// An idle connection is checked with a ping, and fails without a pong.
+		var netErr net.Error
+		if err != nil && errors.As(err, &netErr) && netErr.Timeout() && !awaitingPong {
+			if err = pubsub.Ping(ctx); err == nil {
+				awaitingPong = true
+				continue
+			}
+		}
+
+		if err != nil {
+			logger.Warnw(
+				"Blocklist change subscription failed",
+				"func", "blocklist.receiveChanges",
+				"channel", channel,
+				"err", err.Error(),
+			)
+			if inSync {
+				inSync = false
+				markChangesOutOfSync()
+			}
+
+			pubsub.Close()
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(changesRetryDelay):
+			}
+			pubsub = client.get().Subscribe(ctx, channel)
+			awaitingPong = false
+			continue
+		}
+
+		awaitingPong = false
+		switch message := message.(type) {
+		case *redis.Subscription:
+			if !inSync {
+				if err := retryResyncChanges(ctx, client); err != nil {
+					continue
+				}
+				inSync = true
+				metrics.Int("changes_subscribed").Set(1)
+				metrics.Int("changes_resyncs_total").Add(1)
+			}
+		case *redis.Message:
+			applyChange(message.Payload)
+		}
+	}
+}
+
+// Stop trusting the in-process caches, since changes may be missed.
+func markChangesOutOfSync() {
+	metrics.Int("changes_subscribed").Set(0)
+	if f := getBlockedFilter(); f != nil {
+		f.markOutOfSync()
+	}
+}
+
+// Resync the in-process caches until it succeeds or the context is done.
+//
+// Failed attempts are retried with an exponential backoff, bounded by
+// changesMaxRetryDelay, and the caches are not trusted meanwhile.
+func retryResyncChanges(ctx context.Context, client *changesClient) error {
+	logger := core.GetLogger()
+
+	delay := changesRetryDelay
+	for {
+		err := resyncChanges(ctx, client.get())
+		if err == nil {
+			return nil
+		}
+		markChangesOutOfSync()
+		metrics.Int("changes_resync_failures_total").Add(1)
+		logger.Warnw(
+			"Blocklist change resync failed",
+			"func", "blocklist.retryResyncChanges",
+			"retry", delay.String(),
+			"err", err.Error(),
+		)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > changesMaxRetryDelay {
+			delay = changesMaxRetryDelay
+		}
+	}
+}
+
+// Resync the in-process caches with the blocklist, after missing changes.
+func resyncChanges(ctx context.Context, redisDB redis.UniversalClient) error {
+	if c := getLocalCache(); c != nil {
+		c.purge()
+	}
+	if f := getBlockedFilter(); f != nil {
+		return f.resync(ctx, redisDB)
+	}
+	return nil
+}
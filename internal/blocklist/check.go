@@ -1,9 +1,17 @@
 package blocklist
 
 import (
+	"context"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/divergentcodes/jwtblock/internal/crypto"
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+	"github.com/divergentcodes/jwtblock/internal/tracing"
 )
 
 // A CheckResult contains the result of checking for a token in the blocklist.
@@ -13,27 +21,106 @@ type CheckResult struct {
 	TTL       int    `json:"block_ttl_sec"` // remaining time-to-live of the token in the blocklist.
 	TTLString string `json:"block_ttl_str"` // human readable remaining time-to-live.
 	IsError   bool   `json:"error"`         // whether or not the result was an error.
+
+	IsFailOpen bool `json:"fail_open,omitempty"` // whether or not the token was allowed because the store is unavailable.
 }
 
 // CheckByJwt checks if a token's hash value is in the blocklist.
 //
 // The passed tokenString will be validated, hashed, and looked up.
-func CheckByJwt(redisDB *redis.Client, tokenString string) (CheckResult, error) {
+func CheckByJwt(redisDB redis.UniversalClient, tokenString string) (CheckResult, error) {
 	// Parse, validate, verify the JWT.
 	var checkResult CheckResult
-	_, err := crypto.RunJwtChecks(tokenString)
+	token, err := crypto.RunJwtChecks(tokenString)
 	if err != nil {
 		checkResult.IsError = true
 		checkResult.Message = err.Error()
 		return checkResult, err
 	}
 
-	key := crypto.Sha256FromString(tokenString)
-	return CheckBySha256(redisDB, key)
+	return CheckToken(redisContext, redisDB, tokenString, token)
+}
+
+// CheckByJwtForRoute checks if a token is in the blocklist, applying the store failure policy.
+//
+// The policy is chosen by the protected route and the token's issuer. The
+// check is traced as part of the context's trace.
+func CheckByJwtForRoute(ctx context.Context, redisDB redis.UniversalClient, tokenString string, route string) (CheckResult, error) {
+	checkResult, _, err := CheckByJwtForRouteWithToken(ctx, redisDB, tokenString, route)
+	return checkResult, err
+}
+
+// CheckByJwtForRouteWithToken is CheckByJwtForRoute, also returning the parsed token for its claims.
+//
+// The token is nil when parsing is disabled or fails. Its claims are only
+// trustworthy when "jwt.verify.enabled" is set.
+func CheckByJwtForRouteWithToken(ctx context.Context, redisDB redis.UniversalClient, tokenString string, route string) (CheckResult, jwt.Token, error) {
+	ctx, span := tracing.Start(ctx, "blocklist.check", trace.WithAttributes(attribute.String("jwtblock.route", route)))
+
+	// Parse, validate, verify the JWT.
+	var checkResult CheckResult
+	token, err := crypto.RunJwtChecksContext(ctx, tokenString)
+	if err != nil {
+		checkResult.IsError = true
+		checkResult.Message = err.Error()
+		tracing.End(span, err)
+		return checkResult, nil, err
+	}
+
+	issuer := ""
+	if token != nil {
+		issuer = token.Issuer()
+	}
+	checkResult, err = CheckToken(ctx, redisDB, tokenString, token)
+	checkResult, err = ApplyFailPolicy(checkResult, err, route, issuer)
+	endCheckSpan(span, checkResult, err)
+	return checkResult, token, err
+}
+
+// CheckTokenHashes checks if an already validated token is in the blocklist, under any of its keys.
+//
+// With HMAC secret rotation, the token is looked up with every configured secret.
+func CheckTokenHashes(ctx context.Context, redisDB redis.UniversalClient, tokenString string) (CheckResult, error) {
+	var checkResult CheckResult
+	var err error
+	for _, key := range crypto.TokenHashes(tokenString) {
+		checkResult, err = checkBySha256(ctx, redisDB, key)
+		if err != nil || checkResult.IsBlocked {
+			return checkResult, err
+		}
+	}
+	return checkResult, nil
+}
+
+// CheckBySha256ForRoute checks if the hash value of a token is in the blocklist, applying the store failure policy.
+func CheckBySha256ForRoute(ctx context.Context, redisDB redis.UniversalClient, sha256 string, route string) (CheckResult, error) {
+	ctx, span := tracing.Start(ctx, "blocklist.check", trace.WithAttributes(attribute.String("jwtblock.route", route)))
+
+	checkResult, err := checkBySha256(ctx, redisDB, sha256)
+	checkResult, err = ApplyFailPolicy(checkResult, err, route, "")
+	endCheckSpan(span, checkResult, err)
+	return checkResult, err
+}
+
+// End the span of a check, with its result.
+func endCheckSpan(span trace.Span, checkResult CheckResult, err error) {
+	span.SetAttributes(
+		attribute.Bool("jwtblock.blocked", checkResult.IsBlocked),
+		attribute.Bool("jwtblock.fail_open", checkResult.IsFailOpen),
+	)
+	tracing.End(span, err)
 }
 
 // CheckBySha256 checks if the hash value of a token is in the blocklist.
-func CheckBySha256(redisDB *redis.Client, sha256 string) (CheckResult, error) {
+//
+// When the local cache is enabled, recent results are served from memory. When
+// the blocked filter is enabled, hashes that are definitely not blocked are
+// allowed without a lookup.
+func CheckBySha256(redisDB redis.UniversalClient, sha256 string) (CheckResult, error) {
+	return checkBySha256(redisContext, redisDB, sha256)
+}
+
+func checkBySha256(ctx context.Context, redisDB redis.UniversalClient, sha256 string) (CheckResult, error) {
 	// Verify the hash.
 	var checkResult CheckResult
 	err := crypto.IsValidSha256(sha256)
@@ -41,15 +128,37 @@ func CheckBySha256(redisDB *redis.Client, sha256 string) (CheckResult, error) {
 		return checkResult, err
 	}
 
+	// Serve from the local cache.
+	localCache := getLocalCache()
+	if localCache != nil {
+		if cachedResult, ok := localCache.get(sha256); ok {
+			metrics.Int("local_cache_hits_total").Add(1)
+			return cachedResult, nil
+		}
+		metrics.Int("local_cache_misses_total").Add(1)
+	}
+
+	// Allow hashes that are definitely absent from the blocked filter.
+	if filter := getBlockedFilter(); filter != nil {
+		if filter.isDefinitelyAbsent(sha256) {
+			metrics.Int("filter_negative_total").Add(1)
+			checkResult.Message = SuccessTokenIsAllowed
+			checkResult.TTL = -1
+			return checkResult, nil
+		}
+		metrics.Int("filter_passthrough_total").Add(1)
+	}
+
 	// Perform lookup.
-	ttl, err := redisDB.TTL(redisContext, sha256).Result()
+	ttl, err := redisDB.TTL(ctx, sha256).Result()
 
 	// Handle errors.
 	if err == redis.Nil {
 		return checkResult, nil
 	} else if err != nil {
-		return checkResult, err
+		return checkResult, fmt.Errorf("%w: %w", ErrStoreUnavailable, err)
 	}
+	markStoreSuccess()
 
 	// Process results.
 	if ttl.Nanoseconds() == -2 {
@@ -75,5 +184,9 @@ func CheckBySha256(redisDB *redis.Client, sha256 string) (CheckResult, error) {
 		checkResult.IsError = false
 	}
 
+	if localCache != nil {
+		localCache.set(sha256, checkResult)
+	}
+
 	return checkResult, nil
 }
@@ -5,7 +5,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 
 	"github.com/divergentcodes/jwtblock/internal/core"
@@ -126,3 +128,79 @@ func Test_CheckByJwt_InvalidToken_BadSignature_Error(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func Test_CheckByJwt_RotatedHmacSecret_Success(t *testing.T) {
+	tokenString := generateTokenStringHS256(60)
+
+	// Set the config, with only the old secret.
+	core.InitConfigDefaults()
+	viper.Set(core.OptStr_JwtParseEnabled, true)
+	viper.Set(core.OptStr_JwtValidateEnabled, false)
+	viper.Set(core.OptStr_JwtVerifyEnabled, false)
+	viper.Set(core.OptStr_HashHmacSecrets, "old")
+	defer viper.Set(core.OptStr_HashHmacSecrets, "")
+
+	redisServer := miniredis.RunT(t)
+	redisDB := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	defer redisDB.Close()
+
+	// Block with the old secret, keyed instead of plain.
+	if _, err := Block(redisDB, tokenString); err != nil {
+		t.Fatalf("Failed to block token: err=%s", err)
+	}
+	if !redisServer.Exists(crypto.HmacSha256FromString("old", tokenString)) || redisServer.Exists(crypto.Sha256FromString(tokenString)) {
+		t.Errorf("Expected token to be stored under its keyed hash: keys=%v", redisServer.Keys())
+	}
+
+	// Rotate to a new secret; the token is still blocked.
+	viper.Set(core.OptStr_HashHmacSecrets, "new,old")
+	checkResult, err := CheckByJwt(redisDB, tokenString)
+	if err != nil || !checkResult.IsBlocked {
+		t.Errorf("Expected token to be blocked after rotation: result=%+v, err=%s", checkResult, err)
+	}
+
+	// Unblock removes the old key.
+	unblockResult, err := UnblockByJwt(redisDB, tokenString)
+	if err != nil || !unblockResult.IsUnblocked || len(redisServer.Keys()) != 0 {
+		t.Errorf("Expected token to be unblocked: result=%+v, keys=%v, err=%s", unblockResult, redisServer.Keys(), err)
+	}
+}
+
+func Test_UnblockByJwt_ReblockedAfterRotation_Unblocked(t *testing.T) {
+	tokenString := generateTokenStringHS256(60)
+
+	// Set the config, with only the old secret.
+	core.InitConfigDefaults()
+	viper.Set(core.OptStr_JwtParseEnabled, true)
+	viper.Set(core.OptStr_JwtValidateEnabled, false)
+	viper.Set(core.OptStr_JwtVerifyEnabled, false)
+	viper.Set(core.OptStr_HashHmacSecrets, "old")
+	defer viper.Set(core.OptStr_HashHmacSecrets, "")
+
+	redisServer := miniredis.RunT(t)
+	redisDB := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	defer redisDB.Close()
+
+	if _, err := Block(redisDB, tokenString); err != nil {
+		t.Fatalf("Failed to block token: err=%s", err)
+	}
+
+	// Rotate to a new secret, and block again; the token is not stored twice.
+	viper.Set(core.OptStr_HashHmacSecrets, "new,old")
+	blockResult, err := Block(redisDB, tokenString)
+	if err != nil || blockResult.IsNew || len(redisServer.Keys()) != 1 {
+		t.Errorf("Expected token to be already blocked: result=%+v, keys=%v, err=%s", blockResult, redisServer.Keys(), err)
+	}
+
+	// Store it under both keys anyway, as before the fix, and unblock.
+	redisServer.Set(crypto.HmacSha256FromString("new", tokenString), "1")
+	unblockResult, err := UnblockByJwt(redisDB, tokenString)
+	if err != nil || !unblockResult.IsUnblocked || len(redisServer.Keys()) != 0 {
+		t.Errorf("Expected token to be unblocked under every key: result=%+v, keys=%v, err=%s", unblockResult, redisServer.Keys(), err)
+	}
+
+	checkResult, err := CheckByJwt(redisDB, tokenString)
+	if err != nil || checkResult.IsBlocked {
+		t.Errorf("Expected token to be allowed after unblock: result=%+v, err=%s", checkResult, err)
+	}
+}
@@ -0,0 +1,92 @@
+package blocklist
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+)
+
+var (
+	ErrClaimNotBlockable = errors.New("claim is not in jwt.block_claims")
+	ErrEmptyClaimValue   = errors.New("empty claim value")
+)
+
+// BlockedClaims returns the claims checked for blocked values, from "jwt.block_claims".
+func BlockedClaims() []string {
+	return core.GetConfig().JWT.BlockClaims
+}
+
+// Check if a claim is checked for blocked values.
+func isBlockedClaim(claim string) bool {
+	for _, blockedClaim := range BlockedClaims() {
+		if blockedClaim == claim {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockClaimWithTTL adds a claim value to the blocklist, blocking every token with the value, e.g. every token of a subject.
+//
+// The claim must be in "jwt.block_claims", or the block would never be
+// checked. explicitTTLSeconds behavior is the same as BlockWithTTL, where <0
+// uses the default TTL.
+func BlockClaimWithTTL(redisDB redis.UniversalClient, claim string, value string, explicitTTLSeconds int) (*BlockResult, error) {
+	result := &BlockResult{
+		TTL:     -1,
+		IsError: false,
+	}
+
+	if !isBlockedClaim(claim) {
+		result.IsError = true
+		result.Message = ErrClaimNotBlockable.Error()
+		return result, ErrClaimNotBlockable
+	}
+	if value == "" {
+		result.IsError = true
+		result.Message = ErrEmptyClaimValue.Error()
+		return result, ErrEmptyClaimValue
+	}
+
+	ttl := time.Duration(core.GetConfig().JWT.TTLDefaultSec) * time.Second
+	if explicitTTLSeconds >= 0 {
+		ttl = time.Duration(explicitTTLSeconds) * time.Second
+	}
+
+	return blockKeys(redisDB, crypto.ClaimHashes(claim, value), ttl, result)
+}
+
+// CheckToken checks if an already validated token is in the blocklist, by its own keys or the keys of its blocked claims.
+//
+// The token is the parsed tokenString, and its claims are not checked when it
+// is nil.
+func CheckToken(ctx context.Context, redisDB redis.UniversalClient, tokenString string, token jwt.Token) (CheckResult, error) {
+	checkResult, err := CheckTokenHashes(ctx, redisDB, tokenString)
+	if err != nil || checkResult.IsBlocked || token == nil {
+		return checkResult, err
+	}
+
+	for _, claim := range BlockedClaims() {
+		value, ok := token.Get(claim)
+		if !ok {
+			continue
+		}
+		stringValue, ok := value.(string)
+		if !ok || stringValue == "" {
+			continue
+		}
+		for _, key := range crypto.ClaimHashes(claim, stringValue) {
+			checkResult, err = checkBySha256(ctx, redisDB, key)
+			if err != nil || checkResult.IsBlocked {
+				return checkResult, err
+			}
+		}
+	}
+	return checkResult, nil
+}
@@ -0,0 +1,139 @@
+package blocklist
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+)
+
+// Policies for the outcome of a check when the blocklist store is unavailable.
+const (
+	FailPolicyOpen   = "open"   // allow the token.
+	FailPolicyClosed = "closed" // deny the token.
+)
+
+// Message of a check result allowed by the fail-open policy.
+var SuccessTokenIsAllowedFailOpen = "JWT is allowed (blocklist store unavailable, fail-open)"
+
+// Time of the last successful blocklist store lookup, in Unix nanoseconds.
+var lastStoreSuccess int64
+
+// Record a successful blocklist store lookup.
+func markStoreSuccess() {
+	now := time.Now()
+	atomic.StoreInt64(&lastStoreSuccess, now.UnixNano())
+	metrics.Int("store_last_success_unix").Set(now.Unix())
+}
+
+// ResolveFailPolicy returns the store failure policy for a protected route and token issuer.
+//
+// Issuer rules match the "iss" claim exactly, and route rules match the
+// longest path prefix. When both an issuer and a route rule apply, closed
+// wins. Without a matching rule, the global policy applies.
+func ResolveFailPolicy(route string, issuer string) string {
+	config := core.GetConfig().StoreFailure
+
+	issuerPolicy := ""
+	if issuer != "" {
+		issuerPolicy = normalizeFailPolicy(config.Issuers[strings.ToLower(issuer)])
+	}
+
+	routePolicy := ""
+	if route != "" {
+		routes := config.Routes
+		prefixes := make([]string, 0, len(routes))
+		for prefix := range routes {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(strings.ToLower(route), prefix) {
+				routePolicy = normalizeFailPolicy(routes[prefix])
+				break
+			}
+		}
+	}
+
+	if issuerPolicy == FailPolicyClosed || routePolicy == FailPolicyClosed {
+		return FailPolicyClosed
+	}
+	if issuerPolicy != "" || routePolicy != "" {
+		return FailPolicyOpen
+	}
+	if policy := normalizeFailPolicy(config.Policy); policy != "" {
+		return policy
+	}
+	return FailPolicyClosed
+}
+
+// Normalize a configured policy, where unknown values are empty.
+func normalizeFailPolicy(policy string) string {
+	switch strings.ToLower(strings.TrimSpace(policy)) {
+	case FailPolicyOpen:
+		return FailPolicyOpen
+	case FailPolicyClosed:
+		return FailPolicyClosed
+	}
+	return ""
+}
+
+// ApplyFailPolicy decides the outcome of a check that failed because the blocklist store is unavailable.
+//
+// Results of checks that succeeded, or failed for another reason (e.g. an
+// invalid token), are returned unchanged. With the fail-open policy, the token
+// is allowed, but only within the stale-allowed window after the last
+// successful store lookup. Otherwise the store error is returned.
+func ApplyFailPolicy(result CheckResult, err error, route string, issuer string) (CheckResult, error) {
+	logger := core.GetLogger()
+
+	if err == nil || !errors.Is(err, ErrStoreUnavailable) {
+		return result, err
+	}
+
+	policy := ResolveFailPolicy(route, issuer)
+	if policy == FailPolicyOpen {
+		staleAllowed := time.Duration(core.GetConfig().StoreFailure.StaleAllowedSec) * time.Second
+		lastSuccess := time.Unix(0, atomic.LoadInt64(&lastStoreSuccess))
+		if staleAllowed < 0 || (lastSuccess.Unix() > 0 && time.Since(lastSuccess) <= staleAllowed) {
+			metrics.Int("store_failure_open_total").Add(1)
+			logger.Warnw(
+				"BLOCKLIST STORE UNAVAILABLE: allowing token by fail-open policy",
+				"func", "blocklist.ApplyFailPolicy",
+				"route", route,
+				"issuer", issuer,
+				"lastStoreSuccess", lastSuccess,
+				"err", err.Error(),
+			)
+			return CheckResult{
+				Message:    SuccessTokenIsAllowedFailOpen,
+				TTL:        -1,
+				IsFailOpen: true,
+			}, nil
+		}
+		logger.Warnw(
+			"Fail-open policy expired, past the stale-allowed window",
+			"func", "blocklist.ApplyFailPolicy",
+			"route", route,
+			"issuer", issuer,
+			"lastStoreSuccess", lastSuccess,
+			"staleAllowed", staleAllowed.String(),
+		)
+	}
+
+	metrics.Int("store_failure_closed_total").Add(1)
+	logger.Errorw(
+		"BLOCKLIST STORE UNAVAILABLE: denying token by fail-closed policy",
+		"func", "blocklist.ApplyFailPolicy",
+		"route", route,
+		"issuer", issuer,
+		"err", err.Error(),
+	)
+	result.IsError = true
+	result.Message = err.Error()
+	return result, err
+}
@@ -0,0 +1,86 @@
+package blocklist
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+)
+
+func Test_ResolveFailPolicy_Rules_Success(t *testing.T) {
+	setupFailPolicy(t, "closed", 300)
+	viper.Set(core.OptStr_StoreFailureRoutes, map[string]string{
+		"/public":       "open",
+		"/public/admin": "closed",
+	})
+	viper.Set(core.OptStr_StoreFailureIssuers, map[string]string{
+		"https://partner.example.com/": "closed",
+		"https://auth.example.com/":    "open",
+	})
+
+	cases := []struct {
+		route    string
+		issuer   string
+		expected string
+	}{
+		{"/private", "", FailPolicyClosed},
+		{"/public/page", "", FailPolicyOpen},
+		{"/public/admin/users", "", FailPolicyClosed},
+		{"/private", "https://auth.example.com/", FailPolicyOpen},
+		{"/public/page", "https://partner.example.com/", FailPolicyClosed},
+	}
+	for _, c := range cases {
+		if policy := ResolveFailPolicy(c.route, c.issuer); policy != c.expected {
+			t.Errorf("Unexpected policy: route=%s, issuer=%s, actual=%s, expected=%s", c.route, c.issuer, policy, c.expected)
+		}
+	}
+}
+
+func Test_ApplyFailPolicy_OpenWithinWindow_Allowed(t *testing.T) {
+	setupFailPolicy(t, "open", 300)
+	markStoreSuccess()
+
+	storeErr := fmt.Errorf("%w: %w", ErrStoreUnavailable, errors.New("connection refused"))
+	result, err := ApplyFailPolicy(CheckResult{}, storeErr, "/", "")
+	if err != nil || result.IsBlocked || !result.IsFailOpen {
+		t.Errorf("Expected fail-open result: result=%+v, err=%v", result, err)
+	}
+}
+
+func Test_ApplyFailPolicy_OpenPastWindow_Denied(t *testing.T) {
+	setupFailPolicy(t, "open", 60)
+	atomic.StoreInt64(&lastStoreSuccess, time.Now().Add(-2*time.Minute).UnixNano())
+
+	storeErr := fmt.Errorf("%w: %w", ErrStoreUnavailable, errors.New("connection refused"))
+	result, err := ApplyFailPolicy(CheckResult{}, storeErr, "/", "")
+	if !errors.Is(err, ErrStoreUnavailable) || result.IsFailOpen || !result.IsError {
+		t.Errorf("Expected store error past the stale-allowed window: result=%+v, err=%v", result, err)
+	}
+}
+
+func Test_ApplyFailPolicy_OtherErrors_Unchanged(t *testing.T) {
+	setupFailPolicy(t, "open", -1)
+
+	_, err := ApplyFailPolicy(CheckResult{}, crypto.ErrMalformedSha256, "/", "")
+	if !errors.Is(err, crypto.ErrMalformedSha256) {
+		t.Errorf("Expected validation error to be returned: err=%v", err)
+	}
+}
+
+// Set the global store failure policy for the test.
+func setupFailPolicy(t *testing.T, policy string, staleAllowedSec int) {
+	viper.Set(core.OptStr_StoreFailurePolicy, policy)
+	viper.Set(core.OptStr_StoreFailureStaleAllowedSec, staleAllowedSec)
+	t.Cleanup(func() {
+		viper.Set(core.OptStr_StoreFailurePolicy, "closed")
+		viper.Set(core.OptStr_StoreFailureRoutes, map[string]string{})
+		viper.Set(core.OptStr_StoreFailureIssuers, map[string]string{})
+		atomic.StoreInt64(&lastStoreSuccess, 0)
+	})
+}
@@ -0,0 +1,264 @@
+package blocklist
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/divergentcodes/jwtblock/internal/bloom"
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+)
+
+var filterOnce sync.Once
+var blockedHashFilter *blockedFilter
+
+// A blockedFilter is a Bloom filter of the blocked token hashes.
+//
+// Checks for hashes that are definitely absent from the filter are allowed
+// without a lookup. The filter only answers while it is in sync with the
+// blocklist, and is rebuilt periodically to drop unblocked and expired hashes.
+type blockedFilter struct {
+	mu       sync.RWMutex
+	current  *bloom.Filter // nil until built, or while out of sync.
+	building bool
+	pending  []string // changes received while building, replayed after the build.
+	inSync   bool
+	gen      uint64 // incremented when out of sync, so older rebuilds are discarded.
+	capacity uint64
+	fpRate   float64
+	interval time.Duration
+
+	rebuildMu  sync.Mutex
+	rebuildNow chan struct{} // requests an early rebuild.
+}
+
+func newBlockedFilter(capacity uint64, fpRate float64, interval time.Duration) *blockedFilter {
+	return &blockedFilter{
+		capacity:   capacity,
+		fpRate:     fpRate,
+		interval:   interval,
+		rebuildNow: make(chan struct{}, 1),
+	}
+}
+
+// Get a singleton of the blocked filter, or nil if it is disabled.
+func getBlockedFilter() *blockedFilter {
+	filterOnce.Do(func() {
+		config := core.GetConfig().Cache
+		if !config.FilterEnabled {
+			return
+		}
+		blockedHashFilter = newBlockedFilter(
+			uint64(config.FilterCapacity),
+			config.FilterFPRate,
+			time.Duration(config.FilterRebuildInterval)*time.Second,
+		)
+		blockedHashFilter.publishMetrics()
+	})
+	return blockedHashFilter
+}
+
+// Add a blocked hash to the filter, and to the filter being built.
+func (f *blockedFilter) add(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.current != nil {
+		f.current.Add(key)
+	}
+	if f.building {
+		f.pending = append(f.pending, key)
+	}
+}
+
+// Test if a hash is definitely not blocked.
+//
+// Returns false when the hash is possibly blocked, or the filter cannot be trusted.
+func (f *blockedFilter) isDefinitelyAbsent(key string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if !f.inSync || f.current == nil {
+		return false
+	}
+	return !f.current.Test(key)
+}
+
+// Stop answering from the filter, until it is resynced.
+func (f *blockedFilter) markOutOfSync() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.inSync = false
+	f.current = nil
+	f.gen++
+}
+
+// Rebuild the filter and start answering from it again.
+func (f *blockedFilter) resync(ctx context.Context, redisDB redis.UniversalClient) error {
+	f.mu.Lock()
+	f.inSync = true
+	f.mu.Unlock()
+
+	return f.rebuild(ctx, redisDB)
+}
+
+// Build a new filter by scanning the blocklist, and swap it in.
+//
+// Changes received during the scan are applied to the current filter, and
+// replayed on the new filter before it is swapped in.
+func (f *blockedFilter) rebuild(ctx context.Context, redisDB redis.UniversalClient) error {
+	logger := core.GetLogger()
+
+	f.rebuildMu.Lock()
+	defer f.rebuildMu.Unlock()
+	start := time.Now()
+
+	// Leave headroom for growth until the next rebuild.
+	capacity := f.capacity
+	size, err := Size(redisDB)
+	if err != nil {
+		metrics.Int("filter_rebuild_errors_total").Add(1)
+		return err
+	}
+	if uint64(size)*2 > capacity {
+		capacity = uint64(size) * 2
+	}
+	next := bloom.New(capacity, f.fpRate)
+
+	gen := f.beginBuild()
+	if err := scanKeys(ctx, redisDB, next.Add); err != nil {
+		f.finishBuild(nil, gen)
+		metrics.Int("filter_rebuild_errors_total").Add(1)
+		return err
+	}
+	f.finishBuild(next, gen)
+
+	elapsed := time.Since(start)
+	metrics.Int("filter_rebuilds_total").Add(1)
+	metrics.Int("filter_last_rebuild_unix").Set(time.Now().Unix())
+	metrics.Int("filter_last_rebuild_ms").Set(elapsed.Milliseconds())
+
+	logger.Debugw(
+		"Rebuilt blocked filter",
+		"func", "blocklist.rebuild",
+		"keys", next.Count(),
+		"bits", next.Bits(),
+		"elapsed", elapsed.String(),
+	)
+
+	return nil
+}
+
+// Start collecting the changes received during a build, and return the generation of the build.
+func (f *blockedFilter) beginBuild() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.building = true
+	f.pending = nil
+	return f.gen
+}
+
+// Replay the changes received during a build on the built filter, and swap it in.
+//
+// The built filter is nil when the build failed. It is discarded when the
+// filter went out of sync during the build.
+func (f *blockedFilter) finishBuild(next *bloom.Filter, gen uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if next != nil {
+		for _, key := range f.pending {
+			next.Add(key)
+		}
+		if f.inSync && f.gen == gen {
+			f.current = next
+		}
+	}
+	f.building = false
+	f.pending = nil
+}
+
+// Request a rebuild before the next interval, e.g. after changes may have been missed.
+func (f *blockedFilter) requestRebuild() {
+	select {
+	case f.rebuildNow <- struct{}{}:
+	default: // a rebuild is already requested.
+	}
+}
+
+// Rebuild the filter at the configured interval, or when requested, until the context is done.
+func (f *blockedFilter) rebuildPeriodically(ctx context.Context, client *changesClient) {
+	logger := core.GetLogger()
+
+	var tick <-chan time.Time
+	if f.interval > 0 {
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-f.rebuildNow:
+			metrics.Int("filter_early_rebuilds_total").Add(1)
+		case <-tick:
+		}
+		if err := f.rebuild(ctx, client.get()); err != nil {
+			logger.Warnw(
+				"Failed to rebuild blocked filter",
+				"func", "blocklist.rebuildPeriodically",
+				"err", err.Error(),
+			)
+		}
+	}
+}
+
+// Expose the filter state as metrics.
+func (f *blockedFilter) publishMetrics() {
+	metrics.Float("filter_fp_rate_target").Set(f.fpRate)
+	metrics.Int("filter_rebuild_interval_sec").Set(int64(f.interval.Seconds()))
+
+	// Read the filter state when the metrics are read.
+	current := func() *bloom.Filter {
+		f.mu.RLock()
+		defer f.mu.RUnlock()
+		if !f.inSync {
+			return nil
+		}
+		return f.current
+	}
+	metrics.Func("filter_ready", func() interface{} {
+		return current() != nil
+	})
+	metrics.Func("filter_keys", func() interface{} {
+		if filter := current(); filter != nil {
+			return filter.Count()
+		}
+		return 0
+	})
+	metrics.Func("filter_bits", func() interface{} {
+		if filter := current(); filter != nil {
+			return filter.Bits()
+		}
+		return 0
+	})
+	metrics.Func("filter_hashes", func() interface{} {
+		if filter := current(); filter != nil {
+			return filter.Hashes()
+		}
+		return 0
+	})
+	metrics.Func("filter_fp_rate_estimated", func() interface{} {
+		if filter := current(); filter != nil {
+			return filter.EstimatedFPRate()
+		}
+		return 0
+	})
+}
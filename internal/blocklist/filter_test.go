@@ -0,0 +1,175 @@
+package blocklist
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/divergentcodes/jwtblock/internal/bloom"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+)
+
+func Test_CheckBySha256_BlockedFilter_Success(t *testing.T) {
+	f := setupBlockedFilter(t)
+	redisServer := miniredis.RunT(t)
+	redisDB := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	defer redisDB.Close()
+
+	blockedHash := crypto.Sha256FromString("blocked")
+	allowedHash := crypto.Sha256FromString("allowed")
+	if _, err := BlockBySha256WithTTL(redisDB, blockedHash, 60); err != nil {
+		t.Fatalf("Failed to block hash: err=%s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := SubscribeChanges(ctx, redisDB); err != nil {
+		t.Fatalf("Failed to subscribe: err=%s", err)
+	}
+
+	if !f.isDefinitelyAbsent(allowedHash) || f.isDefinitelyAbsent(blockedHash) {
+		t.Errorf("Expected filter to be built from the blocklist")
+	}
+
+	// The allowed hash is answered by the filter, even when Redis is down.
+	redisServer.Close()
+	result, err := CheckBySha256(redisDB, allowedHash)
+	if err != nil || result.IsBlocked || result.Message != SuccessTokenIsAllowed {
+		t.Errorf("Expected allowed result from the filter: result=%+v, err=%v", result, err)
+	}
+}
+
+func Test_BlockedFilter_ChangesAndResync_Success(t *testing.T) {
+	f := setupBlockedFilter(t)
+	redisServer := miniredis.RunT(t)
+	redisDB := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	publisher := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	defer redisDB.Close()
+	defer publisher.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := SubscribeChanges(ctx, redisDB); err != nil {
+		t.Fatalf("Failed to subscribe: err=%s", err)
+	}
+
+	// A block from another process is added to the filter.
+	blockedHash := crypto.Sha256FromString("blocked")
+	if err := publisher.Set(ctx, blockedHash, true, time.Minute).Err(); err != nil {
+		t.Fatalf("Failed to block hash: err=%s", err)
+	}
+	if err := publisher.Publish(ctx, "jwtblock:invalidate", blockedHash).Err(); err != nil {
+		t.Fatalf("Failed to publish change: err=%s", err)
+	}
+	waitFor(t, "blocked hash in the filter", func() bool { return !f.isDefinitelyAbsent(blockedHash) })
+
+	// The filter is not used while changes can be missed.
+	allowedHash := crypto.Sha256FromString("allowed")
+	redisServer.Close()
+	waitFor(t, "filter out of sync", func() bool { return !f.isDefinitelyAbsent(allowedHash) })
+
+	// The filter is rebuilt when the subscription is restored.
+	if err := redisServer.Restart(); err != nil {
+		t.Fatalf("Failed to restart Redis: err=%s", err)
+	}
+	waitFor(t, "filter resynced", func() bool { return f.isDefinitelyAbsent(allowedHash) })
+}
+
+func Test_BlockedFilter_ChangeDuringBuild_Replayed(t *testing.T) {
+	f := newBlockedFilter(1000, 0.001, 0)
+	f.inSync = true
+	blockedHash := crypto.Sha256FromString("blocked")
+
+	// A change received after the scan passed its key.
+	gen := f.beginBuild()
+	f.add(blockedHash)
+	f.finishBuild(bloom.New(1000, 0.001), gen)
+
+	if f.isDefinitelyAbsent(blockedHash) {
+		t.Errorf("Expected the change received during the build to be replayed")
+	}
+	if !f.isDefinitelyAbsent(crypto.Sha256FromString("allowed")) {
+		t.Errorf("Expected the filter to answer after the build")
+	}
+}
+
+func Test_publishChange_PublishFailed_RebuildRequested(t *testing.T) {
+	f := setupBlockedFilter(t)
+	redisServer := miniredis.RunT(t)
+	redisDB := redis.NewClient(&redis.Options{Addr: redisServer.Addr(), MaxRetries: -1})
+	defer redisDB.Close()
+	redisServer.Close()
+
+	failures := metrics.Int("changes_publish_errors_total").Value()
+	publishChange(redisDB, crypto.Sha256FromString("blocked"))
+
+	if actual := metrics.Int("changes_publish_errors_total").Value(); actual != failures+1 {
+		t.Errorf("Expected %d publish errors, got %d", failures+1, actual)
+	}
+	if len(f.rebuildNow) != 1 {
+		t.Errorf("Expected an early filter rebuild to be requested")
+	}
+}
+
+func Test_retryResyncChanges_StoreUnavailable_Retried(t *testing.T) {
+	f := setupBlockedFilter(t)
+	redisServer := miniredis.RunT(t)
+	redisDB := redis.NewClient(&redis.Options{Addr: redisServer.Addr(), MaxRetries: -1})
+	defer redisDB.Close()
+	redisServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	failures := metrics.Int("changes_resync_failures_total").Value()
+	resynced := make(chan error, 1)
+	go func() {
+		resynced <- retryResyncChanges(ctx, &changesClient{client: redisDB})
+	}()
+	waitFor(t, "a failed resync", func() bool {
+		return metrics.Int("changes_resync_failures_total").Value() > failures
+	})
+	allowedHash := crypto.Sha256FromString("allowed")
+	if f.isDefinitelyAbsent(allowedHash) {
+		t.Errorf("Expected the filter to be out of sync while the resync fails")
+	}
+
+	// The resync is retried until the store is back.
+	if err := redisServer.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-resynced:
+		if err != nil || !f.isDefinitelyAbsent(allowedHash) {
+			t.Errorf("Expected the filter to be resynced: err=%v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the resync")
+	}
+}
+
+// Use an enabled blocked filter for the test.
+func setupBlockedFilter(t *testing.T) *blockedFilter {
+	setupLocalCache(t, nil)
+	changesRetryDelay = 10 * time.Millisecond
+	filterOnce.Do(func() {})
+	blockedHashFilter = newBlockedFilter(1000, 0.001, 0)
+	t.Cleanup(func() {
+		blockedHashFilter = nil
+	})
+	return blockedHashFilter
+}
+
+// Wait up to a second for a condition.
+func waitFor(t *testing.T, description string, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", description)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
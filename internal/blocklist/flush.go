@@ -1,6 +1,8 @@
 package blocklist
 
 import (
+	"context"
+
 	"github.com/redis/go-redis/v9"
 
 	"github.com/divergentcodes/jwtblock/internal/core"
@@ -14,7 +16,7 @@ type FlushResult struct {
 }
 
 // Flush empties the blocklist cache of all tokens, so none are blocked.
-func Flush(redisDB *redis.Client) (*FlushResult, error) {
+func Flush(redisDB redis.UniversalClient) (*FlushResult, error) {
 	logger := core.GetLogger()
 	result := &FlushResult{
 		Count:   -1,
@@ -28,14 +30,18 @@ func Flush(redisDB *redis.Client) (*FlushResult, error) {
 		return result, err
 	}
 
-	// Flush the cache.
-	flushResult, err := redisDB.FlushDB(redisContext).Result()
+	// Flush the cache on every master node.
+	flushResult := "OK"
+	err = forEachMaster(redisContext, redisDB, func(ctx context.Context, node redis.Cmdable) error {
+		return node.FlushDB(ctx).Err()
+	})
 	if err != nil {
 		result.IsError = true
 		return result, err
 	}
 	result.Message = flushResult
 	result.Count = count
+	publishChange(redisDB, changeAll)
 
 	logger.Infow(
 		"Flushed the blocklist",
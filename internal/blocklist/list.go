@@ -14,7 +14,7 @@ type ListResult struct {
 }
 
 // List will dump all token hashes in the cache.
-func List(redisDB *redis.Client) (*ListResult, error) {
+func List(redisDB redis.UniversalClient) (*ListResult, error) {
 	logger := core.GetLogger()
 	result := &ListResult{
 		Size:    -1,
@@ -27,10 +27,15 @@ func List(redisDB *redis.Client) (*ListResult, error) {
 		return result, err
 	}
 
-	// Keys are the token hashes.
-	// Getting token hashes just requires calling "KEYS."
-	cacheKeys := redisDB.Keys(redisContext, "*")
-	result.TokenHashes = cacheKeys.Val()
+	// Keys are the token hashes, scanned on every master node.
+	result.TokenHashes = make([]string, 0, size)
+	err = scanKeys(redisContext, redisDB, func(key string) {
+		result.TokenHashes = append(result.TokenHashes, key)
+	})
+	if err != nil {
+		result.IsError = true
+		return result, err
+	}
 	result.Size = size
 
 	logger.Infow("Listed token hashes in the blocklist", "size", result.Size)
@@ -0,0 +1,152 @@
+package blocklist
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+)
+
+var localCacheOnce sync.Once
+var checkCache *localCache
+
+// A localCache is a bounded, in-process LRU cache of check results with short TTLs.
+//
+// Blocked and allowed results have separate TTLs, which bound how long a
+// missed invalidation can serve a stale result.
+type localCache struct {
+	mu         sync.Mutex
+	size       int
+	ttlBlocked time.Duration
+	ttlAllowed time.Duration
+	entries    map[string]*list.Element
+	order      *list.List // most recently used at the front.
+}
+
+type localCacheEntry struct {
+	key          string
+	result       CheckResult
+	blockedUntil time.Time // zero when the result is allowed or blocked without expiration.
+	expiresAt    time.Time
+}
+
+func newLocalCache(size int, ttlBlocked time.Duration, ttlAllowed time.Duration) *localCache {
+	return &localCache{
+		size:       size,
+		ttlBlocked: ttlBlocked,
+		ttlAllowed: ttlAllowed,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// Get a singleton of the local cache, or nil if it is disabled.
+func getLocalCache() *localCache {
+	localCacheOnce.Do(func() {
+		config := core.GetConfig().Cache
+		if !config.LocalEnabled {
+			return
+		}
+		checkCache = newLocalCache(
+			config.LocalSize,
+			time.Duration(config.LocalTTLBlockedMs)*time.Millisecond,
+			time.Duration(config.LocalTTLAllowedMs)*time.Millisecond,
+		)
+		metrics.Func("local_cache_size", func() interface{} { return checkCache.len() })
+	})
+	return checkCache
+}
+
+// Get an unexpired check result, with the remaining block TTL brought up to date.
+func (c *localCache) get(key string) (CheckResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return CheckResult{}, false
+	}
+	entry := element.Value.(*localCacheEntry)
+	now := time.Now()
+	if now.After(entry.expiresAt) || (!entry.blockedUntil.IsZero() && now.After(entry.blockedUntil)) {
+		c.removeElement(element)
+		return CheckResult{}, false
+	}
+	c.order.MoveToFront(element)
+
+	result := entry.result
+	if !entry.blockedUntil.IsZero() {
+		ttl := time.Until(entry.blockedUntil).Truncate(time.Second)
+		result.TTL = int(ttl.Seconds())
+		result.TTLString = ttl.String()
+	}
+	return result, true
+}
+
+// Store a check result, evicting the least recently used entry when full.
+func (c *localCache) set(key string, result CheckResult) {
+	ttl := c.ttlAllowed
+	if result.IsBlocked {
+		ttl = c.ttlBlocked
+	}
+	if ttl <= 0 || c.size <= 0 {
+		return
+	}
+
+	now := time.Now()
+	entry := &localCacheEntry{
+		key:       key,
+		result:    result,
+		expiresAt: now.Add(ttl),
+	}
+	if result.IsBlocked && result.TTL > 0 {
+		entry.blockedUntil = now.Add(time.Duration(result.TTL) * time.Second)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// Remove a single entry.
+func (c *localCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.removeElement(element)
+	}
+}
+
+// Remove every entry.
+func (c *localCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+// Get the number of entries.
+func (c *localCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *localCache) removeElement(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*localCacheEntry).key)
+}
@@ -0,0 +1,152 @@
+package blocklist
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/cache"
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+)
+
+func Test_LocalCache_LRUEviction_Success(t *testing.T) {
+	c := newLocalCache(2, time.Minute, time.Minute)
+	allowed := CheckResult{Message: SuccessTokenIsAllowed, TTL: -1}
+
+	c.set("a", allowed)
+	c.set("b", allowed)
+	c.get("a")
+	c.set("c", allowed)
+
+	if _, ok := c.get("b"); ok {
+		t.Errorf("Expected least recently used entry to be evicted")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Errorf("Expected recently used entry to be kept")
+	}
+	if c.len() != 2 {
+		t.Errorf("Unexpected local cache size: actual=%d, expected=%d", c.len(), 2)
+	}
+}
+
+func Test_LocalCache_Expiration_Success(t *testing.T) {
+	c := newLocalCache(10, time.Minute, time.Millisecond)
+	c.set("allowed", CheckResult{Message: SuccessTokenIsAllowed, TTL: -1})
+	c.set("blocked", CheckResult{Message: SuccessTokenIsBlocked, IsBlocked: true, TTL: 60})
+
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := c.get("allowed"); ok {
+		t.Errorf("Expected allowed entry to expire")
+	}
+	result, ok := c.get("blocked")
+	if !ok || !result.IsBlocked || result.TTL > 60 || result.TTL < 59 {
+		t.Errorf("Expected blocked entry with a current TTL: result=%+v", result)
+	}
+}
+
+func Test_CheckBySha256_LocalCache_Hit_Success(t *testing.T) {
+	setupLocalCache(t, newLocalCache(10, time.Minute, time.Minute))
+	tokenHash := crypto.Sha256FromString("foo")
+	redisDB, redisMock := redismock.NewClientMock()
+
+	// Only the first lookup reaches Redis.
+	redisMock.ExpectTTL(tokenHash).SetVal(time.Minute)
+	for i := 0; i < 3; i++ {
+		result, err := CheckBySha256(redisDB, tokenHash)
+		if err != nil || !result.IsBlocked {
+			t.Errorf("Expected blocked result: result=%+v, err=%v", result, err)
+		}
+	}
+
+	// Verify all expected Redis commands and results happened.
+	if err := redisMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_LocalCache_PubSubInvalidation_Success(t *testing.T) {
+	c := newLocalCache(10, time.Minute, time.Minute)
+	setupLocalCache(t, c)
+	redisServer := miniredis.RunT(t)
+	publisher := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	subscriber := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	defer publisher.Close()
+	defer subscriber.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := SubscribeChanges(ctx, subscriber); err != nil {
+		t.Fatalf("Failed to subscribe: err=%s", err)
+	}
+
+	// Cache an allowed result, then publish a change from another client.
+	tokenHash := crypto.Sha256FromString("foo")
+	c.set(tokenHash, CheckResult{Message: SuccessTokenIsAllowed, TTL: -1})
+	err := publisher.Publish(ctx, "jwtblock:invalidate", tokenHash).Err()
+	if err != nil {
+		t.Fatalf("Failed to publish change: err=%s", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for c.len() != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if c.len() != 0 {
+		t.Errorf("Expected local cache entry to be invalidated")
+	}
+}
+
+func Test_SubscribeChanges_RedisReconnected_Resubscribed(t *testing.T) {
+	c := newLocalCache(10, time.Minute, time.Minute)
+	setupLocalCache(t, c)
+	changesRetryDelay = 10 * time.Millisecond
+	redisServer := miniredis.RunT(t)
+	viper.Set(core.OptStr_RedisHost, redisServer.Host())
+	viper.Set(core.OptStr_RedisPort, redisServer.Port())
+	defer viper.Set(core.OptStr_RedisHost, nil)
+	defer viper.Set(core.OptStr_RedisPort, nil)
+	publisher := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	defer publisher.Close()
+	cache.SetRedisClient(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+	defer cache.SetRedisClient(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := SubscribeChanges(ctx, cache.GetRedisClient()); err != nil {
+		t.Fatalf("Failed to subscribe: err=%s", err)
+	}
+
+	// The old client is closed, and the subscription moves to the new one.
+	resyncs := metrics.Int("changes_resyncs_total").Value()
+	cache.ReconnectRedisClient()
+	waitFor(t, "the resubscription", func() bool {
+		return metrics.Int("changes_resyncs_total").Value() > resyncs
+	})
+
+	tokenHash := crypto.Sha256FromString("foo")
+	c.set(tokenHash, CheckResult{Message: SuccessTokenIsAllowed, TTL: -1})
+	if err := publisher.Publish(ctx, "jwtblock:invalidate", tokenHash).Err(); err != nil {
+		t.Fatalf("Failed to publish change: err=%s", err)
+	}
+	waitFor(t, "the local cache invalidation", func() bool {
+		return c.len() == 0
+	})
+}
+
+// Use the given local cache instead of the configured one for the test.
+func setupLocalCache(t *testing.T, c *localCache) {
+	viper.Set(core.OptStr_LocalCacheChannel, "jwtblock:invalidate")
+	localCacheOnce.Do(func() {})
+	checkCache = c
+	t.Cleanup(func() {
+		checkCache = nil
+	})
+}
@@ -6,8 +6,6 @@ package blocklist
 import (
 	"context"
 	"errors"
-
-	"github.com/divergentcodes/jwtblock/internal/core"
 )
 
 var (
@@ -22,8 +20,6 @@ var (
 
 	ErrMisconfiguredCache = errors.New("server cache configuration error")
 	ErrNoExpForTTL        = errors.New("token has no set expiration")
+	ErrStoreUnavailable   = errors.New("blocklist store unavailable")
+	ErrNoChangeChannel    = errors.New("cache.local.channel is required for the local cache and blocked filter")
 )
-
-func init() {
-	core.InitConfigDefaults()
-}
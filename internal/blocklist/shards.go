@@ -0,0 +1,37 @@
+package blocklist
+
+import (
+	"context"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Number of keys requested per SCAN call.
+const scanCount = 1000
+
+// Run a function on every master node, which is just the client itself unless it is a Redis Cluster client.
+//
+// With a Redis Cluster, the function runs concurrently on each master.
+func forEachMaster(ctx context.Context, redisDB redis.UniversalClient, fn func(ctx context.Context, node redis.Cmdable) error) error {
+	if cluster, ok := redisDB.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return fn(ctx, node)
+		})
+	}
+	return fn(ctx, redisDB)
+}
+
+// Scan every key in the blocklist, across all master nodes.
+func scanKeys(ctx context.Context, redisDB redis.UniversalClient, fn func(key string)) error {
+	var mu sync.Mutex
+	return forEachMaster(ctx, redisDB, func(ctx context.Context, node redis.Cmdable) error {
+		iter := node.Scan(ctx, 0, "*", scanCount).Iterator()
+		for iter.Next(ctx) {
+			mu.Lock()
+			fn(iter.Val())
+			mu.Unlock()
+		}
+		return iter.Err()
+	})
+}
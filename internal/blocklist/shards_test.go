@@ -0,0 +1,47 @@
+package blocklist
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+)
+
+func Test_ListSizeFlush_ClusterClient_Success(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+	redisDB := redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs: []string{redisServer.Addr()},
+	})
+	defer redisDB.Close()
+
+	hashes := []string{crypto.Sha256FromString("foo"), crypto.Sha256FromString("bar")}
+	sort.Strings(hashes)
+	for _, hash := range hashes {
+		if _, err := BlockBySha256WithTTL(redisDB, hash, 60); err != nil {
+			t.Fatalf("Failed to block hash: err=%s", err)
+		}
+	}
+
+	// List scans every master node.
+	listResult, err := List(redisDB)
+	if err != nil {
+		t.Fatalf("Failed to list: err=%s", err)
+	}
+	sort.Strings(listResult.TokenHashes)
+	if listResult.Size != 2 || len(listResult.TokenHashes) != 2 || listResult.TokenHashes[0] != hashes[0] {
+		t.Errorf("Unexpected list result: result=%+v", listResult)
+	}
+
+	// Flush empties every master node.
+	flushResult, err := Flush(redisDB)
+	if err != nil || flushResult.Count != 2 {
+		t.Errorf("Unexpected flush result: result=%+v, err=%v", flushResult, err)
+	}
+	size, err := Size(redisDB)
+	if err != nil || size != 0 {
+		t.Errorf("Expected empty blocklist: size=%d, err=%v", size, err)
+	}
+}
@@ -1,12 +1,23 @@
 package blocklist
 
 import (
+	"context"
+	"sync/atomic"
+
 	"github.com/redis/go-redis/v9"
 )
 
-// Size will return the number of token hashes in the blocklist.
-func Size(redisDB *redis.Client) (int64, error) {
-	count, err := redisDB.DBSize(redisContext).Result()
+// Size will return the number of token hashes in the blocklist, across all master nodes.
+func Size(redisDB redis.UniversalClient) (int64, error) {
+	var count int64
+	err := forEachMaster(redisContext, redisDB, func(ctx context.Context, node redis.Cmdable) error {
+		nodeCount, err := node.DBSize(ctx).Result()
+		if err != nil {
+			return err
+		}
+		atomic.AddInt64(&count, nodeCount)
+		return nil
+	})
 	if err != nil {
 		return 0, err
 	}
@@ -0,0 +1,19 @@
+package blocklist
+
+import (
+	"github.com/redis/go-redis/v9"
+)
+
+// A StatusResult contains the status of the blocklist.
+type StatusResult struct {
+	Size int64 `json:"size"` // the number of blocked tokens.
+}
+
+// Status returns the current status of the blocklist.
+func Status(redisDB redis.UniversalClient) (*StatusResult, error) {
+	size, err := Size(redisDB)
+	if err != nil {
+		return &StatusResult{Size: -1}, err
+	}
+	return &StatusResult{Size: size}, nil
+}
@@ -1,6 +1,8 @@
 package blocklist
 
 import (
+	"fmt"
+
 	"github.com/redis/go-redis/v9"
 
 	"github.com/divergentcodes/jwtblock/internal/crypto"
@@ -14,7 +16,9 @@ type UnblockResult struct {
 }
 
 // UnblockByJwt removes a token's hash from the blocklist by first hashing the passed token.
-func UnblockByJwt(redisDB *redis.Client, tokenString string) (*UnblockResult, error) {
+//
+// With HMAC secret rotation, the token is removed under any of its keys.
+func UnblockByJwt(redisDB redis.UniversalClient, tokenString string) (*UnblockResult, error) {
 	result := &UnblockResult{
 		IsError: false,
 	}
@@ -27,12 +31,26 @@ func UnblockByJwt(redisDB *redis.Client, tokenString string) (*UnblockResult, er
 		return result, err
 	}
 
-	key := crypto.Sha256FromString(tokenString)
-	return UnblockBySha256(redisDB, key)
+	// Remove the token under every key it may be stored under, since it may be stored under several.
+	isUnblocked := false
+	for _, key := range crypto.TokenHashes(tokenString) {
+		result, err = UnblockBySha256(redisDB, key)
+		if err != nil {
+			return result, err
+		}
+		isUnblocked = isUnblocked || result.IsUnblocked
+	}
+
+	result.IsUnblocked = isUnblocked
+	result.Message = SuccessTokenUnblocked
+	if !result.IsUnblocked {
+		result.Message = SuccessTokenNotExists
+	}
+	return result, nil
 }
 
 // UnblockBySha256 removes the passed token hash from the blocklist.
-func UnblockBySha256(redisDB *redis.Client, sha256 string) (*UnblockResult, error) {
+func UnblockBySha256(redisDB redis.UniversalClient, sha256 string) (*UnblockResult, error) {
 	result := &UnblockResult{
 		IsError: false,
 	}
@@ -47,15 +65,15 @@ func UnblockBySha256(redisDB *redis.Client, sha256 string) (*UnblockResult, erro
 	// Allow a JWT by removing the SHA256 from the blocklist.
 	status, err := redisDB.Del(redisContext, sha256).Result()
 	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrStoreUnavailable, err)
 		result.Message = err.Error()
 		result.IsError = true
 		return result, err
 	}
 
-	if status == 1 {
-		result.Message = err.Error()
-		result.IsError = true
-		return result, nil
+	result.IsUnblocked = status == 1
+	if result.IsUnblocked {
+		publishChange(redisDB, sha256)
 	}
 
 	result.Message = SuccessTokenUnblocked
@@ -0,0 +1,124 @@
+// Package bloom implements a concurrent Bloom filter for string keys.
+//
+// A Bloom filter answers "definitely absent" or "possibly present", with a
+// bounded false-positive rate for the capacity it was sized for.
+package bloom
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// A Filter is a Bloom filter sized for an expected number of keys and false-positive rate.
+//
+// A Filter is safe for concurrent use.
+type Filter struct {
+	mu     sync.RWMutex
+	bits   []uint64
+	m      uint64 // number of bits.
+	k      uint64 // number of hash functions.
+	count  uint64 // number of added keys, including duplicates.
+	fpRate float64
+}
+
+// New creates a filter for the given capacity and false-positive rate.
+func New(capacity uint64, fpRate float64) *Filter {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+
+	// Optimal number of bits and hash functions.
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Round(float64(m) / float64(capacity) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &Filter{
+		bits:   make([]uint64, (m+63)/64),
+		m:      m,
+		k:      k,
+		fpRate: fpRate,
+	}
+}
+
+// Add a key to the filter.
+func (f *Filter) Add(key string) {
+	h1, h2 := hashes(key)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+	f.count++
+}
+
+// Test if a key is possibly in the filter. A false result means the key was never added.
+func (f *Filter) Test(key string) bool {
+	h1, h2 := hashes(key)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Count returns the number of keys added, including duplicates.
+func (f *Filter) Count() uint64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.count
+}
+
+// Bits returns the size of the filter in bits.
+func (f *Filter) Bits() uint64 {
+	return f.m
+}
+
+// Hashes returns the number of hash functions.
+func (f *Filter) Hashes() uint64 {
+	return f.k
+}
+
+// TargetFPRate returns the false-positive rate the filter was sized for.
+func (f *Filter) TargetFPRate() float64 {
+	return f.fpRate
+}
+
+// EstimatedFPRate returns the current false-positive rate, estimated from the added keys.
+func (f *Filter) EstimatedFPRate() float64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return math.Pow(1-math.Exp(-float64(f.k)*float64(f.count)/float64(f.m)), float64(f.k))
+}
+
+// Two independent hashes for double hashing.
+func hashes(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h1 := h.Sum64()
+
+	h = fnv.New64()
+	h.Write([]byte(key))
+	h2 := h.Sum64() | 1 // odd, so the probes cover every bit.
+
+	return h1, h2
+}
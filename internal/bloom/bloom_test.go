@@ -0,0 +1,43 @@
+package bloom
+
+import (
+	"fmt"
+	"testing"
+)
+
+func Test_Filter_AddedKeys_Present(t *testing.T) {
+	f := New(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		f.Add(fmt.Sprintf("key-%d", i))
+	}
+	for i := 0; i < 1000; i++ {
+		if !f.Test(fmt.Sprintf("key-%d", i)) {
+			t.Fatalf("Expected added key to be present: key=key-%d", i)
+		}
+	}
+	if f.Count() != 1000 {
+		t.Errorf("Unexpected count: actual=%d, expected=%d", f.Count(), 1000)
+	}
+}
+
+func Test_Filter_FalsePositiveRate_WithinBounds(t *testing.T) {
+	f := New(10000, 0.01)
+	for i := 0; i < 10000; i++ {
+		f.Add(fmt.Sprintf("key-%d", i))
+	}
+
+	falsePositives := 0
+	trials := 100000
+	for i := 0; i < trials; i++ {
+		if f.Test(fmt.Sprintf("other-%d", i)) {
+			falsePositives++
+		}
+	}
+	rate := float64(falsePositives) / float64(trials)
+	if rate > 0.02 {
+		t.Errorf("False-positive rate too high: actual=%f, target=%f", rate, f.TargetFPRate())
+	}
+	if estimated := f.EstimatedFPRate(); estimated > 0.02 {
+		t.Errorf("Estimated false-positive rate too high: estimated=%f", estimated)
+	}
+}
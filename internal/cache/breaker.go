@@ -0,0 +1,230 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+)
+
+// ErrCircuitOpen is returned without contacting Redis while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("redis circuit breaker is open")
+
+// Circuit breaker states.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// A Breaker is a circuit breaker for Redis commands, installed as a client hook.
+//
+// The breaker opens after a number of consecutive store failures, and fails
+// commands immediately while open. After the open duration, it lets a limited
+// number of probe commands through (half-open), and closes once they all succeed.
+// Any probe failure opens it again.
+//
+// A Breaker is safe for concurrent use.
+type Breaker struct {
+	mu               sync.Mutex
+	state            string
+	failures         int // consecutive failures while closed.
+	openedAt         time.Time
+	probes           int // probes in flight while half-open.
+	successes        int // successful probes while half-open.
+	failureThreshold int
+	openDuration     time.Duration
+	halfOpenProbes   int
+	now              func() time.Time
+}
+
+// NewBreaker creates a closed circuit breaker.
+func NewBreaker(failureThreshold int, openDuration time.Duration, halfOpenProbes int) *Breaker {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	if halfOpenProbes < 1 {
+		halfOpenProbes = 1
+	}
+	return &Breaker{
+		state:            BreakerClosed,
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		halfOpenProbes:   halfOpenProbes,
+		now:              time.Now,
+	}
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.openDuration {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// Allow a command through the breaker, returning ErrCircuitOpen if it is rejected.
+//
+// The command is a probe when the breaker is half-open. Every allowed command
+// must be followed by a call to done with whether it is a probe, and its error.
+func (b *Breaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen {
+		if b.now().Sub(b.openedAt) < b.openDuration {
+			metrics.Int("redis_breaker_rejected_total").Add(1)
+			return false, ErrCircuitOpen
+		}
+		b.setState(BreakerHalfOpen)
+	}
+	if b.state == BreakerHalfOpen {
+		if b.probes >= b.halfOpenProbes {
+			metrics.Int("redis_breaker_rejected_total").Add(1)
+			return false, ErrCircuitOpen
+		}
+		b.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+// Record the result of an allowed command.
+//
+// Commands allowed before the breaker opened may complete while it is
+// half-open, and only count while it is still closed, so they never take the
+// place of a probe.
+func (b *Breaker) done(probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failed := isStoreFailure(err)
+	switch {
+	case probe:
+		if b.state != BreakerHalfOpen || b.probes == 0 {
+			return // the probe outlived its half-open period.
+		}
+		b.probes--
+		if failed {
+			b.trip()
+			return
+		}
+		b.successes++
+		if b.successes >= b.halfOpenProbes {
+			b.setState(BreakerClosed)
+		}
+	case b.state == BreakerClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.failureThreshold {
+			b.trip()
+		}
+	}
+}
+
+// Open the breaker. Must be called with the lock held.
+func (b *Breaker) trip() {
+	logger := core.GetLogger()
+
+	b.openedAt = b.now()
+	b.setState(BreakerOpen)
+	metrics.Int("redis_breaker_trips_total").Add(1)
+	logger.Warnw(
+		"Redis circuit breaker opened",
+		"func", "cache.Breaker.trip",
+		"failures", b.failures,
+		"open", b.openDuration.String(),
+	)
+}
+
+// Change the state and reset the counters. Must be called with the lock held.
+func (b *Breaker) setState(state string) {
+	logger := core.GetLogger()
+
+	if b.state != state {
+		logger.Infow(
+			"Redis circuit breaker state changed",
+			"func", "cache.Breaker.setState",
+			"from", b.state,
+			"to", state,
+		)
+	}
+	b.state = state
+	b.failures = 0
+	b.probes = 0
+	b.successes = 0
+}
+
+// Check if a command error is a failure of the store, rather than a command result.
+func isStoreFailure(err error) bool {
+	if err == nil || errors.Is(err, redis.Nil) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	// Errors replied by Redis (e.g. WRONGTYPE) mean the store is reachable,
+	// except while it is loading, failing over, or down.
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		for _, prefix := range []string{"LOADING", "READONLY", "MASTERDOWN", "CLUSTERDOWN", "TRYAGAIN"} {
+			if strings.HasPrefix(redisErr.Error(), prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// DialHook implements redis.Hook.
+func (b *Breaker) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook implements redis.Hook.
+func (b *Breaker) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		probe, err := b.allow()
+		if err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		err = next(ctx, cmd)
+		b.done(probe, err)
+		return err
+	}
+}
+
+// ProcessPipelineHook implements redis.Hook.
+func (b *Breaker) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		probe, err := b.allow()
+		if err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+		err = next(ctx, cmds)
+		b.done(probe, err)
+		return err
+	}
+}
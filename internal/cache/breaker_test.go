@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func Test_Breaker_TripAndRecover_Success(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(2, 10*time.Second, 1)
+	b.now = func() time.Time { return now }
+	storeErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+
+	// Command results do not count as failures.
+	for i := 0; i < 3; i++ {
+		probe, _ := b.allow()
+		b.done(probe, redis.Nil)
+	}
+	if b.State() != BreakerClosed {
+		t.Fatalf("Unexpected state: actual=%s, expected=%s", b.State(), BreakerClosed)
+	}
+
+	// Consecutive store failures open the breaker.
+	for i := 0; i < 2; i++ {
+		probe, _ := b.allow()
+		b.done(probe, storeErr)
+	}
+	if _, err := b.allow(); b.State() != BreakerOpen || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected open breaker to reject: state=%s", b.State())
+	}
+
+	// After the open duration, a single probe is allowed.
+	now = now.Add(10 * time.Second)
+	probe, err := b.allow()
+	if err != nil || !probe {
+		t.Fatalf("Expected probe to be allowed: probe=%t, err=%v", probe, err)
+	}
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected concurrent probe to be rejected")
+	}
+
+	// A failed probe opens the breaker again, and a successful one closes it.
+	b.done(probe, storeErr)
+	if b.State() != BreakerOpen {
+		t.Fatalf("Unexpected state: actual=%s, expected=%s", b.State(), BreakerOpen)
+	}
+	now = now.Add(10 * time.Second)
+	probe, _ = b.allow()
+	b.done(probe, nil)
+	if b.State() != BreakerClosed {
+		t.Errorf("Unexpected state: actual=%s, expected=%s", b.State(), BreakerClosed)
+	}
+}
+
+func Test_Breaker_InFlightDuringTrip_NotProbe(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(1, 10*time.Second, 1)
+	b.now = func() time.Time { return now }
+	storeErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+
+	// A command is in flight while another one opens the breaker.
+	inFlight, _ := b.allow()
+	tripping, _ := b.allow()
+	b.done(tripping, storeErr)
+
+	// After the open duration, the probe takes the only slot.
+	now = now.Add(10 * time.Second)
+	probe, err := b.allow()
+	if err != nil || !probe || inFlight {
+		t.Fatalf("Expected only the half-open command to be a probe: inFlight=%t, probe=%t, err=%v", inFlight, probe, err)
+	}
+
+	// The command in flight completes without freeing the probe slot.
+	b.done(inFlight, nil)
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected a second probe to be rejected: err=%v", err)
+	}
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("Unexpected state: actual=%s, expected=%s", b.State(), BreakerHalfOpen)
+	}
+
+	b.done(probe, nil)
+	if b.State() != BreakerClosed {
+		t.Errorf("Unexpected state: actual=%s, expected=%s", b.State(), BreakerClosed)
+	}
+}
+
+func Test_Breaker_ClientHook_FailsFast(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{
+		Addr:       redisServer.Addr(),
+		MaxRetries: -1,
+	})
+	defer client.Close()
+	b := NewBreaker(1, time.Minute, 1)
+	client.AddHook(b)
+
+	ctx := context.Background()
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Fatalf("Expected ping to succeed: err=%s", err)
+	}
+
+	redisServer.Close()
+	if err := client.Ping(ctx).Err(); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected store failure: err=%v", err)
+	}
+	if err := client.TTL(ctx, "foo").Err(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected open breaker to fail fast: err=%v", err)
+	}
+}
@@ -1,23 +1,67 @@
 // Package cache implements a standardized, pre-configured Redis cache client.
+//
+// The client connects to a single Redis instance by default, to a Sentinel
+// managed master when "redis.sentinel.master_name" is set, or to a Redis
+// Cluster when "redis.cluster.addrs" is set.
 package cache
 
 import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 
 	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/metrics"
 )
 
 var once sync.Once
-var redisClient *redis.Client
-var redisContext context.Context
+var breaker *Breaker
+
+// The Redis client singleton, replaced by SetRedisClient and ReconnectRedisClient.
+var (
+	redisClientMu sync.RWMutex
+	redisClient   redis.UniversalClient
+)
+
+// A ReconnectHook is called with the old and new Redis client singleton after a reconnect.
+type ReconnectHook func(old redis.UniversalClient, client redis.UniversalClient)
+
+// Hooks registered with OnReconnect, by registration ID.
+var (
+	reconnectHooksMu sync.Mutex
+	reconnectHooks   = make(map[int]ReconnectHook)
+	reconnectHookID  int
+)
+
+func initRedisClient() redis.UniversalClient {
+	client := NewRedisClient(viper.GetInt(core.OptStr_RedisDbnum))
+
+	if viper.GetBool(core.OptStr_RedisBreakerEnabled) {
+		breaker = NewBreaker(
+			viper.GetInt(core.OptStr_RedisBreakerFailures),
+			time.Duration(viper.GetInt(core.OptStr_RedisBreakerOpenSec))*time.Second,
+			viper.GetInt(core.OptStr_RedisBreakerProbes),
+		)
+		client.AddHook(breaker)
+	}
+	publishPoolMetrics(client)
+
+	return client
+}
+
+// NewRedisClient creates a Redis client from the configuration, for the given database number.
+//
+// Unlike the singleton, the client has no circuit breaker or pool metrics.
+// The database number is ignored by Redis Cluster.
+func NewRedisClient(db int) redis.UniversalClient {
+	logger := core.GetLogger()
 
-func initRedisClient() *redis.Client {
 	redisHost := viper.GetString(core.OptStr_RedisHost)
 	redisPort := viper.GetString(core.OptStr_RedisPort)
 	tlsEnabled := viper.GetBool(core.OptStr_RedisTlsEnabled)
@@ -31,42 +75,177 @@ func initRedisClient() *redis.Client {
 		}
 	}
 
-	client := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", redisHost, redisPort),
+	options := &redis.UniversalOptions{
+		Addrs:    []string{fmt.Sprintf("%s:%s", redisHost, redisPort)},
 		Username: viper.GetString(core.OptStr_RedisUsername),
-		Password: viper.GetString(core.OptStr_RedisPassword),
-		DB:       viper.GetInt(core.OptStr_RedisDbnum),
+		Password: core.GetSecret(core.OptStr_RedisPassword),
+		DB:       db,
+
+		PoolSize:     viper.GetInt(core.OptStr_RedisPoolSize),
+		MinIdleConns: viper.GetInt(core.OptStr_RedisPoolMinIdle),
+		DialTimeout:  time.Duration(viper.GetInt(core.OptStr_RedisTimeoutDialMs)) * time.Millisecond,
+		ReadTimeout:  time.Duration(viper.GetInt(core.OptStr_RedisTimeoutReadMs)) * time.Millisecond,
+		WriteTimeout: time.Duration(viper.GetInt(core.OptStr_RedisTimeoutWriteMs)) * time.Millisecond,
+
+		// Retries back off exponentially between the min and max, with jitter.
+		MaxRetries:      viper.GetInt(core.OptStr_RedisRetryMax),
+		MinRetryBackoff: time.Duration(viper.GetInt(core.OptStr_RedisRetryMinBackoffMs)) * time.Millisecond,
+		MaxRetryBackoff: time.Duration(viper.GetInt(core.OptStr_RedisRetryMaxBackoffMs)) * time.Millisecond,
 
 		TLSConfig: tlsConfig,
-	})
+	}
+
+	var client redis.UniversalClient
+	clusterAddrs := splitAddrs(viper.GetString(core.OptStr_RedisClusterAddrs))
+	masterName := viper.GetString(core.OptStr_RedisSentinelMasterName)
+	if len(clusterAddrs) > 0 {
+		// Redis Cluster, discovered from the seed nodes.
+		logger.Debugw(
+			"Using Redis Cluster",
+			"func", "cache.initRedisClient",
+			"addrs", clusterAddrs,
+		)
+		options.Addrs = clusterAddrs
+		client = redis.NewClusterClient(options.Cluster())
+	} else if masterName != "" {
+		// Master managed by Redis Sentinel.
+		sentinelAddrs := splitAddrs(viper.GetString(core.OptStr_RedisSentinelAddrs))
+		logger.Debugw(
+			"Using Redis Sentinel",
+			"func", "cache.initRedisClient",
+			"master", masterName,
+			"addrs", sentinelAddrs,
+		)
+		options.Addrs = sentinelAddrs
+		options.MasterName = masterName
+		options.SentinelUsername = viper.GetString(core.OptStr_RedisSentinelUsername)
+		options.SentinelPassword = core.GetSecret(core.OptStr_RedisSentinelPassword)
+		client = redis.NewFailoverClient(options.Failover())
+	} else {
+		client = redis.NewClient(options.Simple())
+	}
+
+	if viper.GetBool(core.OptStr_TracingEnabled) {
+		client.AddHook(tracingHook{})
+	}
 
 	return client
 }
 
+// Split a comma-separated list of addresses.
+func splitAddrs(value string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(value, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
 // GetRedisClient returns a singleton of a configured Redis client.
-func GetRedisClient() *redis.Client {
+//
+// The singleton may be replaced by ReconnectRedisClient, so long-lived users
+// should get it again when it fails, or register with OnReconnect.
+func GetRedisClient() redis.UniversalClient {
 	once.Do(func() {
+		redisClientMu.Lock()
+		defer redisClientMu.Unlock()
 		if redisClient == nil {
 			redisClient = initRedisClient()
 		}
-		if redisContext == nil {
-			redisContext = context.TODO()
-		}
 	})
 
+	redisClientMu.RLock()
+	defer redisClientMu.RUnlock()
 	return redisClient
 }
 
+// GetBreaker returns the circuit breaker of the Redis client singleton, or nil if it is disabled.
+func GetBreaker() *Breaker {
+	GetRedisClient()
+	return breaker
+}
+
+// BreakerState returns the state of the circuit breaker, or "disabled".
+func BreakerState() string {
+	if b := GetBreaker(); b != nil {
+		return b.State()
+	}
+	return "disabled"
+}
+
+// Expose the connection pool statistics of a client as metrics.
+func publishPoolMetrics(client redis.UniversalClient) {
+	metrics.Func("redis_breaker_state", func() interface{} {
+		return BreakerState()
+	})
+	metrics.Func("redis_pool", func() interface{} {
+		return client.PoolStats()
+	})
+}
+
 // SetRedisClient overrides and explicitly sets the Redis client singleton.
-func SetRedisClient(rc *redis.Client) {
+func SetRedisClient(rc redis.UniversalClient) {
+	redisClientMu.Lock()
+	defer redisClientMu.Unlock()
 	redisClient = rc
 }
 
+// ReconnectRedisClient replaces the Redis client singleton with a new client, e.g. when its connections went stale.
+//
+// The circuit breaker of the old client is kept. Hooks registered with
+// OnReconnect are called before the old client is closed, and commands still
+// in flight on the old client fail when it is closed.
+func ReconnectRedisClient() redis.UniversalClient {
+	GetRedisClient()
+
+	client := NewRedisClient(viper.GetInt(core.OptStr_RedisDbnum))
+	if breaker != nil {
+		client.AddHook(breaker)
+	}
+	publishPoolMetrics(client)
+
+	redisClientMu.Lock()
+	oldClient := redisClient
+	redisClient = client
+	redisClientMu.Unlock()
+
+	reconnectHooksMu.Lock()
+	hooks := make([]ReconnectHook, 0, len(reconnectHooks))
+	for _, hook := range reconnectHooks {
+		hooks = append(hooks, hook)
+	}
+	reconnectHooksMu.Unlock()
+	for _, hook := range hooks {
+		hook(oldClient, client)
+	}
+
+	_ = oldClient.Close()
+	return client
+}
+
+// OnReconnect registers a hook called after ReconnectRedisClient replaces the singleton.
+//
+// Returns a function that removes the hook.
+func OnReconnect(hook ReconnectHook) func() {
+	reconnectHooksMu.Lock()
+	defer reconnectHooksMu.Unlock()
+	reconnectHookID++
+	id := reconnectHookID
+	reconnectHooks[id] = hook
+
+	return func() {
+		reconnectHooksMu.Lock()
+		defer reconnectHooksMu.Unlock()
+		delete(reconnectHooks, id)
+	}
+}
+
 // Verify that the Redis cache can be interacted with.
 func IsRedisReady() (bool, error) {
-	redisContext = context.TODO()
 	redisDB := GetRedisClient()
-	_, err := redisDB.DBSize(redisContext).Result()
+	_, err := redisDB.Ping(context.TODO()).Result()
 	if err != nil {
 		return false, err
 	}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+func Test_ReconnectRedisClient_Hooks_Called(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+	viper.Set(core.OptStr_RedisHost, redisServer.Host())
+	viper.Set(core.OptStr_RedisPort, redisServer.Port())
+	defer viper.Set(core.OptStr_RedisHost, nil)
+	defer viper.Set(core.OptStr_RedisPort, nil)
+	oldClient := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	SetRedisClient(oldClient)
+	defer SetRedisClient(nil)
+
+	var hookOld, hookNew redis.UniversalClient
+	removeHook := OnReconnect(func(old redis.UniversalClient, client redis.UniversalClient) {
+		hookOld, hookNew = old, client
+	})
+	removed := false
+	OnReconnect(func(redis.UniversalClient, redis.UniversalClient) { removed = true })()
+
+	client := ReconnectRedisClient()
+	removeHook()
+
+	if GetRedisClient() != client || hookOld != oldClient || hookNew != client {
+		t.Errorf("Expected the hook to be called with the old and new client")
+	}
+	if removed {
+		t.Errorf("Expected the removed hook not to be called")
+	}
+	if err := oldClient.Ping(context.Background()).Err(); !errors.Is(err, redis.ErrClosed) {
+		t.Errorf("Expected the old client to be closed: err=%v", err)
+	}
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Errorf("Expected the new client to be connected: err=%v", err)
+	}
+}
+
+func Test_ReconnectRedisClient_Concurrent_Success(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+	viper.Set(core.OptStr_RedisHost, redisServer.Host())
+	viper.Set(core.OptStr_RedisPort, redisServer.Port())
+	defer viper.Set(core.OptStr_RedisHost, nil)
+	defer viper.Set(core.OptStr_RedisPort, nil)
+	SetRedisClient(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+	defer SetRedisClient(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			ReconnectRedisClient()
+		}()
+		go func() {
+			defer wg.Done()
+			_ = GetRedisClient().Ping(context.Background()).Err()
+		}()
+	}
+	wg.Wait()
+
+	if err := GetRedisClient().Ping(context.Background()).Err(); err != nil {
+		t.Errorf("Expected the latest client to be connected: err=%v", err)
+	}
+}
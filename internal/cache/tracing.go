@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/divergentcodes/jwtblock/internal/tracing"
+)
+
+// A tracingHook is a go-redis hook that traces Redis commands.
+//
+// Commands are only traced within an existing trace, so background work such
+// as filter rebuilds and change subscriptions does not create root spans.
+type tracingHook struct{}
+
+// DialHook implements redis.Hook.
+func (h tracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook implements redis.Hook.
+func (h tracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			return next(ctx, cmd)
+		}
+		ctx, span := tracing.Start(ctx, "redis "+cmd.Name(), trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemRedis, semconv.DBOperation(cmd.Name())),
+		)
+		err := next(ctx, cmd)
+		tracing.End(span, storeError(err))
+		return err
+	}
+}
+
+// ProcessPipelineHook implements redis.Hook.
+func (h tracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			return next(ctx, cmds)
+		}
+		ctx, span := tracing.Start(ctx, "redis pipeline", trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemRedis, semconv.DBOperation("pipeline")),
+		)
+		err := next(ctx, cmds)
+		tracing.End(span, storeError(err))
+		return err
+	}
+}
+
+// Get the error of a Redis command, where a missing key is not an error.
+func storeError(err error) error {
+	if err == redis.Nil {
+		return nil
+	}
+	return err
+}
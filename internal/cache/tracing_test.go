@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func Test_tracingHook_WithinTrace_Spans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(tracerProvider)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	redisServer := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	defer client.Close()
+	client.AddHook(tracingHook{})
+
+	// Commands outside of a trace are not traced.
+	client.Get(context.Background(), "foo")
+	if len(exporter.GetSpans()) != 0 {
+		t.Fatalf("Expected no spans outside of a trace: spans=%d", len(exporter.GetSpans()))
+	}
+
+	// A missing key is not an error.
+	ctx, parent := tracerProvider.Tracer("test").Start(context.Background(), "parent")
+	client.Get(ctx, "foo")
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 || spans[0].Name != "redis get" || spans[0].Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("Expected a Redis span within the parent: spans=%+v", spans)
+	}
+	if spans[0].Status.Code != codes.Unset {
+		t.Errorf("Expected no error status for a missing key: status=%+v", spans[0].Status)
+	}
+}
@@ -1,21 +1,41 @@
 package core
 
 import (
+	"bytes"
+	_ "embed"
+	"fmt"
 	"os"
 	"strings"
+	"sync"
 
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 // Initialize the application configuration settings and defaults.
 func InitConfigDefaults() {
-	initRootDefaults()
-	initBlocklistDefaults()
-	initRedisDefaults()
-	initHttpDefaults()
+	initDefaults(viper.GetViper())
 
 	initConfigFile()
-	initConfigEnv()
+	initConfigEnv(viper.GetViper())
+}
+
+// Set the configuration defaults of a settings instance.
+func initDefaults(v *viper.Viper) {
+	initRootDefaults(v)
+	initLogDefaults(v)
+	initBlocklistDefaults(v)
+	initRedisDefaults(v)
+	initHttpDefaults(v)
+	initRemoteDefaults(v)
+	initLocalCacheDefaults(v)
+	initFilterDefaults(v)
+	initStoreFailureDefaults(v)
+	initHashDefaults(v)
+	initSecretsDefaults(v)
+	initTracingDefaults(v)
+	initAuditDefaults(v)
+	initLambdaDefaults(v)
 }
 
 // Root configuration options
@@ -26,11 +46,34 @@ var (
 	OptStr_Verbose = "verbose"
 )
 
-func initRootDefaults() {
-	viper.SetDefault(OptStr_Debug, false)
-	viper.SetDefault(OptStr_OutJSON, false)
-	viper.SetDefault(OptStr_Quiet, false)
-	viper.SetDefault(OptStr_Verbose, false)
+func initRootDefaults(v *viper.Viper) {
+	v.SetDefault(OptStr_Debug, false)
+	v.SetDefault(OptStr_OutJSON, false)
+	v.SetDefault(OptStr_Quiet, false)
+	v.SetDefault(OptStr_Verbose, false)
+}
+
+// Logging configuration options
+var (
+	OptStr_LogLevel              = "log.level"
+	OptStr_LogEncoding           = "log.encoding"
+	OptStr_LogTimeFormat         = "log.time_format"
+	OptStr_LogOutputPaths        = "log.output_paths"
+	OptStr_LogErrorOutputPaths   = "log.error_output_paths"
+	OptStr_LogSamplingEnabled    = "log.sampling.enabled"
+	OptStr_LogSamplingInitial    = "log.sampling.initial"
+	OptStr_LogSamplingThereafter = "log.sampling.thereafter"
+)
+
+func initLogDefaults(v *viper.Viper) {
+	v.SetDefault(OptStr_LogLevel, "info")    // debug, info, warn, error.
+	v.SetDefault(OptStr_LogEncoding, "json") // json, console.
+	v.SetDefault(OptStr_LogTimeFormat, "")   // no timestamps, or iso8601, rfc3339, rfc3339nano, epoch, millis, nanos.
+	v.SetDefault(OptStr_LogOutputPaths, "stdout")
+	v.SetDefault(OptStr_LogErrorOutputPaths, "stderr")
+	v.SetDefault(OptStr_LogSamplingEnabled, false)
+	v.SetDefault(OptStr_LogSamplingInitial, 100)    // per message and second, logged before sampling.
+	v.SetDefault(OptStr_LogSamplingThereafter, 100) // then every Nth.
 }
 
 // Blocklist configuration options
@@ -47,21 +90,25 @@ var (
 	OptStr_JwtTTLExpPaddingSeconds = "jwt.ttl.sec_padding"
 	OptStr_JwtTTLUseTokenExp       = "jwt.ttl.use_token_exp"
 	OptStr_JwtTTLRequireTokenExp   = "jwt.ttl.require_token_exp"
+
+	OptStr_JwtBlockClaims = "jwt.block_claims"
 )
 
-func initBlocklistDefaults() {
-	viper.SetDefault(OptStr_JwtParseEnabled, true)
-	viper.SetDefault(OptStr_JwtValidateEnabled, true)
+func initBlocklistDefaults(v *viper.Viper) {
+	v.SetDefault(OptStr_JwtParseEnabled, true)
+	v.SetDefault(OptStr_JwtValidateEnabled, true)
 
-	viper.SetDefault(OptStr_JwtVerifyEnabled, false)
-	viper.SetDefault(OptStr_JwtVerifyRsaKey, "")
-	viper.SetDefault(OptStr_JwtVerifyHmacSecret, "")
+	v.SetDefault(OptStr_JwtVerifyEnabled, false)
+	v.SetDefault(OptStr_JwtVerifyRsaKey, "")
+	v.SetDefault(OptStr_JwtVerifyHmacSecret, "")
 
-	viper.SetDefault(OptStr_JwtTTLDefaultSeconds, 7200) // 2 hours
-	viper.SetDefault(OptStr_JwtTTLSpecifiedSeconds, -1)
-	viper.SetDefault(OptStr_JwtTTLExpPaddingSeconds, 5)
-	viper.SetDefault(OptStr_JwtTTLUseTokenExp, true)
-	viper.SetDefault(OptStr_JwtTTLRequireTokenExp, false)
+	v.SetDefault(OptStr_JwtTTLDefaultSeconds, 7200) // 2 hours
+	v.SetDefault(OptStr_JwtTTLSpecifiedSeconds, -1)
+	v.SetDefault(OptStr_JwtTTLExpPaddingSeconds, 5)
+	v.SetDefault(OptStr_JwtTTLUseTokenExp, true)
+	v.SetDefault(OptStr_JwtTTLRequireTokenExp, false)
+
+	v.SetDefault(OptStr_JwtBlockClaims, "") // comma-separated claims checked for blocked values, e.g. sub,sid.
 }
 
 // Redis configuration options
@@ -73,16 +120,54 @@ var (
 	OptStr_RedisPassword    = "redis.password"
 	OptStr_RedisTlsEnabled  = "redis.tls.enabled"
 	OptStr_RedisTlsNoverify = "redis.tls.noverify"
+
+	OptStr_RedisSentinelMasterName = "redis.sentinel.master_name"
+	OptStr_RedisSentinelAddrs      = "redis.sentinel.addrs"
+	OptStr_RedisSentinelUsername   = "redis.sentinel.username"
+	OptStr_RedisSentinelPassword   = "redis.sentinel.password"
+	OptStr_RedisClusterAddrs       = "redis.cluster.addrs"
+
+	OptStr_RedisPoolSize          = "redis.pool.size"
+	OptStr_RedisPoolMinIdle       = "redis.pool.min_idle"
+	OptStr_RedisTimeoutDialMs     = "redis.timeout.dial_ms"
+	OptStr_RedisTimeoutReadMs     = "redis.timeout.read_ms"
+	OptStr_RedisTimeoutWriteMs    = "redis.timeout.write_ms"
+	OptStr_RedisRetryMax          = "redis.retry.max_retries"
+	OptStr_RedisRetryMinBackoffMs = "redis.retry.min_backoff_ms"
+	OptStr_RedisRetryMaxBackoffMs = "redis.retry.max_backoff_ms"
+	OptStr_RedisBreakerEnabled    = "redis.breaker.enabled"
+	OptStr_RedisBreakerFailures   = "redis.breaker.failure_threshold"
+	OptStr_RedisBreakerOpenSec    = "redis.breaker.open_sec"
+	OptStr_RedisBreakerProbes     = "redis.breaker.half_open_probes"
 )
 
-func initRedisDefaults() {
-	viper.SetDefault(OptStr_RedisHost, "localhost")
-	viper.SetDefault(OptStr_RedisPort, 6379)
-	viper.SetDefault(OptStr_RedisDbnum, 0)
-	viper.SetDefault(OptStr_RedisUsername, "")
-	viper.SetDefault(OptStr_RedisPassword, "")
-	viper.SetDefault(OptStr_RedisTlsEnabled, false)
-	viper.SetDefault(OptStr_RedisTlsNoverify, false)
+func initRedisDefaults(v *viper.Viper) {
+	v.SetDefault(OptStr_RedisHost, "localhost")
+	v.SetDefault(OptStr_RedisPort, 6379)
+	v.SetDefault(OptStr_RedisDbnum, 0)
+	v.SetDefault(OptStr_RedisUsername, "")
+	v.SetDefault(OptStr_RedisPassword, "")
+	v.SetDefault(OptStr_RedisTlsEnabled, false)
+	v.SetDefault(OptStr_RedisTlsNoverify, false)
+
+	v.SetDefault(OptStr_RedisSentinelMasterName, "")
+	v.SetDefault(OptStr_RedisSentinelAddrs, "")
+	v.SetDefault(OptStr_RedisSentinelUsername, "")
+	v.SetDefault(OptStr_RedisSentinelPassword, "")
+	v.SetDefault(OptStr_RedisClusterAddrs, "")
+
+	v.SetDefault(OptStr_RedisPoolSize, 0) // 10 per CPU.
+	v.SetDefault(OptStr_RedisPoolMinIdle, 0)
+	v.SetDefault(OptStr_RedisTimeoutDialMs, 5000)
+	v.SetDefault(OptStr_RedisTimeoutReadMs, 3000)
+	v.SetDefault(OptStr_RedisTimeoutWriteMs, 3000)
+	v.SetDefault(OptStr_RedisRetryMax, 3)
+	v.SetDefault(OptStr_RedisRetryMinBackoffMs, 8)
+	v.SetDefault(OptStr_RedisRetryMaxBackoffMs, 512)
+	v.SetDefault(OptStr_RedisBreakerEnabled, true)
+	v.SetDefault(OptStr_RedisBreakerFailures, 5)
+	v.SetDefault(OptStr_RedisBreakerOpenSec, 10)
+	v.SetDefault(OptStr_RedisBreakerProbes, 1)
 }
 
 // HTTP service configuration options
@@ -90,20 +175,193 @@ var (
 	OptStr_HttpHostname           = "http.hostname"
 	OptStr_HttpPort               = "http.port"
 	OptStr_HttpHeaderSha256       = "http.http_header.sha256"
+	OptStr_HttpHeaderTTL          = "http.http_header.ttl"
+	OptStr_HttpHeaderApiKey       = "http.http_header.api_key"
+	OptStr_HttpAdminEnabled       = "http.admin.enabled"
+	OptStr_HttpAdminApiKeys       = "http.admin.api_keys"
+	OptStr_HttpMetricsEnabled     = "http.metrics.enabled"
 	OptStr_HttpStatusOnAllowed    = "http.status.on_allowed"
 	OptStr_HttpStatusOnBlocked    = "http.status.on_blocked"
 	OptStr_HttpCorsAllowedOrigins = "http.cors.allowed_origins"
 	OptStr_HttpCorsMaxSeconds     = "http.cors.max_seconds"
+	OptStr_HttpHeaderRequestID    = "http.http_header.request_id"
+	OptStr_HttpAccessLogEnabled   = "http.access_log.enabled"
+	OptStr_HttpTrustedProxies     = "http.trusted_proxies"
+	OptStr_HttpHeaderReason       = "http.http_header.reason"
+)
+
+func initHttpDefaults(v *viper.Viper) {
+	v.SetDefault(OptStr_HttpHostname, "")
+	v.SetDefault(OptStr_HttpPort, 4474)
+	v.SetDefault(OptStr_HttpHeaderSha256, "x-jwtblock-sha256")
+	v.SetDefault(OptStr_HttpHeaderTTL, "x-jwtblock-ttl")
+	v.SetDefault(OptStr_HttpHeaderApiKey, "x-jwtblock-api-key")
+	v.SetDefault(OptStr_HttpAdminEnabled, false)
+	v.SetDefault(OptStr_HttpAdminApiKeys, "")
+	v.SetDefault(OptStr_HttpMetricsEnabled, true)
+	v.SetDefault(OptStr_HttpStatusOnAllowed, 200)
+	v.SetDefault(OptStr_HttpStatusOnBlocked, 401)
+	v.SetDefault(OptStr_HttpCorsAllowedOrigins, "")
+	v.SetDefault(OptStr_HttpCorsMaxSeconds, 5)
+	v.SetDefault(OptStr_HttpHeaderRequestID, "x-request-id")
+	v.SetDefault(OptStr_HttpAccessLogEnabled, true)
+	v.SetDefault(OptStr_HttpTrustedProxies, "") // comma-separated IPs or CIDRs.
+	v.SetDefault(OptStr_HttpHeaderReason, "x-jwtblock-reason")
+}
+
+// Remote CLI configuration options
+var (
+	OptStr_RemoteServer       = "remote.server"
+	OptStr_RemoteApiKey       = "remote.api_key"
+	OptStr_RemoteBearerToken  = "remote.bearer_token"
+	OptStr_RemoteBearerHeader = "remote.bearer_header"
+	OptStr_RemoteTimeoutSec   = "remote.timeout_sec"
+)
+
+func initRemoteDefaults(v *viper.Viper) {
+	v.SetDefault(OptStr_RemoteServer, "")
+	v.SetDefault(OptStr_RemoteApiKey, "")
+	v.SetDefault(OptStr_RemoteBearerToken, "")
+	v.SetDefault(OptStr_RemoteBearerHeader, "Proxy-Authorization") // never Authorization, which carries the token operated on.
+	v.SetDefault(OptStr_RemoteTimeoutSec, 10)
+}
+
+// Local cache configuration options
+var (
+	OptStr_LocalCacheEnabled      = "cache.local.enabled"
+	OptStr_LocalCacheSize         = "cache.local.size"
+	OptStr_LocalCacheTTLBlockedMs = "cache.local.ttl_blocked_ms"
+	OptStr_LocalCacheTTLAllowedMs = "cache.local.ttl_allowed_ms"
+	OptStr_LocalCacheChannel      = "cache.local.channel"
+)
+
+func initLocalCacheDefaults(v *viper.Viper) {
+	v.SetDefault(OptStr_LocalCacheEnabled, false)
+	v.SetDefault(OptStr_LocalCacheSize, 10000)
+	v.SetDefault(OptStr_LocalCacheTTLBlockedMs, 10000)
+	v.SetDefault(OptStr_LocalCacheTTLAllowedMs, 1000)
+	v.SetDefault(OptStr_LocalCacheChannel, "jwtblock:invalidate")
+}
+
+// Blocked filter configuration options
+var (
+	OptStr_FilterEnabled            = "cache.filter.enabled"
+	OptStr_FilterCapacity           = "cache.filter.capacity"
+	OptStr_FilterFPRate             = "cache.filter.fp_rate"
+	OptStr_FilterRebuildIntervalSec = "cache.filter.rebuild_interval_sec"
+)
+
+func initFilterDefaults(v *viper.Viper) {
+	v.SetDefault(OptStr_FilterEnabled, false)
+	v.SetDefault(OptStr_FilterCapacity, 1000000)
+	v.SetDefault(OptStr_FilterFPRate, 0.001)
+	v.SetDefault(OptStr_FilterRebuildIntervalSec, 600)
+}
+
+// Store failure policy configuration options
+var (
+	OptStr_StoreFailurePolicy          = "store_failure.policy"
+	OptStr_StoreFailureRoutes          = "store_failure.routes"
+	OptStr_StoreFailureIssuers         = "store_failure.issuers"
+	OptStr_StoreFailureStaleAllowedSec = "store_failure.stale_allowed_sec"
+)
+
+func initStoreFailureDefaults(v *viper.Viper) {
+	v.SetDefault(OptStr_StoreFailurePolicy, "closed")
+	v.SetDefault(OptStr_StoreFailureRoutes, map[string]string{})
+	v.SetDefault(OptStr_StoreFailureIssuers, map[string]string{})
+	v.SetDefault(OptStr_StoreFailureStaleAllowedSec, 300)
+}
+
+// Token hash configuration options
+var (
+	OptStr_HashHmacSecrets  = "hash.hmac_secrets"
+	OptStr_HashCheckUnkeyed = "hash.check_unkeyed"
 )
 
-func initHttpDefaults() {
-	viper.SetDefault(OptStr_HttpHostname, "")
-	viper.SetDefault(OptStr_HttpPort, 4474)
-	viper.SetDefault(OptStr_HttpHeaderSha256, "x-jwtblock-sha256")
-	viper.SetDefault(OptStr_HttpStatusOnAllowed, 200)
-	viper.SetDefault(OptStr_HttpStatusOnBlocked, 401)
-	viper.SetDefault(OptStr_HttpCorsAllowedOrigins, "")
-	viper.SetDefault(OptStr_HttpCorsMaxSeconds, 5)
+func initHashDefaults(v *viper.Viper) {
+	v.SetDefault(OptStr_HashHmacSecrets, "")
+	v.SetDefault(OptStr_HashCheckUnkeyed, true)
+}
+
+// Secret reference configuration options
+var (
+	OptStr_SecretsRefreshSec  = "secrets.refresh_sec"
+	OptStr_SecretsAwsRegion   = "secrets.aws.region"
+	OptStr_SecretsAwsEndpoint = "secrets.aws.endpoint"
+)
+
+func initSecretsDefaults(v *viper.Viper) {
+	v.SetDefault(OptStr_SecretsRefreshSec, 300) // 0 resolves secret references only at startup.
+	v.SetDefault(OptStr_SecretsAwsRegion, "")   // AWS_REGION, or the shared AWS config.
+	v.SetDefault(OptStr_SecretsAwsEndpoint, "") // e.g. a local stub, instead of the AWS endpoints.
+}
+
+// Tracing configuration options
+var (
+	OptStr_TracingEnabled      = "tracing.enabled"
+	OptStr_TracingServiceName  = "tracing.service_name"
+	OptStr_TracingSampleRatio  = "tracing.sample_ratio"
+	OptStr_TracingOtlpEndpoint = "tracing.otlp.endpoint"
+	OptStr_TracingOtlpInsecure = "tracing.otlp.insecure"
+)
+
+func initTracingDefaults(v *viper.Viper) {
+	v.SetDefault(OptStr_TracingEnabled, false)
+	v.SetDefault(OptStr_TracingServiceName, "jwtblock")
+	v.SetDefault(OptStr_TracingSampleRatio, 1.0)
+	v.SetDefault(OptStr_TracingOtlpEndpoint, "") // OTEL_EXPORTER_OTLP_ENDPOINT, or localhost:4318.
+	v.SetDefault(OptStr_TracingOtlpInsecure, false)
+}
+
+// Audit log configuration options
+var (
+	OptStr_AuditEnabled        = "audit.enabled"
+	OptStr_AuditSink           = "audit.sink"
+	OptStr_AuditFilePath       = "audit.file.path"
+	OptStr_AuditFileMaxSizeMB  = "audit.file.max_size_mb"
+	OptStr_AuditFileMaxBackups = "audit.file.max_backups"
+	OptStr_AuditSyslogNetwork  = "audit.syslog.network"
+	OptStr_AuditSyslogAddress  = "audit.syslog.address"
+	OptStr_AuditSyslogTag      = "audit.syslog.tag"
+	OptStr_AuditRedisStream    = "audit.redis.stream"
+	OptStr_AuditRedisDbnum     = "audit.redis.dbnum"
+	OptStr_AuditRedisMaxLen    = "audit.redis.max_len"
+)
+
+func initAuditDefaults(v *viper.Viper) {
+	v.SetDefault(OptStr_AuditEnabled, false)
+	v.SetDefault(OptStr_AuditSink, "file") // file, syslog, redis.
+	v.SetDefault(OptStr_AuditFilePath, "jwtblock-audit.log")
+	v.SetDefault(OptStr_AuditFileMaxSizeMB, 100)
+	v.SetDefault(OptStr_AuditFileMaxBackups, 5)
+	v.SetDefault(OptStr_AuditSyslogNetwork, "") // local syslog, or udp, tcp.
+	v.SetDefault(OptStr_AuditSyslogAddress, "")
+	v.SetDefault(OptStr_AuditSyslogTag, "jwtblock")
+	v.SetDefault(OptStr_AuditRedisStream, "jwtblock:audit")
+	v.SetDefault(OptStr_AuditRedisDbnum, 1)  // must differ from redis.dbnum, which is flushed with the blocklist.
+	v.SetDefault(OptStr_AuditRedisMaxLen, 0) // approximate, 0 is unlimited.
+}
+
+// AWS Lambda configuration options
+var (
+	OptStr_LambdaAuthorizerResponse       = "lambda.authorizer.response"
+	OptStr_LambdaAuthorizerPolicyResource = "lambda.authorizer.policy_resource"
+	OptStr_LambdaAuthorizerRules          = "lambda.authorizer.rules"
+	OptStr_LambdaAuthorizerCacheTTLSec    = "lambda.authorizer.cache_ttl_sec"
+
+	OptStr_LambdaRedisValidateIdleSec = "lambda.redis.validate_idle_sec"
+	OptStr_LambdaRedisPingTimeoutMs   = "lambda.redis.ping_timeout_ms"
+)
+
+func initLambdaDefaults(v *viper.Viper) {
+	v.SetDefault(OptStr_LambdaAuthorizerResponse, "policy")      // policy, or simple for HTTP API request authorizers.
+	v.SetDefault(OptStr_LambdaAuthorizerPolicyResource, "exact") // exact, or wildcard for cache-friendly policies.
+	v.SetDefault(OptStr_LambdaAuthorizerRules, []interface{}{})
+	v.SetDefault(OptStr_LambdaAuthorizerCacheTTLSec, 300) // should match the API Gateway authorizer result TTL.
+
+	v.SetDefault(OptStr_LambdaRedisValidateIdleSec, 60) // ping Redis before invocations after this idle time, <0 disables.
+	v.SetDefault(OptStr_LambdaRedisPingTimeoutMs, 250)
 }
 
 func initConfigFile() {
@@ -118,16 +376,68 @@ func initConfigFile() {
 			// Config file not found. Ignore error and continue.
 		} else {
 			// Config file was found but another error was produced.
+			fmt.Fprintf(os.Stderr, "Error: reading config file: %s\n", err.Error())
 			os.Exit(1)
 		}
 	}
 
 }
 
-func initConfigEnv() {
+func initConfigEnv(v *viper.Viper) {
 	// Support equivalent environment variables.
-	viper.SetEnvPrefix("JWTBLOCK")
+	v.SetEnvPrefix("JWTBLOCK")
 	replacer := strings.NewReplacer(".", "_")
-	viper.SetEnvKeyReplacer(replacer)
-	viper.AutomaticEnv()
+	v.SetEnvKeyReplacer(replacer)
+	v.AutomaticEnv()
+}
+
+var (
+	boundFlagsMu sync.Mutex
+	boundFlags   = map[string]*pflag.Flag{}
+)
+
+// BindPFlag binds a configuration option to a CLI flag.
+//
+// The binding is also applied to the settings of reloaded config files, so
+// flags keep overriding the file.
+func BindPFlag(option string, flag *pflag.Flag) error {
+	if err := viper.BindPFlag(option, flag); err != nil {
+		return err
+	}
+	boundFlagsMu.Lock()
+	defer boundFlagsMu.Unlock()
+	boundFlags[option] = flag
+	return nil
+}
+
+// Create a settings instance with the defaults, environment variables and CLI flags of the
+// application settings, and the content of a config file.
+func newSettings(configContent []byte) (*viper.Viper, error) {
+	v := viper.New()
+	initDefaults(v)
+	initConfigEnv(v)
+
+	boundFlagsMu.Lock()
+	for option, flag := range boundFlags {
+		if err := v.BindPFlag(option, flag); err != nil {
+			boundFlagsMu.Unlock()
+			return nil, err
+		}
+	}
+	boundFlagsMu.Unlock()
+
+	// The same file type as the config file in use.
+	v.SetConfigFile(viper.ConfigFileUsed())
+	if err := v.ReadConfig(bytes.NewReader(configContent)); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+//go:embed example.yaml
+var exampleConfig string
+
+// ExampleConfig returns a commented example config file with the default settings.
+func ExampleConfig() string {
+	return exampleConfig
 }
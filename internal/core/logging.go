@@ -1,78 +1,183 @@
 package core
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// General error messages from the logging configuration.
+var (
+	ErrInvalidLogLevel    = errors.New("invalid log level, expected one of debug, info, warn, error")
+	ErrInvalidLogEncoding = errors.New("invalid log encoding, expected json or console")
 )
 
 var once sync.Once
 var zapLogger *zap.SugaredLogger
 
+// The level of the logger singleton, which can be changed at runtime.
+var logLevel = zap.NewAtomicLevel()
+
 func initZapLogger() *zap.SugaredLogger {
-	debug := viper.GetBool(OptStr_Debug)
-
-	debugConfig := []byte(`{
-		"level": "debug",
-		"encoding": "json",
-		"outputPaths": [
-			"stdout"
-		],
-		"errorOutputPaths": [
-			"stderr"
-		],
-		"initialFields": {
-		},
-		"encoderConfig": {
-			"messageKey": "message",
-			"levelKey": "level",
-			"levelEncoder": "lowercase"
+	logger, err := buildZapLogger()
+	if err != nil {
+		// Fall back to the defaults. The error is returned when the logger is reloaded.
+		cfg := zap.Config{
+			Level:            logLevel,
+			Encoding:         "json",
+			EncoderConfig:    newEncoderConfig(),
+			OutputPaths:      []string{"stdout"},
+			ErrorOutputPaths: []string{"stderr"},
 		}
-	}`)
-
-	standardConfig := []byte(`{
-		"level": "info",
-		"encoding": "json",
-		"outputPaths": [
-			"stdout"
-		],
-		"errorOutputPaths": [
-			"stderr"
-		],
-		"initialFields": {
-		},
-		"encoderConfig": {
-			"messageKey": "message",
-			"levelKey": "level",
-			"levelEncoder": "lowercase"
+		return zap.Must(cfg.Build(zap.WrapCore(newRedactCore))).Sugar()
+	}
+	return logger
+}
+
+func newEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		MessageKey:     "message",
+		LevelKey:       "level",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+	}
+}
+
+// Build a logger from the configuration.
+func buildZapLogger() (*zap.SugaredLogger, error) {
+	level, err := parseLogLevel(ConfiguredLogLevel())
+	if err != nil {
+		return nil, err
+	}
+
+	// The logger may be created before the configuration defaults are set.
+	encoding := viper.GetString(OptStr_LogEncoding)
+	if encoding == "" {
+		encoding = "json"
+	}
+	if encoding != "json" && encoding != "console" {
+		return nil, ErrInvalidLogEncoding
+	}
+
+	encoderConfig := newEncoderConfig()
+	if timeFormat := viper.GetString(OptStr_LogTimeFormat); timeFormat != "" {
+		encoderConfig.TimeKey = "time"
+		if err := encoderConfig.EncodeTime.UnmarshalText([]byte(timeFormat)); err != nil {
+			return nil, err
 		}
-	}`)
+	}
 
-	var cfg zap.Config
-	var err error
-	if debug {
-		err = json.Unmarshal(debugConfig, &cfg)
-	} else {
-		err = json.Unmarshal(standardConfig, &cfg)
+	cfg := zap.Config{
+		Level:            logLevel,
+		Encoding:         encoding,
+		EncoderConfig:    encoderConfig,
+		OutputPaths:      splitLogPaths(viper.GetString(OptStr_LogOutputPaths), "stdout"),
+		ErrorOutputPaths: splitLogPaths(viper.GetString(OptStr_LogErrorOutputPaths), "stderr"),
 	}
+	if viper.GetBool(OptStr_LogSamplingEnabled) {
+		cfg.Sampling = &zap.SamplingConfig{
+			Initial:    viper.GetInt(OptStr_LogSamplingInitial),
+			Thereafter: viper.GetInt(OptStr_LogSamplingThereafter),
+		}
+	}
+
+	// Mask tokens and secrets in every log field.
+	logger, err := cfg.Build(zap.WrapCore(newRedactCore))
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	logLevel.SetLevel(level)
+
+	return logger.Sugar(), nil
+}
+
+// Split comma-separated log output paths, e.g. "stdout,/var/log/jwtblock.log".
+func splitLogPaths(value string, defaultPath string) []string {
+	var paths []string
+	for _, path := range strings.Split(value, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		paths = append(paths, defaultPath)
+	}
+	return paths
+}
+
+func parseLogLevel(value string) (zapcore.Level, error) {
+	value = strings.ToLower(value)
+	switch value {
+	case "debug", "info", "warn", "error":
+		return zapcore.ParseLevel(value)
 	}
+	return zapcore.InfoLevel, fmt.Errorf("%w: %q", ErrInvalidLogLevel, value)
+}
 
-	logger := zap.Must(cfg.Build())
-	defer logger.Sync()
+// ConfiguredLogLevel returns the log level from the configuration.
+//
+// Debug mode always logs at the debug level.
+func ConfiguredLogLevel() string {
+	return configuredLogLevel(viper.GetViper())
+}
 
-	return logger.Sugar()
+func configuredLogLevel(v *viper.Viper) string {
+	if v.GetBool(OptStr_Debug) {
+		return "debug"
+	}
+	if level := v.GetString(OptStr_LogLevel); level != "" {
+		return level
+	}
+	return "info"
 }
 
 // GetLogger returns a singleton of a configured zap logger.
 func GetLogger() *zap.SugaredLogger {
 
 	once.Do(func() {
-		zapLogger = initZapLogger()
+		if zapLogger == nil {
+			zapLogger = initZapLogger()
+		}
 	})
 
 	return zapLogger
 }
+
+// SetLogger overrides and explicitly sets the logger singleton.
+func SetLogger(logger *zap.SugaredLogger) {
+	zapLogger = logger
+}
+
+// ReloadLogger rebuilds the logger singleton from the configuration.
+//
+// The logger is created before the configuration file and CLI flags are read,
+// so it is rebuilt once they are. On error, the current logger is kept.
+func ReloadLogger() error {
+	GetLogger()
+	logger, err := buildZapLogger()
+	if err != nil {
+		return err
+	}
+	zapLogger = logger
+	return nil
+}
+
+// LogLevel returns the current log level.
+func LogLevel() string {
+	return logLevel.Level().String()
+}
+
+// SetLogLevel changes the log level at runtime, without rebuilding the logger.
+func SetLogLevel(level string) error {
+	parsed, err := parseLogLevel(level)
+	if err != nil {
+		return err
+	}
+	logLevel.SetLevel(parsed)
+	return nil
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func Test_buildZapLogger_ConsoleFileOutput_Success(t *testing.T) {
+	InitConfigDefaults()
+	outputPath := filepath.Join(t.TempDir(), "jwtblock.log")
+	setupLogConfig(t, map[string]interface{}{
+		OptStr_LogLevel:       "warn",
+		OptStr_LogEncoding:    "console",
+		OptStr_LogTimeFormat:  "iso8601",
+		OptStr_LogOutputPaths: outputPath,
+	})
+
+	logger, err := buildZapLogger()
+	if err != nil {
+		t.Fatalf("Failed to build logger: err=%s", err)
+	}
+	logger.Infow("hidden message")
+	logger.Warnw("shown message", "token", "foobar")
+	_ = logger.Sync()
+
+	output, _ := os.ReadFile(outputPath)
+	if strings.Contains(string(output), "hidden message") || !strings.Contains(string(output), "shown message") {
+		t.Errorf("Expected only messages at the warn level: output=%s", output)
+	}
+	if strings.HasPrefix(string(output), "{") || strings.Contains(string(output), "foobar") {
+		t.Errorf("Expected redacted console output: output=%s", output)
+	}
+	if !strings.Contains(string(output), "T") || LogLevel() != "warn" {
+		t.Errorf("Expected an ISO8601 timestamp and the warn level: level=%s, output=%s", LogLevel(), output)
+	}
+}
+
+func Test_buildZapLogger_InvalidConfig_Error(t *testing.T) {
+	InitConfigDefaults()
+
+	setupLogConfig(t, map[string]interface{}{OptStr_LogLevel: "verbose"})
+	if _, err := buildZapLogger(); !errors.Is(err, ErrInvalidLogLevel) {
+		t.Errorf("Expected ErrInvalidLogLevel: err=%v", err)
+	}
+
+	setupLogConfig(t, map[string]interface{}{OptStr_LogLevel: "info", OptStr_LogEncoding: "xml"})
+	if _, err := buildZapLogger(); !errors.Is(err, ErrInvalidLogEncoding) {
+		t.Errorf("Expected ErrInvalidLogEncoding: err=%v", err)
+	}
+}
+
+func Test_SetLogLevel_Runtime_Success(t *testing.T) {
+	InitConfigDefaults()
+	setupLogConfig(t, map[string]interface{}{OptStr_LogLevel: "info"})
+
+	logger, err := buildZapLogger()
+	if err != nil {
+		t.Fatalf("Failed to build logger: err=%s", err)
+	}
+	if logger.Desugar().Core().Enabled(-1) {
+		t.Errorf("Expected debug logging to be disabled")
+	}
+
+	if err := SetLogLevel("DEBUG"); err != nil || LogLevel() != "debug" || !logger.Desugar().Core().Enabled(-1) {
+		t.Errorf("Expected debug logging to be enabled at runtime: level=%s, err=%v", LogLevel(), err)
+	}
+	if err := SetLogLevel("foobar"); err == nil || LogLevel() != "debug" {
+		t.Errorf("Expected an invalid level to be rejected: level=%s, err=%v", LogLevel(), err)
+	}
+}
+
+func Test_ConfiguredLogLevel_Debug_Overrides(t *testing.T) {
+	InitConfigDefaults()
+	setupLogConfig(t, map[string]interface{}{OptStr_LogLevel: "error", OptStr_Debug: true})
+
+	if level := ConfiguredLogLevel(); level != "debug" {
+		t.Errorf("Expected debug mode to log at the debug level: level=%s", level)
+	}
+}
+
+// Set logging options, restoring the defaults and the info level afterwards.
+func setupLogConfig(t *testing.T, options map[string]interface{}) {
+	for key, value := range options {
+		viper.Set(key, value)
+	}
+	t.Cleanup(func() {
+		for key := range options {
+			viper.Set(key, nil)
+		}
+		_ = SetLogLevel("info")
+	})
+}
@@ -0,0 +1,188 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// RedactedValue replaces secret values in logs.
+const RedactedValue = "[REDACTED]"
+
+// Length of the token hash prefix shown in place of a token.
+const redactedTokenHashLen = 12
+
+// Configuration options holding secrets, masked when logging the configuration.
+var secretOptions = []string{
+	OptStr_JwtVerifyHmacSecret,
+	OptStr_RedisPassword,
+	OptStr_RedisSentinelPassword,
+	OptStr_HttpAdminApiKeys,
+	OptStr_RemoteApiKey,
+	OptStr_RemoteBearerToken,
+	OptStr_HashHmacSecrets,
+}
+
+// HTTP headers holding credentials, masked when logging requests.
+var secretHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+}
+
+// RedactToken returns a loggable stand-in for a token, with only a prefix of its blocklist key.
+//
+// The prefix is enough to correlate log lines, without revealing the token.
+// With "hash.hmac_secrets", it is a prefix of the HMAC-SHA256 with the newest
+// secret, so holding a token is not enough to find it in the logs.
+func RedactToken(token string) string {
+	if token == "" {
+		return ""
+	}
+	secrets := GetConfig().Hash.HmacSecrets
+	if len(secrets) == 0 {
+		hash := fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+		return fmt.Sprintf("sha256:%s...", hash[:redactedTokenHashLen])
+	}
+	h := hmac.New(sha256.New, []byte(secrets[0]))
+	h.Write([]byte(token))
+	hash := fmt.Sprintf("%x", h.Sum(nil))
+	return fmt.Sprintf("hmac:%s...", hash[:redactedTokenHashLen])
+}
+
+// RedactHeaders returns a copy of HTTP headers, with credential headers masked.
+func RedactHeaders(headers http.Header) http.Header {
+	redacted := headers.Clone()
+	for name := range redacted {
+		if IsSecretHeader(name) {
+			redacted[name] = []string{RedactedValue}
+		}
+	}
+	return redacted
+}
+
+// RedactHeaderMap returns a copy of single-value HTTP headers, with credential headers masked.
+func RedactHeaderMap(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if IsSecretHeader(name) {
+			value = RedactedValue
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// IsSecretHeader checks if an HTTP header holds credentials.
+func IsSecretHeader(name string) bool {
+	if strings.EqualFold(name, GetConfig().HTTP.HeaderApiKey) {
+		return true
+	}
+	for _, secretHeader := range secretHeaders {
+		if strings.EqualFold(name, secretHeader) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactSettings returns a copy of the configuration settings, with secret options masked.
+func RedactSettings(settings map[string]interface{}) map[string]interface{} {
+	return redactSettings(settings, "")
+}
+
+func redactSettings(settings map[string]interface{}, prefix string) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(settings))
+	for key, value := range settings {
+		option := prefix + key
+		if nested, ok := value.(map[string]interface{}); ok {
+			redacted[key] = redactSettings(nested, option+".")
+		} else if isSecretOption(option) && fmt.Sprint(value) != "" {
+			redacted[key] = RedactedValue
+		} else {
+			redacted[key] = value
+		}
+	}
+	return redacted
+}
+
+func isSecretOption(option string) bool {
+	for _, secretOption := range secretOptions {
+		if strings.EqualFold(option, secretOption) {
+			return true
+		}
+	}
+	return false
+}
+
+// A redactCore masks sensitive log fields before they are encoded.
+//
+// Fields named like tokens are replaced with RedactToken, and fields named like
+// credentials are replaced with RedactedValue, so a secret passed to a logger by
+// mistake never reaches the log output.
+type redactCore struct {
+	zapcore.Core
+}
+
+func newRedactCore(c zapcore.Core) zapcore.Core {
+	return &redactCore{Core: c}
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(redactFields(fields))}
+}
+
+func (c *redactCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, redactFields(fields))
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	var redacted []zapcore.Field
+	for i, field := range fields {
+		value, ok := redactField(field)
+		if !ok {
+			continue
+		}
+		if redacted == nil {
+			redacted = make([]zapcore.Field, len(fields))
+			copy(redacted, fields)
+		}
+		redacted[i] = zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: value}
+	}
+	if redacted == nil {
+		return fields
+	}
+	return redacted
+}
+
+// Get the masked value of a sensitive field.
+func redactField(field zapcore.Field) (string, bool) {
+	key := strings.ToLower(field.Key)
+	switch {
+	case key == "token" || key == "jwt" || strings.HasSuffix(key, "_token"):
+		if field.Type == zapcore.StringType {
+			return RedactToken(field.String), true
+		}
+		return RedactedValue, true
+	case key == "authorization" || key == "cookie" ||
+		strings.Contains(key, "password") || strings.Contains(key, "secret") ||
+		strings.Contains(key, "api_key") || strings.Contains(key, "apikey"):
+		return RedactedValue, true
+	}
+	return "", false
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// Configuration options holding comma separated lists of secrets, each of which may be a reference.
+var secretListOptions = []string{
+	OptStr_HttpAdminApiKeys,
+	OptStr_HashHmacSecrets,
+}
+
+// A secretKey identifies the value of a secret reference of an option, e.g. "file:///run/secrets/redis-password".
+//
+// Values are kept by reference, so the settings of a reload can be resolved
+// while the current settings still read theirs.
+type secretKey struct {
+	option    string
+	reference string
+}
+
+var (
+	resolvedSecretsMu sync.RWMutex
+	resolvedSecrets   = map[secretKey]string{}
+)
+
+// A SecretResolver resolves the secret references of settings, and sets their values with SetSecret.
+type SecretResolver func(v *viper.Viper) error
+
+// Resolves the secret references of activated and reloaded settings, guarded by configMu.
+var secretResolver SecretResolver
+
+// SetSecretResolver sets how the secret references of activated and reloaded settings are resolved.
+func SetSecretResolver(resolver SecretResolver) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	secretResolver = resolver
+}
+
+// Resolve the secret references of settings, if a resolver is set.
+func resolveSecrets(v *viper.Viper) error {
+	if secretResolver == nil {
+		return nil
+	}
+	if err := secretResolver(v); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnresolvedSecret, err)
+	}
+	return nil
+}
+
+// SecretOptions returns the configuration options whose values may reference secrets.
+func SecretOptions() []string {
+	return append([]string{OptStr_JwtVerifyRsaKey}, secretOptions...)
+}
+
+// IsSecretListOption checks if a configuration option holds a comma separated list of secrets.
+func IsSecretListOption(option string) bool {
+	for _, listOption := range secretListOptions {
+		if strings.EqualFold(option, listOption) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetSecret returns the value of a configuration option that may reference a secret.
+//
+// The resolved value is returned while the option still holds the reference it
+// was resolved from, or else the configured value.
+func GetSecret(option string) string {
+	return getSecret(viper.GetViper(), option)
+}
+
+func getSecret(v *viper.Viper, option string) string {
+	value := v.GetString(option)
+
+	resolvedSecretsMu.RLock()
+	defer resolvedSecretsMu.RUnlock()
+	if resolved, ok := resolvedSecrets[secretKey{option: strings.ToLower(option), reference: value}]; ok {
+		return resolved
+	}
+	return value
+}
+
+// SetSecret sets the resolved value of a configuration option referencing a secret.
+//
+// Safe to call while the option is read, e.g. when the secret is refreshed.
+func SetSecret(option string, reference string, value string) {
+	resolvedSecretsMu.Lock()
+	defer resolvedSecretsMu.Unlock()
+	resolvedSecrets[secretKey{option: strings.ToLower(option), reference: reference}] = value
+}
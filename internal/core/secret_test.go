@@ -0,0 +1,29 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func Test_GetSecret_ResolvedReference_ResolvedValue(t *testing.T) {
+	InitConfigDefaults()
+	viper.Set(OptStr_RedisPassword, "file:///run/secrets/redis")
+	t.Cleanup(func() { viper.Set(OptStr_RedisPassword, nil) })
+	SetSecret(OptStr_RedisPassword, "file:///run/secrets/redis", "s3cret")
+
+	if actual := GetSecret(OptStr_RedisPassword); actual != "s3cret" {
+		t.Errorf("Expected the resolved value, got %q", actual)
+	}
+}
+
+func Test_GetSecret_ChangedOption_ConfiguredValue(t *testing.T) {
+	InitConfigDefaults()
+	SetSecret(OptStr_RedisPassword, "file:///run/secrets/redis", "s3cret")
+	viper.Set(OptStr_RedisPassword, "plain-password")
+	t.Cleanup(func() { viper.Set(OptStr_RedisPassword, nil) })
+
+	if actual := GetSecret(OptStr_RedisPassword); actual != "plain-password" {
+		t.Errorf("Expected the configured value, got %q", actual)
+	}
+}
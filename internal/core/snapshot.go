@@ -0,0 +1,502 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+)
+
+// General error messages from the configuration snapshot.
+var (
+	ErrInvalidConfig    = errors.New("invalid configuration")
+	ErrUnresolvedSecret = errors.New("unresolved secret reference")
+)
+
+// Valid HTTP header names.
+var validHeaderName = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+// A Config is a typed, validated snapshot of the settings read while serving requests.
+//
+// A snapshot is never modified once loaded, so it can be read concurrently
+// while a new one is loaded.
+type Config struct {
+	LogLevel     string
+	HTTP         HTTPConfig
+	JWT          JWTConfig
+	Hash         HashConfig
+	StoreFailure StoreFailureConfig
+	Cache        CacheConfig
+	Audit        AuditConfig
+	Lambda       LambdaConfig
+}
+
+// HTTPConfig holds the web service settings.
+type HTTPConfig struct {
+	CorsAllowedOrigins []string
+	CorsMaxSeconds     int
+	StatusOnAllowed    int
+	StatusOnBlocked    int
+	HeaderSha256       string
+	HeaderTTL          string
+	HeaderApiKey       string
+	HeaderRequestID    string
+	HeaderReason       string
+	AccessLogEnabled   bool
+	TrustedProxies     []*net.IPNet
+	AdminEnabled       bool
+	AdminApiKeys       []string
+	MetricsEnabled     bool
+}
+
+// JWTConfig holds the token parsing, verification and TTL settings.
+type JWTConfig struct {
+	ParseEnabled    bool
+	ValidateEnabled bool
+	VerifyEnabled   bool
+	VerifyKey       jwk.Key // nil without a valid verification key.
+	VerifyAlg       jwa.SignatureAlgorithm
+	RequireTokenExp bool
+	UseTokenExp     bool
+	TTLDefaultSec   int // 0 blocks without expiration.
+	TTLPaddingSec   int
+	BlockClaims     []string
+}
+
+// HashConfig holds the blocklist key settings.
+type HashConfig struct {
+	HmacSecrets  []string // newest first.
+	CheckUnkeyed bool
+}
+
+// StoreFailureConfig holds the policies for checks while the blocklist store is unavailable.
+type StoreFailureConfig struct {
+	Policy          string
+	Routes          map[string]string // path prefix to policy.
+	Issuers         map[string]string // lower case issuer to policy.
+	StaleAllowedSec int
+}
+
+// CacheConfig holds the in-process local cache and blocked filter settings.
+type CacheConfig struct {
+	LocalEnabled          bool
+	LocalSize             int
+	LocalTTLBlockedMs     int
+	LocalTTLAllowedMs     int
+	Channel               string
+	FilterEnabled         bool
+	FilterCapacity        int64
+	FilterFPRate          float64
+	FilterRebuildInterval int // seconds.
+}
+
+// AuditConfig holds the audit log settings.
+type AuditConfig struct {
+	Enabled        bool
+	Sink           string
+	FilePath       string
+	FileMaxSizeMB  int
+	FileMaxBackups int
+	SyslogNetwork  string
+	SyslogAddress  string
+	SyslogTag      string
+	RedisStream    string
+	RedisDbnum     int
+	RedisMaxLen    int64
+}
+
+// LambdaConfig holds the AWS Lambda authorizer settings.
+type LambdaConfig struct {
+	AuthorizerResponse       string
+	AuthorizerPolicyResource string
+	AuthorizerRules          []AuthorizerRule
+	AuthorizerRulesErr       error // set with invalid rules, so authorizers fail closed.
+	AuthorizerCacheTTLSec    int
+	RedisValidateIdleSec     int
+	RedisPingTimeoutMs       int
+}
+
+// An AuthorizerRule restricts a route of the Lambda authorizer by a claim of the token.
+//
+// The route is an optional method and a path prefix, e.g. "DELETE /orders".
+type AuthorizerRule struct {
+	Route  string   `mapstructure:"route" json:"route"`
+	Claim  string   `mapstructure:"claim" json:"claim"`
+	Values []string `mapstructure:"values" json:"values"`
+	Effect string   `mapstructure:"effect" json:"effect"` // allow or deny, in lower case.
+}
+
+var activeConfig atomic.Pointer[Config]
+
+// Serializes activations and reloads, which read and replace the settings.
+var configMu sync.Mutex
+
+// GetConfig returns the active configuration snapshot.
+//
+// Until a snapshot is activated, e.g. in the CLI or tests, a snapshot of the
+// current settings is loaded on every call, skipping invalid settings.
+func GetConfig() *Config {
+	if config := activeConfig.Load(); config != nil {
+		return config
+	}
+	config, _ := LoadConfig()
+	return config
+}
+
+// LoadConfig loads a snapshot of the current settings, and validates it.
+//
+// The snapshot is returned along with the validation errors, without the
+// invalid settings, e.g. without an invalid verification key.
+func LoadConfig() (*Config, error) {
+	config, errs := loadConfig(viper.GetViper())
+	return config, invalidConfigError(errs)
+}
+
+// NewConfig builds a validated snapshot of the defaults, overridden by the given settings.
+//
+// Unlike LoadConfig, neither the config file nor environment variables are
+// read, and the current settings are left untouched, e.g. for a library
+// embedding jwtblock.
+func NewConfig(settings map[string]interface{}) (*Config, error) {
+	v := viper.New()
+	initDefaults(v)
+	for option, value := range settings {
+		v.Set(option, value)
+	}
+
+	config, errs := loadConfig(v)
+	if err := invalidConfigError(append(errs, validateSettings(v)...)); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Wrap the errors of invalid options, or return nil without errors.
+func invalidConfigError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w:\n%w", ErrInvalidConfig, errors.Join(errs...))
+}
+
+// Load a snapshot of the settings, with the errors of the invalid options.
+func loadConfig(v *viper.Viper) (*Config, []error) {
+	var errs []error
+	invalid := func(option string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", option, fmt.Sprintf(format, args...)))
+	}
+
+	config := &Config{
+		LogLevel: configuredLogLevel(v),
+		HTTP: HTTPConfig{
+			CorsAllowedOrigins: splitList(v.GetString(OptStr_HttpCorsAllowedOrigins)),
+			CorsMaxSeconds:     v.GetInt(OptStr_HttpCorsMaxSeconds),
+			StatusOnAllowed:    v.GetInt(OptStr_HttpStatusOnAllowed),
+			StatusOnBlocked:    v.GetInt(OptStr_HttpStatusOnBlocked),
+			HeaderSha256:       v.GetString(OptStr_HttpHeaderSha256),
+			HeaderTTL:          v.GetString(OptStr_HttpHeaderTTL),
+			HeaderApiKey:       v.GetString(OptStr_HttpHeaderApiKey),
+			HeaderRequestID:    v.GetString(OptStr_HttpHeaderRequestID),
+			HeaderReason:       v.GetString(OptStr_HttpHeaderReason),
+			AccessLogEnabled:   v.GetBool(OptStr_HttpAccessLogEnabled),
+			AdminEnabled:       v.GetBool(OptStr_HttpAdminEnabled),
+			AdminApiKeys:       splitList(getSecret(v, OptStr_HttpAdminApiKeys)),
+			MetricsEnabled:     v.GetBool(OptStr_HttpMetricsEnabled),
+		},
+		JWT: JWTConfig{
+			ParseEnabled:    v.GetBool(OptStr_JwtParseEnabled),
+			ValidateEnabled: v.GetBool(OptStr_JwtValidateEnabled),
+			VerifyEnabled:   v.GetBool(OptStr_JwtVerifyEnabled),
+			RequireTokenExp: v.GetBool(OptStr_JwtTTLRequireTokenExp),
+			UseTokenExp:     v.GetBool(OptStr_JwtTTLUseTokenExp),
+			TTLDefaultSec:   v.GetInt(OptStr_JwtTTLDefaultSeconds),
+			TTLPaddingSec:   v.GetInt(OptStr_JwtTTLExpPaddingSeconds),
+			BlockClaims:     splitList(v.GetString(OptStr_JwtBlockClaims)),
+		},
+		Hash: HashConfig{
+			HmacSecrets:  splitList(getSecret(v, OptStr_HashHmacSecrets)),
+			CheckUnkeyed: v.GetBool(OptStr_HashCheckUnkeyed),
+		},
+		StoreFailure: StoreFailureConfig{
+			Policy:          strings.ToLower(strings.TrimSpace(v.GetString(OptStr_StoreFailurePolicy))),
+			Routes:          v.GetStringMapString(OptStr_StoreFailureRoutes),
+			Issuers:         v.GetStringMapString(OptStr_StoreFailureIssuers),
+			StaleAllowedSec: v.GetInt(OptStr_StoreFailureStaleAllowedSec),
+		},
+		Cache: CacheConfig{
+			LocalEnabled:          v.GetBool(OptStr_LocalCacheEnabled),
+			LocalSize:             v.GetInt(OptStr_LocalCacheSize),
+			LocalTTLBlockedMs:     v.GetInt(OptStr_LocalCacheTTLBlockedMs),
+			LocalTTLAllowedMs:     v.GetInt(OptStr_LocalCacheTTLAllowedMs),
+			Channel:               v.GetString(OptStr_LocalCacheChannel),
+			FilterEnabled:         v.GetBool(OptStr_FilterEnabled),
+			FilterCapacity:        v.GetInt64(OptStr_FilterCapacity),
+			FilterFPRate:          v.GetFloat64(OptStr_FilterFPRate),
+			FilterRebuildInterval: v.GetInt(OptStr_FilterRebuildIntervalSec),
+		},
+		Audit: AuditConfig{
+			Enabled:        v.GetBool(OptStr_AuditEnabled),
+			Sink:           v.GetString(OptStr_AuditSink),
+			FilePath:       v.GetString(OptStr_AuditFilePath),
+			FileMaxSizeMB:  v.GetInt(OptStr_AuditFileMaxSizeMB),
+			FileMaxBackups: v.GetInt(OptStr_AuditFileMaxBackups),
+			SyslogNetwork:  v.GetString(OptStr_AuditSyslogNetwork),
+			SyslogAddress:  v.GetString(OptStr_AuditSyslogAddress),
+			SyslogTag:      v.GetString(OptStr_AuditSyslogTag),
+			RedisStream:    v.GetString(OptStr_AuditRedisStream),
+			RedisDbnum:     v.GetInt(OptStr_AuditRedisDbnum),
+			RedisMaxLen:    v.GetInt64(OptStr_AuditRedisMaxLen),
+		},
+		Lambda: LambdaConfig{
+			AuthorizerResponse:       v.GetString(OptStr_LambdaAuthorizerResponse),
+			AuthorizerPolicyResource: v.GetString(OptStr_LambdaAuthorizerPolicyResource),
+			AuthorizerCacheTTLSec:    v.GetInt(OptStr_LambdaAuthorizerCacheTTLSec),
+			RedisValidateIdleSec:     v.GetInt(OptStr_LambdaRedisValidateIdleSec),
+			RedisPingTimeoutMs:       v.GetInt(OptStr_LambdaRedisPingTimeoutMs),
+		},
+	}
+
+	if _, err := parseLogLevel(config.LogLevel); err != nil {
+		invalid(OptStr_LogLevel, "%s", err.Error())
+	}
+
+	if config.HTTP.CorsMaxSeconds < 0 {
+		invalid(OptStr_HttpCorsMaxSeconds, "must not be negative")
+	}
+	for option, status := range map[string]int{
+		OptStr_HttpStatusOnAllowed: config.HTTP.StatusOnAllowed,
+		OptStr_HttpStatusOnBlocked: config.HTTP.StatusOnBlocked,
+	} {
+		if status < 100 || status > 599 {
+			invalid(option, "invalid HTTP status code %d", status)
+		}
+	}
+	for option, header := range map[string]string{
+		OptStr_HttpHeaderSha256:    config.HTTP.HeaderSha256,
+		OptStr_HttpHeaderTTL:       config.HTTP.HeaderTTL,
+		OptStr_HttpHeaderApiKey:    config.HTTP.HeaderApiKey,
+		OptStr_HttpHeaderRequestID: config.HTTP.HeaderRequestID,
+		OptStr_HttpHeaderReason:    config.HTTP.HeaderReason,
+	} {
+		if !validHeaderName.MatchString(header) {
+			invalid(option, "invalid HTTP header name %q", header)
+		}
+	}
+	for _, value := range splitList(v.GetString(OptStr_HttpTrustedProxies)) {
+		network, err := parseNetwork(value)
+		if err != nil {
+			invalid(OptStr_HttpTrustedProxies, "invalid IP or CIDR %q", value)
+			continue
+		}
+		config.HTTP.TrustedProxies = append(config.HTTP.TrustedProxies, network)
+	}
+
+	if config.JWT.TTLDefaultSec < 0 {
+		invalid(OptStr_JwtTTLDefaultSeconds, "must not be negative")
+	}
+	if config.JWT.TTLPaddingSec < 0 {
+		invalid(OptStr_JwtTTLExpPaddingSeconds, "must not be negative")
+	}
+	if config.Audit.FileMaxBackups < 1 {
+		invalid(OptStr_AuditFileMaxBackups, "must be at least 1, so rotation keeps the current file")
+	}
+	if option, err := loadVerifyKey(v, &config.JWT); err != nil {
+		invalid(option, "%s", err.Error())
+	}
+
+	for option, policies := range map[string]map[string]string{
+		OptStr_StoreFailureRoutes:  config.StoreFailure.Routes,
+		OptStr_StoreFailureIssuers: config.StoreFailure.Issuers,
+	} {
+		for key, policy := range policies {
+			if policy = strings.ToLower(strings.TrimSpace(policy)); policy != "open" && policy != "closed" {
+				invalid(option, "policy of %q must be one of closed, open, got %q", key, policy)
+			}
+		}
+	}
+
+	config.Lambda.AuthorizerRules, config.Lambda.AuthorizerRulesErr = loadAuthorizerRules(v)
+	if config.Lambda.AuthorizerRulesErr != nil {
+		invalid(OptStr_LambdaAuthorizerRules, "%s", config.Lambda.AuthorizerRulesErr.Error())
+	}
+
+	return config, errs
+}
+
+// Parse the configured verification key, an RSA public key or an HMAC secret JWK.
+//
+// The key is only loaded when verification is enabled. The invalid option is
+// returned with the error.
+func loadVerifyKey(v *viper.Viper, config *JWTConfig) (string, error) {
+	if !config.VerifyEnabled {
+		return "", nil
+	}
+
+	if rsaKey := getSecret(v, OptStr_JwtVerifyRsaKey); rsaKey != "" {
+		key, err := jwk.ParseKey([]byte(rsaKey), jwk.WithPEM(true))
+		if err != nil {
+			return OptStr_JwtVerifyRsaKey, err
+		}
+		config.VerifyKey, config.VerifyAlg = key, jwa.RS256
+	} else if hmacSecret := getSecret(v, OptStr_JwtVerifyHmacSecret); hmacSecret != "" {
+		key, err := jwk.ParseKey([]byte(hmacSecret))
+		if err != nil {
+			return OptStr_JwtVerifyHmacSecret, err
+		}
+		config.VerifyKey, config.VerifyAlg = key, jwa.HS256
+	} else {
+		return OptStr_JwtVerifyEnabled, errors.New("no key set for JWT verification")
+	}
+	return "", nil
+}
+
+// Parse the Lambda authorizer rules, a list in the config file, or a JSON array in the environment.
+func loadAuthorizerRules(v *viper.Viper) ([]AuthorizerRule, error) {
+	var rules []AuthorizerRule
+	if value, ok := v.Get(OptStr_LambdaAuthorizerRules).(string); ok {
+		if strings.TrimSpace(value) == "" {
+			return nil, nil
+		}
+		if err := json.Unmarshal([]byte(value), &rules); err != nil {
+			return nil, err
+		}
+	} else if err := v.UnmarshalKey(OptStr_LambdaAuthorizerRules, &rules); err != nil {
+		return nil, err
+	}
+
+	for i, rule := range rules {
+		rules[i].Effect = strings.ToLower(strings.TrimSpace(rule.Effect))
+		if rules[i].Effect != "allow" && rules[i].Effect != "deny" {
+			return nil, fmt.Errorf("rule %d: effect must be one of allow, deny, got %q", i, rule.Effect)
+		}
+		path := strings.TrimSpace(rule.Route)
+		if _, routePath, found := strings.Cut(path, " "); found {
+			path = strings.TrimSpace(routePath)
+		}
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("rule %d: route path must start with /, got %q", i, rule.Route)
+		}
+	}
+	return rules, nil
+}
+
+// Split a comma-separated list, skipping empty values.
+func splitList(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// Parse an IP address or CIDR into a network, where an IP is a single address network.
+func parseNetwork(value string) (*net.IPNet, error) {
+	if !strings.Contains(value, "/") {
+		if ip := net.ParseIP(value); ip != nil && ip.To4() != nil {
+			value += "/32"
+		} else {
+			value += "/128"
+		}
+	}
+	_, network, err := net.ParseCIDR(value)
+	return network, err
+}
+
+// ActivateConfig makes a snapshot of the current settings the active snapshot.
+//
+// Secret references are resolved again, and the whole configuration is
+// validated. A configuration with unresolved references or invalid settings
+// is rejected, keeping the previous snapshot active.
+func ActivateConfig() error {
+	configMu.Lock()
+	defer configMu.Unlock()
+	return activateConfig()
+}
+
+func activateConfig() error {
+	if err := resolveSecrets(viper.GetViper()); err != nil {
+		return err
+	}
+	config, errs := loadConfig(viper.GetViper())
+	if err := invalidConfigError(append(errs, validateSettings(viper.GetViper())...)); err != nil {
+		return err
+	}
+	return activate(config)
+}
+
+// SetConfig makes a validated snapshot the active snapshot, e.g. one built with NewConfig.
+func SetConfig(config *Config) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+	return activate(config)
+}
+
+// Make a validated snapshot the active snapshot.
+func activate(config *Config) error {
+	activeConfig.Store(config)
+	return SetLogLevel(config.LogLevel)
+}
+
+// ReloadConfig re-reads the configuration file, and activates a snapshot of its settings.
+//
+// The file is read into separate settings, with the same defaults, environment
+// variables and flags. Its secret references are resolved, and it is validated
+// before it replaces the current settings. Configurations with unresolved
+// references or invalid settings are rejected, leaving both the current settings and
+// the previous snapshot active. Settings outside the snapshot, e.g. the Redis
+// connection, still require a restart.
+func ReloadConfig() error {
+	configMu.Lock()
+	defer configMu.Unlock()
+	if err := reloadConfigFile(); err != nil {
+		metrics.Int("config_reload_failures_total").Add(1)
+		return err
+	}
+	metrics.Int("config_reloads_total").Add(1)
+	return nil
+}
+
+func reloadConfigFile() error {
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" {
+		// No config file in use, e.g. for secrets refreshed without one.
+		return activateConfig()
+	}
+
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+	settings, err := newSettings(content)
+	if err != nil {
+		return err
+	}
+	// An unresolved reference would be used as the secret itself.
+	if err := resolveSecrets(settings); err != nil {
+		return err
+	}
+	config, errs := loadConfig(settings)
+	if err := invalidConfigError(append(errs, validateSettings(settings)...)); err != nil {
+		return err
+	}
+
+	// Swap in the validated file, then its snapshot.
+	if err := viper.ReadConfig(bytes.NewReader(content)); err != nil {
+		return err
+	}
+	return activate(config)
+}
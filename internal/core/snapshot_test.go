@@ -0,0 +1,257 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+)
+
+func Test_LoadConfig_Defaults_Valid(t *testing.T) {
+	InitConfigDefaults()
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.HTTP.StatusOnBlocked != viper.GetInt(OptStr_HttpStatusOnBlocked) || !config.JWT.ParseEnabled || !config.Hash.CheckUnkeyed {
+		t.Errorf("Expected the default settings, got %+v", config)
+	}
+}
+
+func Test_LoadConfig_InvalidSettings_Error(t *testing.T) {
+	tests := map[string]interface{}{
+		OptStr_HttpStatusOnBlocked:     1000,
+		OptStr_HttpCorsMaxSeconds:      -1,
+		OptStr_HttpHeaderSha256:        "x bad header",
+		OptStr_HttpTrustedProxies:      "10.0.0.0/8,not-an-ip",
+		OptStr_JwtTTLDefaultSeconds:    -5,
+		OptStr_JwtVerifyEnabled:        true,
+		OptStr_LogLevel:                "verbose",
+		OptStr_JwtTTLExpPaddingSeconds: -1,
+		OptStr_AuditFileMaxBackups:     0,
+	}
+	for option, value := range tests {
+		t.Run(option, func(t *testing.T) {
+			InitConfigDefaults()
+			setConfigOption(t, option, value)
+
+			if _, err := LoadConfig(); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Expected ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+}
+
+func Test_LoadConfig_InvalidTrustedProxy_KeepsValidProxies(t *testing.T) {
+	InitConfigDefaults()
+	setConfigOption(t, OptStr_HttpTrustedProxies, "10.0.0.0/8, not-an-ip, 192.0.2.1")
+
+	config, _ := LoadConfig()
+	if len(config.HTTP.TrustedProxies) != 2 {
+		t.Errorf("Expected 2 trusted proxies, got %v", config.HTTP.TrustedProxies)
+	}
+}
+
+func Test_LoadConfig_VerifyKey_Parsed(t *testing.T) {
+	InitConfigDefaults()
+	setConfigOption(t, OptStr_JwtVerifyEnabled, true)
+	setConfigOption(t, OptStr_JwtVerifyHmacSecret, `{"kty":"oct","k":"Zm9vYmFy"}`)
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.JWT.VerifyKey == nil || config.JWT.VerifyAlg.String() != "HS256" {
+		t.Errorf("Expected an HS256 verification key, got %v %v", config.JWT.VerifyKey, config.JWT.VerifyAlg)
+	}
+}
+
+func Test_ActivateConfig_Settings_Pinned(t *testing.T) {
+	InitConfigDefaults()
+	resetActiveConfig(t)
+	setConfigOption(t, OptStr_HttpStatusOnBlocked, 403)
+	if err := ActivateConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	setConfigOption(t, OptStr_HttpStatusOnBlocked, 410)
+	if status := GetConfig().HTTP.StatusOnBlocked; status != 403 {
+		t.Errorf("Expected the active snapshot status 403, got %d", status)
+	}
+}
+
+func Test_ActivateConfig_Invalid_KeepsPrevious(t *testing.T) {
+	InitConfigDefaults()
+	resetActiveConfig(t)
+	setConfigOption(t, OptStr_HttpStatusOnBlocked, 403)
+	if err := ActivateConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	setConfigOption(t, OptStr_HttpStatusOnBlocked, 1000)
+	if err := ActivateConfig(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+	if status := GetConfig().HTTP.StatusOnBlocked; status != 403 {
+		t.Errorf("Expected the previous status 403, got %d", status)
+	}
+}
+
+func Test_ReloadConfig_ChangedFile_Reloaded(t *testing.T) {
+	InitConfigDefaults()
+	resetActiveConfig(t)
+	configPath := filepath.Join(t.TempDir(), "jwtblock.yaml")
+	writeConfigFile(t, configPath, "http:\n  status:\n    on_blocked: 403\n")
+	useConfigFile(t, configPath)
+	if err := ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloads := metrics.Int("config_reloads_total").Value()
+	writeConfigFile(t, configPath, "http:\n  status:\n    on_blocked: 410\n")
+	if err := ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if status := GetConfig().HTTP.StatusOnBlocked; status != 410 {
+		t.Errorf("Expected the reloaded status 410, got %d", status)
+	}
+	if actual := metrics.Int("config_reloads_total").Value(); actual != reloads+1 {
+		t.Errorf("Expected %d reloads, got %d", reloads+1, actual)
+	}
+}
+
+func Test_ReloadConfig_InvalidFile_Rejected(t *testing.T) {
+	InitConfigDefaults()
+	resetActiveConfig(t)
+	configPath := filepath.Join(t.TempDir(), "jwtblock.yaml")
+	writeConfigFile(t, configPath, "http:\n  status:\n    on_blocked: 403\n")
+	useConfigFile(t, configPath)
+	if err := ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	failures := metrics.Int("config_reload_failures_total").Value()
+	writeConfigFile(t, configPath, "http:\n  status:\n    on_blocked: 9999\n")
+	if err := ReloadConfig(); err == nil {
+		t.Error("Expected the invalid configuration to be rejected")
+	}
+	if status := GetConfig().HTTP.StatusOnBlocked; status != 403 {
+		t.Errorf("Expected the previous status 403, got %d", status)
+	}
+	if actual := metrics.Int("config_reload_failures_total").Value(); actual != failures+1 {
+		t.Errorf("Expected %d reload failures, got %d", failures+1, actual)
+	}
+}
+
+func Test_ReloadConfig_InvalidFile_SettingsUnchanged(t *testing.T) {
+	InitConfigDefaults()
+	resetActiveConfig(t)
+	configPath := filepath.Join(t.TempDir(), "jwtblock.yaml")
+	writeConfigFile(t, configPath, "hash:\n  hmac_secrets: old\n")
+	useConfigFile(t, configPath)
+	if err := ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeConfigFile(t, configPath, "hash:\n  hmac_secrets: new\nhttp:\n  status:\n    on_blocked: 9999\n")
+	if err := ReloadConfig(); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("Expected ErrInvalidConfig, got %v", err)
+	}
+	if secrets := viper.GetString(OptStr_HashHmacSecrets); secrets != "old" {
+		t.Errorf("Expected the previous secret to stay set, got %q", secrets)
+	}
+	if status := viper.GetInt(OptStr_HttpStatusOnBlocked); status != 401 {
+		t.Errorf("Expected the previous status 401 to stay set, got %d", status)
+	}
+}
+
+func Test_ReloadConfig_SecretReferences_Resolved(t *testing.T) {
+	InitConfigDefaults()
+	resetActiveConfig(t)
+	useSecretResolver(t, map[string]string{"test://admin-key": "s3cret"})
+	configPath := filepath.Join(t.TempDir(), "jwtblock.yaml")
+	writeConfigFile(t, configPath, "http:\n  admin:\n    api_keys: plain\n")
+	useConfigFile(t, configPath)
+	if err := ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeConfigFile(t, configPath, "http:\n  admin:\n    api_keys: test://admin-key\n")
+	if err := ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if keys := GetConfig().HTTP.AdminApiKeys; len(keys) != 1 || keys[0] != "s3cret" {
+		t.Errorf("Expected the resolved admin key, got %v", keys)
+	}
+}
+
+func Test_ReloadConfig_UnresolvedSecretReference_Rejected(t *testing.T) {
+	InitConfigDefaults()
+	resetActiveConfig(t)
+	useSecretResolver(t, map[string]string{})
+	configPath := filepath.Join(t.TempDir(), "jwtblock.yaml")
+	writeConfigFile(t, configPath, "http:\n  admin:\n    api_keys: plain\n")
+	useConfigFile(t, configPath)
+	if err := ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeConfigFile(t, configPath, "http:\n  admin:\n    api_keys: test://missing\n")
+	if err := ReloadConfig(); !errors.Is(err, ErrUnresolvedSecret) {
+		t.Fatalf("Expected ErrUnresolvedSecret, got %v", err)
+	}
+	if keys := GetConfig().HTTP.AdminApiKeys; len(keys) != 1 || keys[0] != "plain" {
+		t.Errorf("Expected the previous admin key, got %v", keys)
+	}
+	if keys := viper.GetString(OptStr_HttpAdminApiKeys); keys != "plain" {
+		t.Errorf("Expected the previous admin key setting, got %q", keys)
+	}
+}
+
+func setConfigOption(t *testing.T, option string, value interface{}) {
+	viper.Set(option, value)
+	t.Cleanup(func() { viper.Set(option, nil) })
+}
+
+func resetActiveConfig(t *testing.T) {
+	activeConfig.Store(nil)
+	t.Cleanup(func() { activeConfig.Store(nil) })
+}
+
+// Resolve the "test://" references of the admin keys from the given secrets.
+func useSecretResolver(t *testing.T, secrets map[string]string) {
+	SetSecretResolver(func(v *viper.Viper) error {
+		reference := v.GetString(OptStr_HttpAdminApiKeys)
+		if !strings.HasPrefix(reference, "test://") {
+			return nil
+		}
+		value, ok := secrets[reference]
+		if !ok {
+			return errors.New("secret not found")
+		}
+		SetSecret(OptStr_HttpAdminApiKeys, reference, value)
+		return nil
+	})
+	t.Cleanup(func() { SetSecretResolver(nil) })
+}
+
+// Use a config file, and forget its settings after the test.
+func useConfigFile(t *testing.T, path string) {
+	viper.SetConfigFile(path)
+	t.Cleanup(func() {
+		viper.SetConfigFile("")
+		_ = viper.ReadConfig(strings.NewReader(""))
+	})
+}
+
+func writeConfigFile(t *testing.T, path string, content string) {
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
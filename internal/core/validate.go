@@ -0,0 +1,124 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Valid values of the enumerated configuration options.
+var optionValues = map[string][]string{
+	OptStr_LogEncoding:                    {"json", "console"},
+	OptStr_StoreFailurePolicy:             {"closed", "open"},
+	OptStr_AuditSink:                      {"file", "syslog", "redis"},
+	OptStr_LambdaAuthorizerResponse:       {"policy", "simple"},
+	OptStr_LambdaAuthorizerPolicyResource: {"exact", "wildcard"},
+}
+
+// ValidateConfig validates the whole configuration, not only the settings of the snapshot.
+//
+// Every invalid option is reported, e.g. an unknown value, conflicting
+// options, a missing key, or a file that cannot be read or written.
+func ValidateConfig() error {
+	_, errs := loadConfig(viper.GetViper())
+	return invalidConfigError(append(errs, validateSettings(viper.GetViper())...))
+}
+
+// Validate the settings outside the configuration snapshot.
+func validateSettings(v *viper.Viper) []error {
+	var errs []error
+	invalid := func(option string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", option, fmt.Sprintf(format, args...)))
+	}
+
+	for option, values := range optionValues {
+		value := strings.ToLower(strings.TrimSpace(v.GetString(option)))
+		if !containsString(values, value) {
+			invalid(option, "must be one of %s, got %q", strings.Join(values, ", "), value)
+		}
+	}
+
+	// Conflicting options.
+	if v.GetString(OptStr_JwtVerifyRsaKey) != "" && v.GetString(OptStr_JwtVerifyHmacSecret) != "" {
+		invalid(OptStr_JwtVerifyRsaKey, "conflicts with %s, set only one verification key", OptStr_JwtVerifyHmacSecret)
+	}
+	if !v.GetBool(OptStr_JwtParseEnabled) && v.GetBool(OptStr_JwtVerifyEnabled) {
+		invalid(OptStr_JwtVerifyEnabled, "requires %s", OptStr_JwtParseEnabled)
+	}
+	if v.GetBool(OptStr_JwtTTLRequireTokenExp) && !v.GetBool(OptStr_JwtValidateEnabled) {
+		invalid(OptStr_JwtTTLRequireTokenExp, "requires %s", OptStr_JwtValidateEnabled)
+	}
+	if v.GetString(OptStr_RedisClusterAddrs) != "" && v.GetString(OptStr_RedisSentinelMasterName) != "" {
+		invalid(OptStr_RedisClusterAddrs, "conflicts with %s, use either Redis Cluster or Sentinel", OptStr_RedisSentinelMasterName)
+	}
+	if v.GetBool(OptStr_AuditEnabled) && v.GetString(OptStr_AuditSink) == "redis" {
+		if v.GetString(OptStr_RedisClusterAddrs) != "" {
+			invalid(OptStr_AuditSink, "the redis sink does not support Redis Cluster")
+		}
+		if v.GetInt(OptStr_AuditRedisDbnum) == v.GetInt(OptStr_RedisDbnum) {
+			invalid(OptStr_AuditRedisDbnum, "must differ from %s, which is flushed with the blocklist", OptStr_RedisDbnum)
+		}
+	}
+
+	// Missing keys.
+	if v.GetBool(OptStr_HttpAdminEnabled) && strings.Trim(getSecret(v, OptStr_HttpAdminApiKeys), ", ") == "" {
+		invalid(OptStr_HttpAdminApiKeys, "must be set when %s is true", OptStr_HttpAdminEnabled)
+	}
+
+	// Files.
+	for _, option := range SecretOptions() {
+		for _, value := range strings.Split(v.GetString(option), ",") {
+			path, ok := strings.CutPrefix(strings.TrimSpace(value), "file://")
+			if !ok {
+				continue
+			}
+			if file, err := os.Open(path); err != nil {
+				invalid(option, "cannot read secret file: %s", err.Error())
+			} else {
+				file.Close()
+			}
+		}
+	}
+	if v.GetBool(OptStr_AuditEnabled) && v.GetString(OptStr_AuditSink) == "file" {
+		if err := checkWritableDir(v.GetString(OptStr_AuditFilePath)); err != nil {
+			invalid(OptStr_AuditFilePath, "%s", err.Error())
+		}
+	}
+	for _, option := range []string{OptStr_LogOutputPaths, OptStr_LogErrorOutputPaths} {
+		for _, path := range splitLogPaths(v.GetString(option), "stdout") {
+			if path == "stdout" || path == "stderr" || strings.Contains(path, "://") {
+				continue
+			}
+			if err := checkWritableDir(path); err != nil {
+				invalid(option, "%s", err.Error())
+			}
+		}
+	}
+
+	return errs
+}
+
+// Check that the directory of a file exists, so the file can be created.
+func checkWritableDir(path string) error {
+	dir := filepath.Dir(path)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("directory of %s is not reachable: %w", path, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func Test_ValidateConfig_Defaults_Valid(t *testing.T) {
+	InitConfigDefaults()
+
+	if err := ValidateConfig(); err != nil {
+		t.Errorf("Expected the defaults to be valid, got %v", err)
+	}
+}
+
+func Test_ValidateConfig_InvalidSettings_Error(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"unknown value": {
+			OptStr_StoreFailurePolicy: "ajar",
+		},
+		"conflicting keys": {
+			OptStr_JwtVerifyRsaKey:     "rsa",
+			OptStr_JwtVerifyHmacSecret: "hmac",
+		},
+		"verify without parse": {
+			OptStr_JwtParseEnabled:  false,
+			OptStr_JwtVerifyEnabled: true,
+		},
+		"cluster and sentinel": {
+			OptStr_RedisClusterAddrs:       "localhost:7000",
+			OptStr_RedisSentinelMasterName: "mymaster",
+		},
+		"audit in the blocklist db": {
+			OptStr_AuditEnabled:      true,
+			OptStr_AuditSink:         "redis",
+			OptStr_AuditRedisDbnum:   0,
+			OptStr_RedisDbnum:        0,
+			OptStr_RedisClusterAddrs: "",
+		},
+		"admin without api keys": {
+			OptStr_HttpAdminEnabled: true,
+		},
+		"unreadable secret file": {
+			OptStr_RedisPassword: "file:///nonexistent/jwtblock/redis",
+		},
+		"unreachable audit file": {
+			OptStr_AuditEnabled:  true,
+			OptStr_AuditFilePath: "/nonexistent/jwtblock/audit.log",
+		},
+		"unreachable log file": {
+			OptStr_LogOutputPaths: "stdout,/nonexistent/jwtblock/jwtblock.log",
+		},
+	}
+	for name, settings := range tests {
+		t.Run(name, func(t *testing.T) {
+			InitConfigDefaults()
+			for option, value := range settings {
+				setConfigOption(t, option, value)
+			}
+
+			if err := ValidateConfig(); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Expected ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+}
+
+func Test_ValidateConfig_InvalidSettings_AllReported(t *testing.T) {
+	InitConfigDefaults()
+	setConfigOption(t, OptStr_HttpStatusOnBlocked, 1000)
+	setConfigOption(t, OptStr_LogEncoding, "xml")
+
+	err := ValidateConfig()
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, option := range []string{OptStr_HttpStatusOnBlocked, OptStr_LogEncoding} {
+		if !strings.Contains(err.Error(), option) {
+			t.Errorf("Expected the error to name %s, got %v", option, err)
+		}
+	}
+}
+
+func Test_ValidateConfig_ReadableSecretFile_Valid(t *testing.T) {
+	InitConfigDefaults()
+	path := filepath.Join(t.TempDir(), "redis-password")
+	writeConfigFile(t, path, "s3cret")
+	setConfigOption(t, OptStr_RedisPassword, "file://"+path)
+
+	if err := ValidateConfig(); err != nil {
+		t.Errorf("Expected a valid configuration, got %v", err)
+	}
+}
+
+func Test_ActivateConfig_InvalidSettings_Rejected(t *testing.T) {
+	InitConfigDefaults()
+	resetActiveConfig(t)
+	setConfigOption(t, OptStr_HttpAdminEnabled, true)
+
+	if err := ActivateConfig(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func Test_ExampleConfig_Defaults_Valid(t *testing.T) {
+	InitConfigDefaults()
+	configPath := filepath.Join(t.TempDir(), "jwtblock.yaml")
+	writeConfigFile(t, configPath, ExampleConfig())
+
+	example := viper.New()
+	example.SetConfigFile(configPath)
+	if err := example.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	for _, option := range example.AllKeys() {
+		if !viper.IsSet(option) {
+			t.Errorf("Unknown option %s in the example config", option)
+		} else if actual, expected := example.GetString(option), viper.GetString(option); actual != expected {
+			t.Errorf("Expected the default %s of %s, got %s", expected, option, actual)
+		}
+	}
+}
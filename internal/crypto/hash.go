@@ -1,10 +1,13 @@
 package crypto
 
 import (
+	"crypto/hmac"
 	"crypto/sha256"
 	"errors"
 	"fmt"
 	"regexp"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
 )
 
 // General error messages from the hash utilities.
@@ -21,6 +24,90 @@ func Sha256FromString(value string) string {
 	return sha256Hash
 }
 
+// Get the HMAC-SHA256 digest of a string value, keyed with a secret.
+func HmacSha256FromString(secret string, value string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(value))
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// TokenHash returns the blocklist key of a token.
+//
+// The key is the HMAC-SHA256 of the token with the newest configured secret,
+// or the plain SHA256 of the token without secrets.
+func TokenHash(tokenString string) string {
+	secrets := core.GetConfig().Hash.HmacSecrets
+	if len(secrets) == 0 {
+		return Sha256FromString(tokenString)
+	}
+	return HmacSha256FromString(secrets[0], tokenString)
+}
+
+// TokenHashes returns every blocklist key a token may be stored under, the current one first.
+//
+// During secret rotation, tokens blocked with older secrets are still found.
+// Unkeyed SHA256 keys are included while migrating to HMAC keys.
+func TokenHashes(tokenString string) []string {
+	config := core.GetConfig()
+	secrets := config.Hash.HmacSecrets
+	if len(secrets) == 0 {
+		return []string{Sha256FromString(tokenString)}
+	}
+
+	hashes := make([]string, 0, len(secrets)+1)
+	for _, secret := range secrets {
+		hashes = append(hashes, HmacSha256FromString(secret, tokenString))
+	}
+	if config.Hash.CheckUnkeyed {
+		hashes = append(hashes, Sha256FromString(tokenString))
+	}
+	return hashes
+}
+
+// Domain of the claim key derivation, the HMAC key of unkeyed claim keys.
+const claimKeyDomain = "jwtblock claim key"
+
+// ClaimHash returns the blocklist key of a claim value, which blocks every token with the claim value.
+//
+// Claim keys are HMAC-SHA256 digests with a key derived from the secret, so
+// they are indistinguishable from token keys in the blocklist, but no token
+// value hashes to a claim key.
+func ClaimHash(claim string, value string) string {
+	secrets := core.GetConfig().Hash.HmacSecrets
+	if len(secrets) == 0 {
+		return HmacSha256FromString(claimKeyDomain, claimKeyValue(claim, value))
+	}
+	return HmacSha256FromString(claimKeySecret(secrets[0]), claimKeyValue(claim, value))
+}
+
+// ClaimHashes returns every blocklist key a claim value may be stored under, the current one first.
+func ClaimHashes(claim string, value string) []string {
+	config := core.GetConfig()
+	secrets := config.Hash.HmacSecrets
+	if len(secrets) == 0 {
+		return []string{HmacSha256FromString(claimKeyDomain, claimKeyValue(claim, value))}
+	}
+
+	hashes := make([]string, 0, len(secrets)+1)
+	for _, secret := range secrets {
+		hashes = append(hashes, HmacSha256FromString(claimKeySecret(secret), claimKeyValue(claim, value)))
+	}
+	if config.Hash.CheckUnkeyed {
+		hashes = append(hashes, HmacSha256FromString(claimKeyDomain, claimKeyValue(claim, value)))
+	}
+	return hashes
+}
+
+// Get the HMAC key of claim keys, derived from a hash secret.
+func claimKeySecret(secret string) string {
+	return HmacSha256FromString(secret, claimKeyDomain)
+}
+
+// Get the hashed value of a claim key.
+func claimKeyValue(claim string, value string) string {
+	return "claim:" + claim + "=" + value
+}
+
 // Check if a string value is a valid SHA256 hash.
 func IsValidSha256(value string) error {
 	re := regexp.MustCompile(`^[A-Fa-f0-9]{64}$`)
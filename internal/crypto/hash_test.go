@@ -2,6 +2,10 @@ package crypto
 
 import (
 	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
 )
 
 func Test_Sha256FromString_Success(t *testing.T) {
@@ -42,3 +46,86 @@ func Test_IsValidSha256_InvalidHash_Error(t *testing.T) {
 		t.Errorf("Expected invalid Sha256 with more characters: hashText=%s", err)
 	}
 }
+
+func Test_HmacSha256FromString_Success(t *testing.T) {
+	plainText := "foobar"
+	hashText := "4fcc06915b43d8a49aff193441e9e18654e6a27c2c428b02e8fcc41ccc2299f9"
+
+	result := HmacSha256FromString("secret", plainText)
+	if result != hashText {
+		t.Errorf("Hash mismatch: text=%s, expected=%s, actual=%s", plainText, hashText, result)
+	}
+}
+
+func Test_TokenHash_NoSecrets_Sha256(t *testing.T) {
+	setupHashSecrets(t, "", false)
+
+	result := TokenHash("foobar")
+	if result != Sha256FromString("foobar") {
+		t.Errorf("Expected plain SHA256 without secrets: actual=%s", result)
+	}
+	if hashes := TokenHashes("foobar"); len(hashes) != 1 || hashes[0] != result {
+		t.Errorf("Expected only the plain SHA256: hashes=%v", hashes)
+	}
+}
+
+func Test_TokenHashes_Rotation_AllSecrets(t *testing.T) {
+	setupHashSecrets(t, "new, old,", true)
+
+	if TokenHash("foobar") != HmacSha256FromString("new", "foobar") {
+		t.Errorf("Expected the newest secret to be used for writing")
+	}
+
+	hashes := TokenHashes("foobar")
+	expected := []string{
+		HmacSha256FromString("new", "foobar"),
+		HmacSha256FromString("old", "foobar"),
+		Sha256FromString("foobar"),
+	}
+	if len(hashes) != len(expected) {
+		t.Fatalf("Hash count mismatch: expected=%v, actual=%v", expected, hashes)
+	}
+	for i := range expected {
+		if hashes[i] != expected[i] {
+			t.Errorf("Hash mismatch: index=%d, expected=%s, actual=%s", i, expected[i], hashes[i])
+		}
+	}
+}
+
+func Test_ClaimHashes_ClaimKeyAsToken_NoCollision(t *testing.T) {
+	tests := map[string]struct {
+		secrets      string
+		checkUnkeyed bool
+	}{
+		"noSecrets": {},
+		"secrets":   {secrets: "new, old", checkUnkeyed: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			setupHashSecrets(t, test.secrets, test.checkUnkeyed)
+
+			tokenHashes := map[string]bool{}
+			for _, hash := range TokenHashes(claimKeyValue("sub", "1234567890")) {
+				tokenHashes[hash] = true
+			}
+			hashes := ClaimHashes("sub", "1234567890")
+			if hashes[0] != ClaimHash("sub", "1234567890") {
+				t.Errorf("Expected the current claim key first: hashes=%v", hashes)
+			}
+			for _, hash := range hashes {
+				if tokenHashes[hash] {
+					t.Errorf("Expected the claim key to differ from the token keys: hash=%s", hash)
+				}
+			}
+		})
+	}
+}
+
+func setupHashSecrets(t *testing.T, secrets string, checkUnkeyed bool) {
+	viper.Set(core.OptStr_HashHmacSecrets, secrets)
+	viper.Set(core.OptStr_HashCheckUnkeyed, checkUnkeyed)
+	t.Cleanup(func() {
+		viper.Set(core.OptStr_HashHmacSecrets, "")
+		viper.Set(core.OptStr_HashCheckUnkeyed, nil)
+	})
+}
@@ -1,14 +1,15 @@
 package crypto
 
 import (
+	"context"
 	"errors"
 
-	"github.com/lestrrat-go/jwx/v2/jwa"
-	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/jwx/v2/jwt"
-	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/tracing"
 )
 
 // General error messages from JWT utilities.
@@ -16,52 +17,25 @@ var (
 	ErrJwtVerificationKeyNotSet = errors.New("no key set for JWT verification")
 )
 
-func getJwtParserOptions() ([]jwt.ParseOption, error) {
+func getJwtParserOptions(config core.JWTConfig) ([]jwt.ParseOption, error) {
 	// Set the JWT parser options based on configuration values.
-	var err error
-
-	// Configure the JWT parser.
-	doValidate := viper.GetBool(core.OptStr_JwtValidateEnabled)
-	doVerify := viper.GetBool(core.OptStr_JwtVerifyEnabled)
-	requireExp := viper.GetBool(core.OptStr_JwtTTLRequireTokenExp)
-
 	var jwtParseOptions = []jwt.ParseOption{
-		jwt.WithValidate(doValidate),
-		jwt.WithVerify(doVerify),
+		jwt.WithValidate(config.ValidateEnabled),
+		jwt.WithVerify(config.VerifyEnabled),
 	}
 
 	// Require the expiration claim to be present.
-	if doValidate && requireExp {
+	if config.ValidateEnabled && config.RequireTokenExp {
 		jwtParseOptions = append(jwtParseOptions, jwt.WithRequiredClaim("exp"))
 	}
 
-	// Figure out the verification key.
-	if doVerify {
-		var parsedKey jwk.Key
-
-		if viper.GetString(core.OptStr_JwtVerifyRsaKey) != "" {
-			// Verify with RSA?
-			rsaPublicKey := viper.GetString(core.OptStr_JwtVerifyRsaKey)
-			parsedKey, err = jwk.ParseKey([]byte(rsaPublicKey), jwk.WithPEM(true))
-			if err != nil {
-				return jwtParseOptions, err
-			}
-			jwtParseOptions = append(jwtParseOptions, jwt.WithKey(jwa.RS256, parsedKey))
-
-		} else if viper.GetString(core.OptStr_JwtVerifyHmacSecret) != "" {
-			// Verify with HMAC?
-			hmacSecret := viper.GetString(core.OptStr_JwtVerifyHmacSecret)
-			key, err := jwk.ParseKey([]byte(hmacSecret))
-			if err != nil {
-				return jwtParseOptions, err
-			}
-			jwtParseOptions = append(jwtParseOptions, jwt.WithKey(jwa.HS256, key))
-
-		} else {
+	// Verify with the RSA or HMAC key of the configuration snapshot.
+	if config.VerifyEnabled {
+		if config.VerifyKey == nil {
 			// No key/alg to verify with.
 			return jwtParseOptions, ErrJwtVerificationKeyNotSet
 		}
-
+		jwtParseOptions = append(jwtParseOptions, jwt.WithKey(config.VerifyAlg, config.VerifyKey))
 	}
 
 	return jwtParseOptions, nil
@@ -71,18 +45,29 @@ func getJwtParserOptions() ([]jwt.ParseOption, error) {
 //
 // JWT parsing, validation, and verification are configurable.
 func RunJwtChecks(tokenString string) (jwt.Token, error) {
+	return RunJwtChecksContext(context.Background(), tokenString)
+}
+
+// Check a JWT by parsing, validating, and verifying, traced as part of the context's trace.
+func RunJwtChecksContext(ctx context.Context, tokenString string) (jwt.Token, error) {
 	// Parse and verify the JWT.
 	logger := core.GetLogger()
 	var token jwt.Token
 
-	doParse := viper.GetBool(core.OptStr_JwtParseEnabled)
-	if doParse {
-		jwtParserOptions, err := getJwtParserOptions()
+	config := core.GetConfig().JWT
+	if config.ParseEnabled {
+		_, span := tracing.Start(ctx, "jwt.checks", trace.WithAttributes(
+			attribute.Bool("jwt.validate", config.ValidateEnabled),
+			attribute.Bool("jwt.verify", config.VerifyEnabled),
+		))
+		jwtParserOptions, err := getJwtParserOptions(config)
 		if err != nil {
+			tracing.End(span, err)
 			return nil, err
 		}
 
 		token, err = jwt.Parse([]byte(tokenString), jwtParserOptions...)
+		tracing.End(span, err)
 		if err != nil {
 			logger.Errorw(
 				"Failed to parse token",
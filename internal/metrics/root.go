@@ -0,0 +1,67 @@
+// Package metrics implements the runtime metrics of jwtblock.
+//
+// Metrics are expvar variables in a single "jwtblock" map, and are served as
+// flat JSON without the process-wide expvar variables (e.g. cmdline, which can
+// contain secrets).
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+var mu sync.Mutex
+var registry = expvar.NewMap("jwtblock")
+
+// Int returns the named integer metric, creating it if needed.
+func Int(name string) *expvar.Int {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if v, ok := registry.Get(name).(*expvar.Int); ok {
+		return v
+	}
+	v := new(expvar.Int)
+	registry.Set(name, v)
+	return v
+}
+
+// Float returns the named float metric, creating it if needed.
+func Float(name string) *expvar.Float {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if v, ok := registry.Get(name).(*expvar.Float); ok {
+		return v
+	}
+	v := new(expvar.Float)
+	registry.Set(name, v)
+	return v
+}
+
+// Func sets the named metric to be computed when read, replacing any previous one.
+func Func(name string, f func() interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	registry.Set(name, expvar.Func(f))
+}
+
+// Value returns the JSON value of the named metric, or an empty string if it does not exist.
+func Value(name string) string {
+	v := registry.Get(name)
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
+
+// Handler serves the metrics as a JSON object.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, registry.String())
+	})
+}
@@ -0,0 +1,105 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+// Load the AWS configuration, from the environment, shared config files or the instance role.
+//
+// Secrets are resolved rarely, so the configuration is not kept between
+// resolutions, and credential rotation is picked up.
+func awsConfig(ctx context.Context) (aws.Config, error) {
+	var options []func(*config.LoadOptions) error
+	if region := viper.GetString(core.OptStr_SecretsAwsRegion); region != "" {
+		options = append(options, config.WithRegion(region))
+	}
+	return config.LoadDefaultConfig(ctx, options...)
+}
+
+// Get the configured AWS endpoint, or nil for the AWS endpoints.
+func awsEndpoint() *string {
+	if endpoint := viper.GetString(core.OptStr_SecretsAwsEndpoint); endpoint != "" {
+		return aws.String(endpoint)
+	}
+	return nil
+}
+
+// Get a parameter from SSM Parameter Store, decrypting SecureString parameters.
+//
+// The name is the parameter name or ARN, e.g. "/jwtblock/redis-password".
+func resolveSSMParameter(ctx context.Context, name string) (string, error) {
+	cfg, err := awsConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	client := ssm.NewFromConfig(cfg, func(o *ssm.Options) {
+		o.BaseEndpoint = awsEndpoint()
+	})
+
+	output, err := client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+	if output.Parameter == nil || output.Parameter.Value == nil {
+		return "", ErrSecretNotFound
+	}
+	return *output.Parameter.Value, nil
+}
+
+// Get a secret from Secrets Manager.
+//
+// The location is the secret name or ARN, optionally followed by "#" and a key
+// of a JSON secret, e.g. "jwtblock/redis#password".
+func resolveSecretsManagerSecret(ctx context.Context, location string) (string, error) {
+	secretID, jsonKey, _ := strings.Cut(location, "#")
+
+	cfg, err := awsConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	client := secretsmanager.NewFromConfig(cfg, func(o *secretsmanager.Options) {
+		o.BaseEndpoint = awsEndpoint()
+	})
+
+	output, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretID),
+	})
+	if err != nil {
+		return "", err
+	}
+	var value string
+	switch {
+	case output.SecretString != nil:
+		value = *output.SecretString
+	case output.SecretBinary != nil:
+		value = string(output.SecretBinary)
+	default:
+		return "", ErrSecretNotFound
+	}
+	if jsonKey == "" {
+		return value, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", err
+	}
+	field, ok := fields[jsonKey].(string)
+	if !ok {
+		return "", ErrSecretKeyNotFound
+	}
+	return field, nil
+}
@@ -0,0 +1,18 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// Read a secret from a file, e.g. a Kubernetes secret mounted in a volume.
+//
+// Trailing newlines are trimmed, since editors and "echo" add them.
+func resolveFile(ctx context.Context, path string) (string, error) {
+	value, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(value), "\r\n"), nil
+}
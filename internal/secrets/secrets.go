@@ -0,0 +1,218 @@
+// Package secrets resolves configuration values referencing secrets.
+//
+// Secret options (e.g. "redis.password" or "hash.hmac_secrets") may hold a
+// reference instead of the secret itself:
+//
+//	file:///run/secrets/redis-password
+//	aws-ssm:///jwtblock/redis-password
+//	aws-secretsmanager://jwtblock/redis#password
+//
+// References are resolved at startup, cached, and refreshed periodically.
+// The references of reloaded config files are resolved before they are used,
+// and a reload with unresolved references is rejected.
+// Resolved values are read with core.GetSecret.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+)
+
+// General error messages from the secret resolvers.
+var (
+	ErrEmptySecret       = errors.New("secret is empty")
+	ErrSecretNotFound    = errors.New("secret not found")
+	ErrSecretKeyNotFound = errors.New("secret JSON key not found")
+)
+
+// A resolver gets the value of a secret, from the part of a reference after its scheme.
+type resolver func(ctx context.Context, location string) (string, error)
+
+// Resolvers of the secret reference schemes.
+var resolvers = map[string]resolver{
+	"file":               resolveFile,
+	"aws-ssm":            resolveSSMParameter,
+	"aws-secretsmanager": resolveSecretsManagerSecret,
+}
+
+// A cachedSecret is a resolved secret, reused until it expires.
+type cachedSecret struct {
+	value   string
+	expires time.Time
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]cachedSecret{}
+)
+
+var once sync.Once
+var initErr error
+
+// Init resolves the secret references of the configuration, and refreshes them in the background.
+//
+// Secrets are refreshed every "secrets.refresh_sec", keeping the previous value
+// when a refresh fails. Safe to call more than once.
+func Init(ctx context.Context) error {
+	once.Do(func() {
+		initErr = ResolveOptions(ctx)
+		if initErr != nil {
+			return
+		}
+		core.SetSecretResolver(func(v *viper.Viper) error {
+			return ResolveSettings(context.Background(), v)
+		})
+
+		refresh := time.Duration(viper.GetInt(core.OptStr_SecretsRefreshSec)) * time.Second
+		if refresh > 0 && hasReferences() {
+			go refreshOptions(refresh)
+		}
+	})
+	return initErr
+}
+
+// IsReference checks if a configuration value references a secret.
+func IsReference(value string) bool {
+	scheme, _, found := strings.Cut(value, "://")
+	if !found {
+		return false
+	}
+	_, ok := resolvers[scheme]
+	return ok
+}
+
+// Resolve gets the value of a secret reference, or of the cached secret if it has not expired.
+func Resolve(ctx context.Context, reference string) (string, error) {
+	scheme, location, found := strings.Cut(reference, "://")
+	resolve, ok := resolvers[scheme]
+	if !found || !ok {
+		return "", fmt.Errorf("unsupported secret reference scheme %q", scheme)
+	}
+
+	cacheMu.Lock()
+	cached, ok := cache[reference]
+	cacheMu.Unlock()
+	if ok && (cached.expires.IsZero() || time.Now().Before(cached.expires)) {
+		return cached.value, nil
+	}
+
+	value, err := resolve(ctx, location)
+	if err == nil && value == "" {
+		err = ErrEmptySecret
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve secret %s: %w", reference, err)
+	}
+
+	// Secrets are cached for good when they are never refreshed.
+	var expires time.Time
+	if refresh := time.Duration(viper.GetInt(core.OptStr_SecretsRefreshSec)) * time.Second; refresh > 0 {
+		expires = time.Now().Add(refresh)
+	}
+	cacheMu.Lock()
+	cache[reference] = cachedSecret{value: value, expires: expires}
+	cacheMu.Unlock()
+	return value, nil
+}
+
+// ResolveOptions resolves the secret references of the configuration options.
+//
+// Options holding lists of secrets may mix references and plain values. An
+// option is only updated when all of its references are resolved.
+func ResolveOptions(ctx context.Context) error {
+	return ResolveSettings(ctx, viper.GetViper())
+}
+
+// ResolveSettings resolves the secret references of the options of the given settings, e.g. of a reloaded config file.
+func ResolveSettings(ctx context.Context, v *viper.Viper) error {
+	var errs []error
+	for _, option := range core.SecretOptions() {
+		reference := v.GetString(option)
+		if !hasReference(option, reference) {
+			continue
+		}
+
+		value, err := resolveOption(ctx, option, reference)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", option, err))
+			continue
+		}
+		core.SetSecret(option, reference, value)
+	}
+	return errors.Join(errs...)
+}
+
+// Resolve the value of an option, resolving each element of secret lists.
+func resolveOption(ctx context.Context, option string, reference string) (string, error) {
+	if !core.IsSecretListOption(option) {
+		return Resolve(ctx, reference)
+	}
+
+	values := strings.Split(reference, ",")
+	for i, value := range values {
+		value = strings.TrimSpace(value)
+		if !IsReference(value) {
+			continue
+		}
+		resolved, err := Resolve(ctx, value)
+		if err != nil {
+			return "", err
+		}
+		values[i] = resolved
+	}
+	return strings.Join(values, ","), nil
+}
+
+// Check if the value of an option holds a secret reference.
+func hasReference(option string, value string) bool {
+	if !core.IsSecretListOption(option) {
+		return IsReference(value)
+	}
+	for _, element := range strings.Split(value, ",") {
+		if IsReference(strings.TrimSpace(element)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if any configuration option holds a secret reference.
+func hasReferences() bool {
+	for _, option := range core.SecretOptions() {
+		if hasReference(option, viper.GetString(option)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Refresh the secret references of the configuration periodically.
+func refreshOptions(interval time.Duration) {
+	logger := core.GetLogger()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		// Activating resolves the references again, since the configuration
+		// snapshot holds the parsed verification key and secret lists.
+		if err := core.ActivateConfig(); err != nil {
+			if errors.Is(err, core.ErrUnresolvedSecret) {
+				metrics.Int("secrets_refresh_failures_total").Add(1)
+			}
+			logger.Errorw(
+				"rejected configuration with refreshed secrets, keeping the previous one",
+				"func", "secrets.refreshOptions",
+				"err", err.Error(),
+			)
+		}
+	}
+}
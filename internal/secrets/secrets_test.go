@@ -0,0 +1,231 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+func Test_IsReference_Values_Detected(t *testing.T) {
+	tests := map[string]bool{
+		"file:///run/secrets/redis":           true,
+		"aws-ssm:///jwtblock/redis":           true,
+		"aws-secretsmanager://jwtblock/redis": true,
+		"https://example.com":                 false,
+		"plain-password":                      false,
+		"":                                    false,
+	}
+	for value, expected := range tests {
+		if actual := IsReference(value); actual != expected {
+			t.Errorf("IsReference(%q): expected %t, got %t", value, expected, actual)
+		}
+	}
+}
+
+func Test_Resolve_File_TrimsNewline(t *testing.T) {
+	setupSecrets(t)
+	path := writeSecretFile(t, "redis-password", "s3cret\n")
+
+	value, err := Resolve(context.Background(), "file://"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "s3cret" {
+		t.Errorf("Expected s3cret, got %q", value)
+	}
+}
+
+func Test_Resolve_EmptyFile_Error(t *testing.T) {
+	setupSecrets(t)
+	path := writeSecretFile(t, "empty", "\n")
+
+	if _, err := Resolve(context.Background(), "file://"+path); !errors.Is(err, ErrEmptySecret) {
+		t.Errorf("Expected ErrEmptySecret, got %v", err)
+	}
+}
+
+func Test_Resolve_UnknownScheme_Error(t *testing.T) {
+	setupSecrets(t)
+
+	if _, err := Resolve(context.Background(), "vault://secret/redis"); err == nil {
+		t.Error("Expected an error for an unsupported scheme")
+	}
+}
+
+func Test_Resolve_CachedSecret_NotReread(t *testing.T) {
+	setupSecrets(t)
+	path := writeSecretFile(t, "redis-password", "first")
+	if _, err := Resolve(context.Background(), "file://"+path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("second"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	value, _ := Resolve(context.Background(), "file://"+path)
+	if value != "first" {
+		t.Errorf("Expected the cached value, got %q", value)
+	}
+}
+
+func Test_Resolve_SSMParameter_Success(t *testing.T) {
+	setupSecrets(t)
+	setupAWSStub(t, func(target string, body map[string]interface{}) (int, interface{}) {
+		if target != "AmazonSSM.GetParameter" || body["Name"] != "/jwtblock/redis" || body["WithDecryption"] != true {
+			return http.StatusBadRequest, map[string]string{"__type": "ValidationException"}
+		}
+		return http.StatusOK, map[string]interface{}{
+			"Parameter": map[string]string{"Name": "/jwtblock/redis", "Type": "SecureString", "Value": "ssm-secret"},
+		}
+	})
+
+	value, err := Resolve(context.Background(), "aws-ssm:///jwtblock/redis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "ssm-secret" {
+		t.Errorf("Expected ssm-secret, got %q", value)
+	}
+}
+
+func Test_Resolve_MissingSSMParameter_Error(t *testing.T) {
+	setupSecrets(t)
+	setupAWSStub(t, func(target string, body map[string]interface{}) (int, interface{}) {
+		return http.StatusBadRequest, map[string]string{"__type": "ParameterNotFound"}
+	})
+
+	if _, err := Resolve(context.Background(), "aws-ssm:///jwtblock/missing"); err == nil {
+		t.Error("Expected an error for a missing parameter")
+	}
+}
+
+func Test_Resolve_SecretsManagerSecret_Success(t *testing.T) {
+	setupSecrets(t)
+	setupAWSStub(t, func(target string, body map[string]interface{}) (int, interface{}) {
+		if target != "secretsmanager.GetSecretValue" || body["SecretId"] != "jwtblock/hmac" {
+			return http.StatusBadRequest, map[string]string{"__type": "ResourceNotFoundException"}
+		}
+		return http.StatusOK, map[string]string{"Name": "jwtblock/hmac", "SecretString": "sm-secret"}
+	})
+
+	value, err := Resolve(context.Background(), "aws-secretsmanager://jwtblock/hmac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "sm-secret" {
+		t.Errorf("Expected sm-secret, got %q", value)
+	}
+}
+
+func Test_Resolve_SecretsManagerJSONKey_Success(t *testing.T) {
+	setupSecrets(t)
+	setupAWSStub(t, func(target string, body map[string]interface{}) (int, interface{}) {
+		return http.StatusOK, map[string]string{"Name": "jwtblock/redis", "SecretString": `{"username":"jwtblock","password":"json-secret"}`}
+	})
+
+	value, err := Resolve(context.Background(), "aws-secretsmanager://jwtblock/redis#password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "json-secret" {
+		t.Errorf("Expected json-secret, got %q", value)
+	}
+
+	if _, err := Resolve(context.Background(), "aws-secretsmanager://jwtblock/redis#missing"); !errors.Is(err, ErrSecretKeyNotFound) {
+		t.Errorf("Expected ErrSecretKeyNotFound, got %v", err)
+	}
+}
+
+func Test_ResolveOptions_References_Resolved(t *testing.T) {
+	setupSecrets(t)
+	passwordPath := writeSecretFile(t, "redis-password", "s3cret")
+	hmacPath := writeSecretFile(t, "hmac-current", "current-key")
+	setOption(t, core.OptStr_RedisPassword, "file://"+passwordPath)
+	setOption(t, core.OptStr_HashHmacSecrets, "file://"+hmacPath+", old-key")
+	setOption(t, core.OptStr_RemoteApiKey, "plain-key")
+
+	if err := ResolveOptions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if actual := core.GetSecret(core.OptStr_RedisPassword); actual != "s3cret" {
+		t.Errorf("Expected the resolved password, got %q", actual)
+	}
+	if actual := core.GetSecret(core.OptStr_HashHmacSecrets); actual != "current-key, old-key" {
+		t.Errorf("Expected the resolved HMAC secrets, got %q", actual)
+	}
+	if actual := core.GetSecret(core.OptStr_RemoteApiKey); actual != "plain-key" {
+		t.Errorf("Expected the plain API key, got %q", actual)
+	}
+}
+
+func Test_ResolveOptions_FailedRefresh_KeepsPreviousValue(t *testing.T) {
+	setupSecrets(t)
+	passwordPath := writeSecretFile(t, "redis-password", "s3cret")
+	setOption(t, core.OptStr_RedisPassword, "file://"+passwordPath)
+	if err := ResolveOptions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Expire the cache, and remove the secret.
+	cache = map[string]cachedSecret{}
+	if err := os.Remove(passwordPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ResolveOptions(context.Background()); err == nil {
+		t.Error("Expected an error for the removed secret")
+	}
+	if actual := core.GetSecret(core.OptStr_RedisPassword); actual != "s3cret" {
+		t.Errorf("Expected the previous password, got %q", actual)
+	}
+}
+
+func setupSecrets(t *testing.T) {
+	core.InitConfigDefaults()
+	cache = map[string]cachedSecret{}
+	t.Cleanup(func() { cache = map[string]cachedSecret{} })
+}
+
+func setOption(t *testing.T, option string, value string) {
+	viper.Set(option, value)
+	t.Cleanup(func() { viper.Set(option, nil) })
+}
+
+func writeSecretFile(t *testing.T, name string, value string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(value), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Stub the AWS JSON APIs, answering each request with the status and body of the handler.
+func setupAWSStub(t *testing.T, handle func(target string, body map[string]interface{}) (int, interface{})) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		status, response := handle(r.Header.Get("X-Amz-Target"), body)
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	setOption(t, core.OptStr_SecretsAwsRegion, "us-east-1")
+	setOption(t, core.OptStr_SecretsAwsEndpoint, server.URL)
+}
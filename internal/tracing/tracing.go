@@ -0,0 +1,160 @@
+// Package tracing implements OpenTelemetry tracing for jwtblock.
+//
+// Tracing is a no-op unless "tracing.enabled" is set, in which case spans are
+// exported with OTLP over HTTP. The exporter also honors the standard
+// OTEL_EXPORTER_OTLP_* environment variables.
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+// Name of the instrumentation library, reported with every span.
+const instrumentationName = "github.com/divergentcodes/jwtblock"
+
+var once sync.Once
+var provider *sdktrace.TracerProvider
+var initErr error
+
+// Init configures the global tracer provider and W3C trace context propagation.
+//
+// Does nothing unless "tracing.enabled" is set. Safe to call more than once.
+func Init(ctx context.Context) error {
+	once.Do(func() {
+		if !viper.GetBool(core.OptStr_TracingEnabled) {
+			return
+		}
+		initErr = initProvider(ctx)
+	})
+	return initErr
+}
+
+func initProvider(ctx context.Context) error {
+	logger := core.GetLogger()
+
+	var options []otlptracehttp.Option
+	if endpoint := viper.GetString(core.OptStr_TracingOtlpEndpoint); endpoint != "" {
+		options = append(options, otlptracehttp.WithEndpoint(endpoint))
+	}
+	if viper.GetBool(core.OptStr_TracingOtlpInsecure) {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return err
+	}
+
+	serviceResource, err := resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(viper.GetString(core.OptStr_TracingServiceName)),
+			semconv.ServiceVersion(core.Version),
+		),
+	)
+	if err != nil {
+		return err
+	}
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(serviceResource),
+		sdktrace.WithSampler(sdktrace.ParentBased(
+			sdktrace.TraceIDRatioBased(viper.GetFloat64(core.OptStr_TracingSampleRatio)),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	logger.Debugw(
+		"Tracing enabled",
+		"func", "tracing.initProvider",
+		"service", viper.GetString(core.OptStr_TracingServiceName),
+	)
+	return nil
+}
+
+// Flush exports all finished spans, e.g. before a Lambda invocation returns.
+func Flush(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.ForceFlush(ctx)
+}
+
+// Shutdown flushes and stops the tracer provider.
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
+
+// Start creates a span and a context containing it.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// End ends a span, recording the error if there is one.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// ExtractHTTP returns a context with the remote trace context from HTTP headers.
+func ExtractHTTP(ctx context.Context, headers http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(headers))
+}
+
+// ExtractHeaderMap returns a context with the remote trace context from single-value headers, e.g. from API Gateway events.
+func ExtractHeaderMap(ctx context.Context, headers map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, headerMapCarrier(headers))
+}
+
+// A headerMapCarrier reads headers case-insensitively, since API Gateway may not lowercase them.
+type headerMapCarrier map[string]string
+
+func (c headerMapCarrier) Get(key string) string {
+	if value, ok := c[key]; ok {
+		return value
+	}
+	for name, value := range c {
+		if strings.EqualFold(name, key) {
+			return value
+		}
+	}
+	return ""
+}
+
+func (c headerMapCarrier) Set(key string, value string) {
+	c[key] = value
+}
+
+func (c headerMapCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
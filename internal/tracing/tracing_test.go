@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+const (
+	testTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testParentID    = "00f067aa0ba902b7"
+	testTraceparent = "00-" + testTraceID + "-" + testParentID + "-01"
+)
+
+func Test_ExtractHeaderMap_MixedCase_Parented(t *testing.T) {
+	exporter := setupTracing(t)
+
+	for _, name := range []string{"traceparent", "Traceparent", "TRACEPARENT"} {
+		ctx := ExtractHeaderMap(context.Background(), map[string]string{name: testTraceparent})
+		_, span := Start(ctx, "test")
+		span.End()
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans: spans=%d", len(spans))
+	}
+	for _, span := range spans {
+		if span.SpanContext.TraceID().String() != testTraceID || span.Parent.SpanID().String() != testParentID {
+			t.Errorf("Expected span to continue the remote trace: traceID=%s, parentID=%s", span.SpanContext.TraceID(), span.Parent.SpanID())
+		}
+	}
+}
+
+func Test_ExtractHTTP_NoTraceContext_NewTrace(t *testing.T) {
+	exporter := setupTracing(t)
+
+	ctx := ExtractHTTP(context.Background(), http.Header{})
+	_, span := Start(ctx, "test")
+	span.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Parent.IsValid() || !spans[0].SpanContext.IsValid() {
+		t.Errorf("Expected a new root span: spans=%+v", spans)
+	}
+}
+
+func Test_End_Error_Recorded(t *testing.T) {
+	exporter := setupTracing(t)
+
+	_, span := Start(context.Background(), "test")
+	End(span, errors.New("foobar"))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Status.Code != codes.Error || spans[0].Status.Description != "foobar" || len(spans[0].Events) != 1 {
+		t.Errorf("Expected the error to be recorded: spans=%+v", spans)
+	}
+}
+
+func Test_Init_Disabled_NoOp(t *testing.T) {
+	if err := Init(context.Background()); err != nil || provider != nil {
+		t.Errorf("Expected no tracer provider when tracing is disabled: err=%v", err)
+	}
+	if err := Flush(context.Background()); err != nil {
+		t.Errorf("Expected flush to do nothing: err=%s", err)
+	}
+}
+
+func Test_initProvider_Otlp_Success(t *testing.T) {
+	core.InitConfigDefaults()
+	viper.Set(core.OptStr_TracingOtlpEndpoint, "localhost:4318")
+	viper.Set(core.OptStr_TracingOtlpInsecure, true)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	if err := initProvider(context.Background()); err != nil || provider == nil {
+		t.Fatalf("Failed to create tracer provider: err=%v", err)
+	}
+	if err := Shutdown(context.Background()); err != nil {
+		t.Errorf("Failed to shut down tracer provider: err=%s", err)
+	}
+	provider = nil
+}
+
+// Export spans to memory, with W3C trace context propagation.
+func setupTracing(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+	return exporter
+}
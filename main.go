@@ -15,7 +15,11 @@ func init() {
 
 	logger.Debug("Initialized default configuration")
 
-	logger.Debug(viper.AllSettings())
+	logger.Debugw(
+		"Configuration settings",
+		"func", "main.init",
+		"settings", core.RedactSettings(viper.AllSettings()),
+	)
 }
 
 func runCli() {
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+)
+
+// Benchmarks of the per-request overhead of the middleware, with an in-memory Redis.
+//
+// Run with: go test ./middleware -bench . -run '^$'
+
+func BenchmarkMiddleware_Check(b *testing.B) {
+	redisClient := setupMockRedis(b)
+	tokenString := generateTokenStringHS256(60)
+	m := newMiddleware(b, WithRedisClient(redisClient))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := m.Check(context.Background(), tokenString); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+type contextKey int
+
+const (
+	tokenContextKey contextKey = iota
+	checkResultContextKey
+)
+
+// Add the verified token and check result to a context.
+func newContext(ctx context.Context, token jwt.Token, result CheckResult) context.Context {
+	ctx = context.WithValue(ctx, checkResultContextKey, result)
+	if token != nil {
+		ctx = context.WithValue(ctx, tokenContextKey, token)
+	}
+	return ctx
+}
+
+// TokenFromContext returns the verified token of an allowed request.
+//
+// With WithoutVerification, the token is only parsed and validated.
+func TokenFromContext(ctx context.Context) (jwt.Token, bool) {
+	token, ok := ctx.Value(tokenContextKey).(jwt.Token)
+	return token, ok
+}
+
+// ClaimsFromContext returns the claims of the verified token of an allowed request.
+func ClaimsFromContext(ctx context.Context) (map[string]interface{}, bool) {
+	token, ok := TokenFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	claims, err := token.AsMap(ctx)
+	if err != nil {
+		return nil, false
+	}
+	return claims, true
+}
+
+// CheckResultFromContext returns the blocklist check result of an allowed request.
+func CheckResultFromContext(ctx context.Context) (CheckResult, bool) {
+	result, ok := ctx.Value(checkResultContextKey).(CheckResult)
+	return result, ok
+}
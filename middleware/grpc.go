@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// A MetadataTokenSource reads a token from incoming gRPC metadata.
+//
+// Returns an empty string when the metadata has no token in the source.
+type MetadataTokenSource func(md metadata.MD) string
+
+// A GRPCDenyFunc returns the error status for a denied gRPC call.
+//
+// The error wraps ErrTokenMissing, ErrTokenInvalid, ErrTokenBlocked or ErrStoreUnavailable.
+type GRPCDenyFunc func(ctx context.Context, err error) error
+
+// FromMetadata reads a bearer token from the named metadata key.
+func FromMetadata(key string) MetadataTokenSource {
+	return func(md metadata.MD) string {
+		values := md.Get(key)
+		if len(values) == 0 {
+			return ""
+		}
+		return parseBearerToken(values[len(values)-1])
+	}
+}
+
+// FromMetadataRaw reads a token without a bearer prefix from the named metadata key.
+func FromMetadataRaw(key string) MetadataTokenSource {
+	return func(md metadata.MD) string {
+		values := md.Get(key)
+		if len(values) == 0 {
+			return ""
+		}
+		return values[len(values)-1]
+	}
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor that only allows unary calls with an allowed token.
+//
+// The verified token is available to the handler through the context accessors.
+func (m *Middleware) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := m.checkIncomingContext(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor that only allows streams with an allowed token.
+//
+// The verified token is available to the handler through the context accessors of the stream context.
+func (m *Middleware) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := m.checkIncomingContext(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// Check the token in the incoming metadata, and return a context with the verified token.
+//
+// The full method name is the route that chooses the store failure policy.
+func (m *Middleware) checkIncomingContext(ctx context.Context, fullMethod string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var tokenString string
+	for _, source := range m.grpcSources {
+		if tokenString = source(md); tokenString != "" {
+			break
+		}
+	}
+	if tokenString == "" {
+		return ctx, m.grpcDenyStatus(ctx, ErrTokenMissing)
+	}
+
+	token, result, err := m.check(ctx, tokenString, fullMethod)
+	if err != nil {
+		return ctx, m.grpcDenyStatus(ctx, err)
+	}
+	return newContext(ctx, token, result), nil
+}
+
+// DefaultGRPCDenyFunc returns an Unavailable status when the blocklist store is unavailable, and Unauthenticated otherwise.
+func DefaultGRPCDenyFunc(ctx context.Context, err error) error {
+	if errors.Is(err, ErrStoreUnavailable) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Unauthenticated, err.Error())
+}
+
+// A serverStream overrides the context of a gRPC server stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
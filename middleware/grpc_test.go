@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/divergentcodes/jwtblock/internal/blocklist"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+)
+
+func Test_UnaryServerInterceptor_AllowedToken_Success(t *testing.T) {
+	redisClient := setupMockRedis(t)
+	tokenString := generateTokenStringHS256(60)
+	interceptor := newMiddleware(t, WithRedisClient(redisClient)).UnaryServerInterceptor()
+
+	md := metadata.Pairs("authorization", fmt.Sprintf("Bearer %s", tokenString))
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	response, err := interceptor(ctx, "request", &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		if _, ok := TokenFromContext(ctx); !ok {
+			t.Errorf("Expected token in the call context")
+		}
+		return "response", nil
+	})
+
+	if err != nil || response != "response" {
+		t.Errorf("Expected call to be allowed: response=%v, err=%v", response, err)
+	}
+}
+
+func Test_UnaryServerInterceptor_BlockedToken_Error(t *testing.T) {
+	redisClient := setupMockRedis(t)
+	tokenString := generateTokenStringHS256(60)
+	_, err := blocklist.BlockBySha256WithTTL(redisClient, crypto.Sha256FromString(tokenString), 60)
+	if err != nil {
+		t.Fatalf("Failed to block token: err=%s", err)
+	}
+	interceptor := newMiddleware(t, WithRedisClient(redisClient)).UnaryServerInterceptor()
+
+	md := metadata.Pairs("authorization", fmt.Sprintf("Bearer %s", tokenString))
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	_, err = interceptor(ctx, "request", &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		t.Errorf("Expected call to be denied")
+		return nil, nil
+	})
+
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Unexpected status code: actual=%s, expected=%s", status.Code(err), codes.Unauthenticated)
+	}
+}
+
+func Test_StreamServerInterceptor_MissingToken_Error(t *testing.T) {
+	redisClient := setupMockRedis(t)
+	interceptor := newMiddleware(t, WithRedisClient(redisClient)).StreamServerInterceptor()
+
+	stream := &serverStream{ctx: context.Background()}
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{}, func(srv interface{}, ss grpc.ServerStream) error {
+		t.Errorf("Expected stream to be denied")
+		return nil
+	})
+
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Unexpected status code: actual=%s, expected=%s", status.Code(err), codes.Unauthenticated)
+	}
+}
+
+func Test_StreamServerInterceptor_AllowedToken_Success(t *testing.T) {
+	redisClient := setupMockRedis(t)
+	tokenString := generateTokenStringHS256(60)
+	interceptor := newMiddleware(t,
+		WithRedisClient(redisClient),
+		WithMetadataTokenSources(FromMetadataRaw("x-token")),
+	).StreamServerInterceptor()
+
+	md := metadata.Pairs("x-token", tokenString)
+	stream := &serverStream{ctx: metadata.NewIncomingContext(context.Background(), md)}
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{}, func(srv interface{}, ss grpc.ServerStream) error {
+		if _, ok := CheckResultFromContext(ss.Context()); !ok {
+			t.Errorf("Expected check result in the stream context")
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Errorf("Expected stream to be allowed: err=%v", err)
+	}
+}
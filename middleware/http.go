@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+// A TokenSource reads a token from an HTTP request.
+//
+// Returns an empty string when the request has no token in the source.
+type TokenSource func(r *http.Request) string
+
+// A DenyHandler writes the response for a denied HTTP request.
+//
+// The error wraps ErrTokenMissing, ErrTokenInvalid, ErrTokenBlocked or ErrStoreUnavailable.
+type DenyHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+// FromAuthorizationHeader reads a bearer token from the Authorization header.
+func FromAuthorizationHeader() TokenSource {
+	return func(r *http.Request) string {
+		return parseBearerToken(r.Header.Get("Authorization"))
+	}
+}
+
+// FromHeader reads a token from the named header.
+func FromHeader(name string) TokenSource {
+	return func(r *http.Request) string {
+		return strings.TrimSpace(r.Header.Get(name))
+	}
+}
+
+// FromCookie reads a token from the named cookie.
+func FromCookie(name string) TokenSource {
+	return func(r *http.Request) string {
+		cookie, err := r.Cookie(name)
+		if err != nil {
+			return ""
+		}
+		return cookie.Value
+	}
+}
+
+// FromQuery reads a token from the named URL query parameter.
+func FromQuery(name string) TokenSource {
+	return func(r *http.Request) string {
+		return r.URL.Query().Get(name)
+	}
+}
+
+// Handler decorates an HTTP handler, so it only receives requests with an allowed token.
+//
+// The verified token and check result are available to the handler through
+// TokenFromContext, ClaimsFromContext and CheckResultFromContext.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger := core.GetLogger()
+
+		tokenString := m.tokenFromRequest(r)
+		if tokenString == "" {
+			m.denyHandler(w, r, ErrTokenMissing)
+			return
+		}
+
+		token, result, err := m.check(r.Context(), tokenString, r.URL.Path)
+		if err != nil {
+			logger.Debugw(
+				"request denied",
+				"func", "middleware.Handler",
+				"path", r.URL.Path,
+				"err", err.Error(),
+			)
+			m.denyHandler(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(newContext(r.Context(), token, result)))
+	})
+}
+
+// Get the token from the first token source that has one.
+func (m *Middleware) tokenFromRequest(r *http.Request) string {
+	for _, source := range m.tokenSources {
+		if tokenString := source(r); tokenString != "" {
+			return tokenString
+		}
+	}
+	return ""
+}
+
+// DefaultDenyHandler writes a JSON error response.
+//
+// The status is 503 when the blocklist store is unavailable, and 401 otherwise.
+func DefaultDenyHandler(w http.ResponseWriter, r *http.Request, err error) {
+	httpStatus := http.StatusUnauthorized
+	if errors.Is(err, ErrStoreUnavailable) {
+		httpStatus = http.StatusServiceUnavailable
+	}
+
+	data := struct {
+		Message string `json:"message"`
+		IsError bool   `json:"error"`
+	}{
+		Message: err.Error(),
+		IsError: true,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// Extract the token from a bearer authorization value.
+func parseBearerToken(value string) string {
+	substrings := strings.Split(strings.TrimSpace(value), " ")
+	if len(substrings) != 2 || !strings.EqualFold(substrings[0], "bearer") {
+		return ""
+	}
+	return substrings[1]
+}
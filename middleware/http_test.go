@@ -0,0 +1,274 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/blocklist"
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+)
+
+func Test_Handler_AllowedToken_Success(t *testing.T) {
+	redisClient := setupMockRedis(t)
+	tokenString := generateTokenStringHS256(60)
+
+	var issuer interface{}
+	handler := newMiddleware(t, WithRedisClient(redisClient)).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFromContext(r.Context())
+		if !ok {
+			t.Errorf("Expected claims in the request context")
+		}
+		issuer = claims["iss"]
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, request)
+
+	if w.Result().StatusCode != http.StatusNoContent || issuer != "some-issuer" {
+		t.Errorf("Expected request to be allowed: status=%d, iss=%v", w.Result().StatusCode, issuer)
+	}
+}
+
+func Test_Handler_BlockedToken_Error(t *testing.T) {
+	redisClient := setupMockRedis(t)
+	tokenString := generateTokenStringHS256(60)
+	_, err := blocklist.BlockBySha256WithTTL(redisClient, crypto.Sha256FromString(tokenString), 60)
+	if err != nil {
+		t.Fatalf("Failed to block token: err=%s", err)
+	}
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	status, message := serveDenied(t, newMiddleware(t, WithRedisClient(redisClient)), request)
+
+	if status != http.StatusUnauthorized || message != ErrTokenBlocked.Error() {
+		t.Errorf("Expected ErrTokenBlocked: status=%d, message='%s'", status, message)
+	}
+}
+
+func Test_Handler_MissingToken_Error(t *testing.T) {
+	redisClient := setupMockRedis(t)
+
+	request := httptest.NewRequest("GET", "/", nil)
+	status, message := serveDenied(t, newMiddleware(t, WithRedisClient(redisClient)), request)
+
+	if status != http.StatusUnauthorized || message != ErrTokenMissing.Error() {
+		t.Errorf("Expected ErrTokenMissing: status=%d, message='%s'", status, message)
+	}
+}
+
+func Test_Handler_InvalidToken_Error(t *testing.T) {
+	redisClient := setupMockRedis(t)
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Add("Authorization", "Bearer foo")
+	var denyErr error
+	m := newMiddleware(t,
+		WithRedisClient(redisClient),
+		WithDenyHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+			denyErr = err
+			DefaultDenyHandler(w, r, err)
+		}),
+	)
+	status, _ := serveDenied(t, m, request)
+
+	if status != http.StatusUnauthorized || !errors.Is(denyErr, ErrTokenInvalid) {
+		t.Errorf("Expected ErrTokenInvalid: status=%d, err=%v", status, denyErr)
+	}
+}
+
+func Test_Handler_StoreUnavailable_Error(t *testing.T) {
+	redisClient := setupMockRedis(t)
+	redisClient.Close()
+	tokenString := generateTokenStringHS256(60)
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	status, _ := serveDenied(t, newMiddleware(t, WithRedisClient(redisClient)), request)
+
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("Unexpected status code: actual=%d, expected=%d", status, http.StatusServiceUnavailable)
+	}
+}
+
+func Test_Handler_TokenSources_Success(t *testing.T) {
+	redisClient := setupMockRedis(t)
+	tokenString := generateTokenStringHS256(60)
+	m := newMiddleware(t,
+		WithRedisClient(redisClient),
+		WithTokenSources(FromHeader("X-Token"), FromCookie("session"), FromQuery("token")),
+	)
+
+	requests := map[string]*http.Request{
+		"header": httptest.NewRequest("GET", "/", nil),
+		"cookie": httptest.NewRequest("GET", "/", nil),
+		"query":  httptest.NewRequest("GET", "/?token="+tokenString, nil),
+	}
+	requests["header"].Header.Add("X-Token", tokenString)
+	requests["cookie"].AddCookie(&http.Cookie{Name: "session", Value: tokenString})
+
+	for source, request := range requests {
+		handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, request)
+		if w.Result().StatusCode != http.StatusNoContent {
+			t.Errorf("Expected request to be allowed: source=%s, status=%d", source, w.Result().StatusCode)
+		}
+	}
+}
+
+func Test_New_VerifyDisabled_Error(t *testing.T) {
+	redisClient := setupMockRedis(t)
+
+	if _, err := New(WithRedisClient(redisClient)); !errors.Is(err, ErrVerifyDisabled) {
+		t.Errorf("Expected ErrVerifyDisabled, got %v", err)
+	}
+}
+
+func Test_New_NoRedisClient_Error(t *testing.T) {
+	if _, err := New(WithHmacVerifyKey(testVerifyKey)); !errors.Is(err, ErrNoRedisClient) {
+		t.Errorf("Expected ErrNoRedisClient, got %v", err)
+	}
+}
+
+func Test_New_InvalidSetting_Error(t *testing.T) {
+	redisClient := setupMockRedis(t)
+
+	_, err := New(WithRedisClient(redisClient), WithHmacVerifyKey("not a JWK"))
+	if !errors.Is(err, core.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func Test_New_GlobalSettings_Ignored(t *testing.T) {
+	redisClient := setupMockRedis(t)
+	viper.Set(core.OptStr_JwtBlockClaims, "sub")
+	defer viper.Set(core.OptStr_JwtBlockClaims, nil)
+
+	newMiddleware(t, WithRedisClient(redisClient), WithHmacVerifyKey(testVerifyKey), WithHashSecrets("current", "old"))
+	config := core.GetConfig()
+	if len(config.JWT.BlockClaims) != 0 || len(config.Hash.HmacSecrets) != 2 {
+		t.Errorf("Expected only the middleware settings: claims=%v, secrets=%d", config.JWT.BlockClaims, len(config.Hash.HmacSecrets))
+	}
+}
+
+func Test_Import_GlobalSettings_Untouched(t *testing.T) {
+	if viper.IsSet(core.OptStr_RedisHost) || viper.ConfigFileUsed() != "" {
+		t.Errorf("Expected no jwtblock settings in the global settings")
+	}
+}
+
+func Test_New_WithoutVerification_Allowed(t *testing.T) {
+	redisClient := setupMockRedis(t)
+	tokenString := generateTokenStringHS256(60)
+
+	m, err := New(WithRedisClient(redisClient), WithoutVerification())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.Check(context.Background(), tokenString); err != nil {
+		t.Errorf("Expected the unverified token to be allowed, got %v", err)
+	}
+}
+
+func Test_Handler_VerifyDisabledAfterNew_Error(t *testing.T) {
+	redisClient := setupMockRedis(t)
+	tokenString := generateTokenStringHS256(60)
+	m := newMiddleware(t, WithRedisClient(redisClient))
+	if _, err := New(WithRedisClient(redisClient), WithoutVerification()); err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	if status, _ := serveDenied(t, m, request); status != http.StatusUnauthorized {
+		t.Errorf("Unexpected status code: actual=%d, expected=%d", status, http.StatusUnauthorized)
+	}
+}
+
+// Serve a request that must not reach the wrapped handler, and return the status and message.
+func serveDenied(t *testing.T, m *Middleware, request *http.Request) (int, string) {
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected request to be denied")
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, request)
+
+	response := w.Result()
+	body, _ := io.ReadAll(response.Body)
+	var result struct {
+		Message string `json:"message"`
+		IsError bool   `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil || !result.IsError {
+		t.Errorf("Expected JSON error response: body='%s'", body)
+	}
+	return response.StatusCode, result.Message
+}
+
+// Key of the test tokens, "foobar" as a JWK.
+const testVerifyKey = `{"kty":"oct","k":"Zm9vYmFy"}`
+
+func setupMockRedis(t testing.TB) *redis.Client {
+	redisServer := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: redisServer.Addr(),
+	})
+	t.Cleanup(func() {
+		redisClient.Close()
+	})
+	return redisClient
+}
+
+// Create a middleware that verifies the test tokens, failing the test on error.
+func newMiddleware(t testing.TB, options ...Option) *Middleware {
+	m, err := New(append([]Option{WithHmacVerifyKey(testVerifyKey)}, options...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func generateTokenStringHS256(ttlSeconds int) string {
+	// Generate the token headers.
+	tokenHeaders := jws.NewHeaders()
+	tokenHeaders.Set("typ", "JWT")
+
+	// Generate the token body, with the given EXP claim.
+	ttl := time.Duration(ttlSeconds) * time.Second
+	tokenBody, _ := jwt.NewBuilder().
+		Issuer(`some-issuer`).
+		Expiration(time.Now().Add(ttl)).
+		JwtID(time.Now().String()).
+		Build()
+	tokenBodyBytes, _ := jwt.NewSerializer().Serialize(tokenBody)
+
+	// Generate the signed, finished HS256 token.
+	key, _ := jwk.FromRaw([]byte(`foobar`))
+	tokenBytes, _ := jws.Sign(
+		tokenBodyBytes,
+		jws.WithKey(jwa.HS256, key, jws.WithProtectedHeaders(tokenHeaders)),
+	)
+	return string(tokenBytes)
+}
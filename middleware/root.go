@@ -0,0 +1,238 @@
+// Package middleware enforces the JWT blocklist in-process, for Go services
+// that check tokens without a network hop to the jwtblock service.
+//
+// Tokens are parsed, validated and verified with the settings given as
+// options, and looked up directly in the blocklist cache. It provides a
+// net/http handler decorator and gRPC server interceptors.
+//
+// The jwtblock config file and environment variables are never read, so
+// importing the package has no side effects on the host program.
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/divergentcodes/jwtblock/internal/blocklist"
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+)
+
+// General error messages from the middleware.
+var (
+	ErrTokenMissing     = errors.New("missing token in request")
+	ErrTokenInvalid     = errors.New("invalid token")
+	ErrTokenBlocked     = errors.New("token is blocked")
+	ErrStoreUnavailable = errors.New("blocklist store unavailable")
+	ErrVerifyDisabled   = errors.New("JWT verification is disabled, set a verification key or use WithoutVerification")
+	ErrNoRedisClient    = errors.New("a Redis client is required, set it with WithRedisClient")
+)
+
+// CheckResult is the result of looking up a token in the blocklist.
+type CheckResult = blocklist.CheckResult
+
+// A Middleware checks tokens against the blocklist.
+//
+// A Middleware is safe for concurrent use.
+type Middleware struct {
+	redisDB        redis.UniversalClient
+	tokenSources   []TokenSource
+	grpcSources    []MetadataTokenSource
+	denyHandler    DenyHandler
+	grpcDenyStatus GRPCDenyFunc
+	unverified     bool
+	settings       map[string]interface{}
+}
+
+// An Option configures a Middleware.
+type Option func(*Middleware)
+
+// New creates a middleware with the given options.
+//
+// By default, tokens are read as bearer tokens from the Authorization header
+// (or the "authorization" gRPC metadata). The settings are the jwtblock
+// defaults, overridden by the options, and are validated and activated once,
+// so they are not loaded again for every token. The settings are shared by
+// the process, so the last middleware created wins.
+//
+// Returns ErrNoRedisClient without WithRedisClient, the validation errors of
+// invalid settings, or ErrVerifyDisabled without a verification key, since
+// the token in the context would not be verified, unless WithoutVerification
+// is given.
+func New(options ...Option) (*Middleware, error) {
+	m := &Middleware{
+		tokenSources:   []TokenSource{FromAuthorizationHeader()},
+		grpcSources:    []MetadataTokenSource{FromMetadata("authorization")},
+		denyHandler:    DefaultDenyHandler,
+		grpcDenyStatus: DefaultGRPCDenyFunc,
+		settings:       map[string]interface{}{},
+	}
+	for _, option := range options {
+		option(m)
+	}
+	if m.redisDB == nil {
+		return nil, ErrNoRedisClient
+	}
+
+	config, err := core.NewConfig(m.settings)
+	if err != nil {
+		return nil, err
+	}
+	if !m.unverified && !config.JWT.VerifyEnabled {
+		return nil, ErrVerifyDisabled
+	}
+	if err := core.SetConfig(config); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// WithRedisClient sets the Redis client of the blocklist store.
+func WithRedisClient(redisDB redis.UniversalClient) Option {
+	return func(m *Middleware) {
+		m.redisDB = redisDB
+	}
+}
+
+// WithTokenSources sets where tokens are read from in HTTP requests, tried in order.
+func WithTokenSources(sources ...TokenSource) Option {
+	return func(m *Middleware) {
+		m.tokenSources = sources
+	}
+}
+
+// WithMetadataTokenSources sets where tokens are read from in gRPC metadata, tried in order.
+func WithMetadataTokenSources(sources ...MetadataTokenSource) Option {
+	return func(m *Middleware) {
+		m.grpcSources = sources
+	}
+}
+
+// WithDenyHandler sets how HTTP requests are denied.
+func WithDenyHandler(denyHandler DenyHandler) Option {
+	return func(m *Middleware) {
+		m.denyHandler = denyHandler
+	}
+}
+
+// WithGRPCDenyFunc sets the gRPC status returned when calls are denied.
+func WithGRPCDenyFunc(denyFunc GRPCDenyFunc) Option {
+	return func(m *Middleware) {
+		m.grpcDenyStatus = denyFunc
+	}
+}
+
+// WithHmacVerifyKey verifies HS256 tokens with a symmetric key, as a JWK, e.g. {"kty":"oct","k":"..."}.
+func WithHmacVerifyKey(key string) Option {
+	return func(m *Middleware) {
+		m.settings[core.OptStr_JwtVerifyEnabled] = true
+		m.settings[core.OptStr_JwtVerifyHmacSecret] = key
+	}
+}
+
+// WithRsaVerifyKey verifies RS256 tokens with a public key, as PEM.
+func WithRsaVerifyKey(key string) Option {
+	return func(m *Middleware) {
+		m.settings[core.OptStr_JwtVerifyEnabled] = true
+		m.settings[core.OptStr_JwtVerifyRsaKey] = key
+	}
+}
+
+// WithHashSecrets sets the HMAC secrets of the blocklist keys, current secret first.
+//
+// They must match the "hash.hmac_secrets" of the jwtblock service.
+func WithHashSecrets(secrets ...string) Option {
+	return func(m *Middleware) {
+		m.settings[core.OptStr_HashHmacSecrets] = strings.Join(secrets, ",")
+	}
+}
+
+// WithBlockClaims sets the claims whose values can be blocked, e.g. "sub" or "sid".
+//
+// They must match the "jwt.block_claims" of the jwtblock service.
+func WithBlockClaims(claims ...string) Option {
+	return func(m *Middleware) {
+		m.settings[core.OptStr_JwtBlockClaims] = strings.Join(claims, ",")
+	}
+}
+
+// WithSetting overrides any other jwtblock setting, by its config file option name, e.g. "store_failure.policy".
+func WithSetting(option string, value interface{}) Option {
+	return func(m *Middleware) {
+		m.settings[option] = value
+	}
+}
+
+// WithoutVerification allows the middleware without JWT verification.
+//
+// Tokens are still parsed and validated, but their signature is not checked,
+// so their claims must not be trusted. Only use it when a trusted proxy in
+// front of the service verifies the tokens.
+func WithoutVerification() Option {
+	return func(m *Middleware) {
+		m.unverified = true
+	}
+}
+
+// Check parses, validates and verifies a token, and looks it up in the blocklist.
+//
+// Returns the parsed token, which is nil when JWT parsing is disabled. The
+// error wraps ErrTokenInvalid, ErrTokenBlocked or ErrStoreUnavailable. When
+// the blocklist store is unavailable, the configured store failure policy
+// decides whether the token is allowed.
+func (m *Middleware) Check(ctx context.Context, tokenString string) (jwt.Token, CheckResult, error) {
+	return m.check(ctx, tokenString, "")
+}
+
+// Check a token for a protected route, which chooses the store failure policy.
+func (m *Middleware) check(ctx context.Context, tokenString string, route string) (jwt.Token, CheckResult, error) {
+	logger := core.GetLogger()
+	var result CheckResult
+
+	// Verification may be disabled by the settings of a later middleware.
+	if !m.unverified && !core.GetConfig().JWT.VerifyEnabled {
+		result.IsError = true
+		result.Message = ErrVerifyDisabled.Error()
+		return nil, result, fmt.Errorf("%w: %s", ErrTokenInvalid, ErrVerifyDisabled.Error())
+	}
+
+	token, err := crypto.RunJwtChecksContext(ctx, tokenString)
+	if err != nil {
+		result.IsError = true
+		result.Message = err.Error()
+		return nil, result, fmt.Errorf("%w: %s", ErrTokenInvalid, err.Error())
+	}
+
+	redisDB := m.redisDB
+	issuer := ""
+	if token != nil {
+		issuer = token.Issuer()
+	}
+	result, err = blocklist.CheckToken(ctx, redisDB, tokenString, token)
+	result, err = blocklist.ApplyFailPolicy(result, err, route, issuer)
+	if err != nil {
+		logger.Errorw(
+			"blocklist lookup failed",
+			"func", "middleware.Check",
+			"err", err.Error(),
+		)
+		return token, result, fmt.Errorf("%w: %s", ErrStoreUnavailable, err.Error())
+	}
+	if result.IsBlocked {
+		return token, result, ErrTokenBlocked
+	}
+	return token, result, nil
+}
+
+// SubscribeChanges keeps the local cache and blocked filter in sync with blocklist changes from other processes.
+//
+// Does nothing unless "cache.local.enabled" or "cache.filter.enabled" are set
+// with WithSetting. The subscription runs until the context is done.
+func (m *Middleware) SubscribeChanges(ctx context.Context) error {
+	return blocklist.SubscribeChanges(ctx, m.redisDB)
+}
@@ -2,47 +2,189 @@ package awslambda
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/lestrrat-go/jwx/v2/jwt"
 
 	"github.com/divergentcodes/jwtblock/internal/blocklist"
 	"github.com/divergentcodes/jwtblock/internal/cache"
 	"github.com/divergentcodes/jwtblock/internal/core"
 )
 
-// Generate an IAM policy to return in the response.
-func generatePolicyResponse(principalId, effect, resourceArn string, checkResult blocklist.CheckResult) events.APIGatewayCustomAuthorizerResponse {
-	logger := core.GetLogger()
+// Response modes of the authorizer.
+const (
+	authorizerResponsePolicy = "policy" // IAM policy document, for REST and HTTP APIs.
+	authorizerResponseSimple = "simple" // {isAuthorized, context}, for HTTP API request authorizers.
+)
 
-	authResponse := events.APIGatewayCustomAuthorizerResponse{PrincipalID: principalId}
+// Principal of the IAM policy when the caller has no verified subject.
+const defaultPrincipalID = "user"
 
-	if effect != "" && resourceArn != "" {
-		authResponse.PolicyDocument = events.APIGatewayCustomAuthorizerPolicy{
-			Version: "2012-10-17",
-			Statement: []events.IAMPolicyStatement{
-				{
-					Action:   []string{"execute-api:Invoke"},
-					Effect:   effect,
-					Resource: []string{resourceArn},
-				},
-			},
+// An authorizerIdentity is the identity of the caller, from the claims of a verified token.
+type authorizerIdentity struct {
+	PrincipalID string
+	Subject     string
+	Scopes      string // space-separated.
+	ClientID    string
+}
+
+// Get the identity of the caller from the token claims.
+//
+// Claims are only used when tokens are verified, since the backend trusts the
+// authorizer context. Scopes are read from "scope" or "scp", and the client
+// ID from "client_id" or "azp".
+func identityFromToken(token jwt.Token) authorizerIdentity {
+	identity := authorizerIdentity{PrincipalID: defaultPrincipalID}
+	if token == nil || !core.GetConfig().JWT.VerifyEnabled {
+		return identity
+	}
+
+	if subject := token.Subject(); subject != "" {
+		identity.Subject = subject
+		identity.PrincipalID = subject
+	}
+	for _, name := range []string{"scope", "scp"} {
+		if value, ok := token.Get(name); ok {
+			identity.Scopes = claimString(value, " ")
+			break
+		}
+	}
+	for _, name := range []string{"client_id", "azp"} {
+		if value, ok := token.Get(name); ok {
+			identity.ClientID = claimString(value, " ")
+			break
+		}
+	}
+	return identity
+}
+
+// Format a string or list claim as a string, since the authorizer context only holds primitive values.
+func claimString(value interface{}, separator string) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, separator)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
 		}
+		return strings.Join(values, separator)
 	}
+	return fmt.Sprint(value)
+}
+
+// An authorizerDecision is the outcome of authorizing a request.
+type authorizerDecision struct {
+	Effect        string // Allow or Deny.
+	CheckResult   blocklist.CheckResult
+	Identity      authorizerIdentity
+	IsRouteDenied bool // whether or not the token was denied by authorizer rules.
+	CacheTTL      int  // TTL hint of the response, in seconds.
+
+	token jwt.Token
+	rules []authorizerRule
+}
+
+// Authorize a token for a method and path.
+//
+// Blocked and invalid tokens are denied with ErrLambdaAuth401Unauthorized.
+// Tokens denied by authorizer rules are denied without an error, so API
+// Gateway responds 403.
+func authorize(ctx context.Context, tokenString string, method string, path string) (authorizerDecision, error) {
+	logger := core.GetLogger()
+
+	rules, err := authorizerRules()
+	if err != nil {
+		logger.Errorw(
+			"invalid authorizer rules",
+			"func", "awslambda.authorize",
+			"err", err.Error(),
+		)
+		return authorizerDecision{}, ErrLambdaAuth500AuthConfig
+	}
+
+	// Token validation and blocklist lookup.
+	redisClient := cache.GetRedisClient()
+	checkResult, token, err := blocklist.CheckByJwtForRouteWithToken(ctx, redisClient, tokenString, path)
+	if err != nil {
+		logger.Errorw(
+			"token check failed",
+			"func", "awslambda.authorize",
+			"err", err.Error(),
+		)
+	}
+
+	decision := authorizerDecision{
+		Effect:      "Allow",
+		CheckResult: checkResult,
+		Identity:    identityFromToken(token),
+		CacheTTL:    cacheTTLHint(token),
+		token:       token,
+		rules:       rules,
+	}
+	if checkResult.IsBlocked || err != nil {
+		decision.Effect = "Deny"
+		// This is how to define status codes returned by AWS Lambda Authorizers.
+		return decision, ErrLambdaAuth401Unauthorized
+	}
+	if deniedByRules(rules, token, method, path) {
+		decision.Effect = "Deny"
+		decision.IsRouteDenied = true
+	}
+	return decision, nil
+}
+
+// Generate the authorizer context, available to the backend in requestContext.authorizer.
+func generateAuthorizerContext(decision authorizerDecision) map[string]interface{} {
+	checkResult, identity := decision.CheckResult, decision.Identity
 
 	// Add check result structure to response context.
-	authResponse.Context = map[string]interface{}{
+	authContext := map[string]interface{}{
 		"message":       checkResult.Message,
 		"blocked":       checkResult.IsBlocked,
 		"block_ttl_sec": checkResult.TTL,
 		"block_ttl_str": checkResult.TTLString,
 		"error":         checkResult.IsError,
+		"fail_open":     checkResult.IsFailOpen,
+		"route_denied":  decision.IsRouteDenied,
+		"cache_ttl_sec": decision.CacheTTL,
+	}
+
+	// Add the identity from the verified claims.
+	if identity.Subject != "" {
+		authContext["sub"] = identity.Subject
+	}
+	if identity.Scopes != "" {
+		authContext["scopes"] = identity.Scopes
 	}
+	if identity.ClientID != "" {
+		authContext["client_id"] = identity.ClientID
+	}
+	return authContext
+}
+
+// Generate an IAM policy to return in the response.
+func generatePolicyResponse(decision authorizerDecision, resourceArn string) events.APIGatewayCustomAuthorizerResponse {
+	logger := core.GetLogger()
+
+	authResponse := events.APIGatewayCustomAuthorizerResponse{PrincipalID: decision.Identity.PrincipalID}
+
+	if decision.Effect != "" && resourceArn != "" {
+		authResponse.PolicyDocument = events.APIGatewayCustomAuthorizerPolicy{
+			Version:   "2012-10-17",
+			Statement: generatePolicyStatements(decision, resourceArn),
+		}
+	}
+	authResponse.Context = generateAuthorizerContext(decision)
 
 	logger.Debugw(
 		"generated authorizer response",
 		"func", "generatedPolicyResponse",
-		"effect", effect,
+		"effect", decision.Effect,
 		"resourceArn", resourceArn,
 		"response", authResponse,
 	)
@@ -50,7 +192,30 @@ func generatePolicyResponse(principalId, effect, resourceArn string, checkResult
 	return authResponse
 }
 
-func handleAuthorizerTypeRequestEvent(ctx context.Context, event events.APIGatewayV2CustomAuthorizerV2Request) (events.APIGatewayCustomAuthorizerResponse, error) {
+// Generate a simple response, for HTTP API request authorizers with the simple response format enabled.
+func generateSimpleResponse(decision authorizerDecision) events.APIGatewayV2CustomAuthorizerSimpleResponse {
+	logger := core.GetLogger()
+
+	authResponse := events.APIGatewayV2CustomAuthorizerSimpleResponse{
+		IsAuthorized: decision.Effect == "Allow",
+		Context:      generateAuthorizerContext(decision),
+	}
+
+	logger.Debugw(
+		"generated authorizer response",
+		"func", "generateSimpleResponse",
+		"authorized", authResponse.IsAuthorized,
+		"response", authResponse,
+	)
+
+	return authResponse
+}
+
+// Handle HTTP API (payload v2) request authorizer events.
+//
+// Responds with an IAM policy, or a simple response when
+// "lambda.authorizer.response" is "simple".
+func handleAuthorizerTypeRequestEvent(ctx context.Context, event events.APIGatewayV2CustomAuthorizerV2Request) (interface{}, error) {
 	logger := core.GetLogger()
 
 	if strings.ToLower(event.Type) != "request" {
@@ -77,36 +242,25 @@ func handleAuthorizerTypeRequestEvent(ctx context.Context, event events.APIGatew
 		"token", token,
 	)
 
-	// Token validation and blocklist lookup.
-	redisClient := cache.GetRedisClient()
-	checkResult, err := blocklist.CheckByJwt(redisClient, token)
-	if err != nil {
-		logger.Errorw(
-			"token check failed",
-			"func", "awslambda.handleAuthorizerTypeRequestEvent",
-			"err", err.Error(),
-		)
+	method := event.RequestContext.HTTP.Method
+	if method == "" {
+		method = methodFromArn(event.RouteArn)
 	}
-
-	// Response generation.
-	var response events.APIGatewayCustomAuthorizerResponse
-	var action string
-	if checkResult.IsBlocked || err != nil {
-		action = "Deny"
-		// This is how to define status codes returned by AWS Lambda Authorizers.
-		err = ErrLambdaAuth401Unauthorized
-	} else {
-		action = "Allow"
-		err = nil
+	decision, err := authorize(ctx, token, method, event.RawPath)
+	if err == ErrLambdaAuth500AuthConfig {
+		return events.APIGatewayCustomAuthorizerResponse{}, err
 	}
-	response = generatePolicyResponse("user", action, event.RouteArn, checkResult)
 	logger.Debugw(
 		"lambda authorizer response generated",
 		"func", "awslambda.handleAuthorizerTypeRequestEvent",
-		"action", action,
+		"action", decision.Effect,
+		"principal", decision.Identity.PrincipalID,
 	)
 
-	return response, err
+	if core.GetConfig().Lambda.AuthorizerResponse == authorizerResponseSimple {
+		return generateSimpleResponse(decision), err
+	}
+	return generatePolicyResponse(decision, event.RouteArn), err
 }
 
 func handleAuthorizerTypeTokenEvent(ctx context.Context, event events.APIGatewayCustomAuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
@@ -135,34 +289,16 @@ func handleAuthorizerTypeTokenEvent(ctx context.Context, event events.APIGateway
 		"token", token,
 	)
 
-	// Token validation and blocklist lookup.
-	redisClient := cache.GetRedisClient()
-	checkResult, err := blocklist.CheckByJwt(redisClient, token)
-	if err != nil {
-		logger.Errorw(
-			"token check failed",
-			"func", "awslambda.handleAuthorizerTypeTokenEvent",
-			"err", err.Error(),
-		)
-	}
-
-	// Response generation.
-	var response events.APIGatewayCustomAuthorizerResponse
-	var action string
-	if checkResult.IsBlocked || err != nil {
-		action = "Deny"
-		// This is how to define status codes returned by AWS Lambda Authorizers.
-		err = ErrLambdaAuth401Unauthorized
-	} else {
-		action = "Allow"
-		err = nil
+	decision, err := authorize(ctx, token, methodFromArn(event.MethodArn), routeFromMethodArn(event.MethodArn))
+	if err == ErrLambdaAuth500AuthConfig {
+		return events.APIGatewayCustomAuthorizerResponse{}, err
 	}
-	response = generatePolicyResponse("user", action, event.MethodArn, checkResult)
+	response := generatePolicyResponse(decision, event.MethodArn)
 	logger.Debugw(
 		"lambda authorizer response generated",
 		"func", "awslambda.handleAuthorizerTypeTokenEvent",
-		"action", action,
-		"err", err.Error(),
+		"action", decision.Effect,
+		"err", err,
 	)
 
 	return response, err
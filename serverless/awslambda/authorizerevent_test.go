@@ -0,0 +1,109 @@
+package awslambda
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+func Test_AuthorizerRequestEvent_SimpleResponse_Allow(t *testing.T) {
+	setupMockRedis(t)
+	setupVerifiedClaims(t)
+	viper.Set(core.OptStr_LambdaAuthorizerResponse, authorizerResponseSimple)
+
+	event := events.APIGatewayV2CustomAuthorizerV2Request{
+		Type:     "REQUEST",
+		RouteArn: "arn:aws:execute-api:us-east-1:123456789012:api-id/main/GET/orders",
+		RawPath:  "/orders",
+		Headers:  map[string]string{"authorization": "Bearer " + signTestToken(t)},
+	}
+	response, err := handleAuthorizerTypeRequestEvent(context.Background(), event)
+	simple, ok := response.(events.APIGatewayV2CustomAuthorizerSimpleResponse)
+	if err != nil || !ok || !simple.IsAuthorized {
+		t.Fatalf("Expected an authorized simple response: response=%+v, err=%v", response, err)
+	}
+	if simple.Context["sub"] != "user-1" || simple.Context["scopes"] != "orders:read orders:write" || simple.Context["client_id"] != "web-app" {
+		t.Errorf("Expected the verified claims in the context: context=%v", simple.Context)
+	}
+}
+
+func Test_AuthorizerRequestEvent_SimpleResponse_Deny(t *testing.T) {
+	setupMockRedis(t)
+	viper.Set(core.OptStr_LambdaAuthorizerResponse, authorizerResponseSimple)
+	t.Cleanup(func() {
+		viper.Set(core.OptStr_LambdaAuthorizerResponse, nil)
+	})
+
+	event := events.APIGatewayV2CustomAuthorizerV2Request{
+		Type:    "REQUEST",
+		RawPath: "/orders",
+		Headers: map[string]string{"authorization": "Bearer not-a-token"},
+	}
+	response, err := handleAuthorizerTypeRequestEvent(context.Background(), event)
+	simple, ok := response.(events.APIGatewayV2CustomAuthorizerSimpleResponse)
+	if err != ErrLambdaAuth401Unauthorized || !ok || simple.IsAuthorized {
+		t.Errorf("Expected an unauthorized simple response: response=%+v, err=%v", response, err)
+	}
+}
+
+func Test_AuthorizerTokenEvent_VerifiedClaims_PrincipalID(t *testing.T) {
+	setupMockRedis(t)
+	setupVerifiedClaims(t)
+
+	event := events.APIGatewayCustomAuthorizerRequest{
+		Type:               "TOKEN",
+		AuthorizationToken: signTestToken(t),
+		MethodArn:          "arn:aws:execute-api:us-east-1:123456789012:api-id/main/GET/orders",
+	}
+	response, err := handleAuthorizerTypeTokenEvent(context.Background(), event)
+	if err != nil || response.PrincipalID != "user-1" || response.PolicyDocument.Statement[0].Effect != "Allow" {
+		t.Errorf("Expected the subject as the principal: response=%+v, err=%v", response, err)
+	}
+}
+
+func Test_AuthorizerTokenEvent_UnverifiedClaims_DefaultPrincipalID(t *testing.T) {
+	setupMockRedis(t)
+
+	event := events.APIGatewayCustomAuthorizerRequest{
+		Type:               "TOKEN",
+		AuthorizationToken: testTokenString,
+		MethodArn:          "arn:aws:execute-api:us-east-1:123456789012:api-id/main/GET/orders",
+	}
+	response, err := handleAuthorizerTypeTokenEvent(context.Background(), event)
+	if err != nil || response.PrincipalID != defaultPrincipalID {
+		t.Errorf("Expected the default principal: response=%+v, err=%v", response, err)
+	}
+	if _, ok := response.Context["sub"]; ok {
+		t.Errorf("Expected no unverified claims in the context: context=%v", response.Context)
+	}
+}
+
+func setupVerifiedClaims(t *testing.T) {
+	viper.Set(core.OptStr_JwtVerifyEnabled, true)
+	viper.Set(core.OptStr_JwtVerifyHmacSecret, `{"kty":"oct","k":"Zm9vYmFy"}`) // "foobar", as a JWK.
+	t.Cleanup(func() {
+		viper.Set(core.OptStr_JwtVerifyEnabled, nil)
+		viper.Set(core.OptStr_JwtVerifyHmacSecret, nil)
+		viper.Set(core.OptStr_LambdaAuthorizerResponse, nil)
+	})
+}
+
+// Sign a token with the test HMAC secret, and scopes in the "scp" array claim.
+func signTestToken(t *testing.T) string {
+	token, _ := jwt.NewBuilder().
+		Subject("user-1").
+		Claim("scp", []string{"orders:read", "orders:write"}).
+		Claim("azp", "web-app").
+		Build()
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.HS256, []byte("foobar")))
+	if err != nil {
+		t.Fatalf("Failed to sign token: err=%v", err)
+	}
+	return string(signed)
+}
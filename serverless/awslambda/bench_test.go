@@ -0,0 +1,61 @@
+package awslambda
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Benchmarks of the per-invocation overhead of the handler, with an in-memory Redis.
+//
+// Run with: go test ./serverless/awslambda -bench . -run '^$'
+
+func BenchmarkDetectEventType(b *testing.B) {
+	event := readBenchmarkEvent(b, "apigw-v2-check")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		detectEventType(event)
+	}
+}
+
+func BenchmarkHandleLambdaEvent_AuthorizerToken(b *testing.B) {
+	benchmarkHandleLambdaEvent(b, "authorizer-token")
+}
+
+func BenchmarkHandleLambdaEvent_AuthorizerRequest(b *testing.B) {
+	benchmarkHandleLambdaEvent(b, "authorizer-request")
+}
+
+func BenchmarkHandleLambdaEvent_HttpProxyV1(b *testing.B) {
+	benchmarkHandleLambdaEvent(b, "apigw-v1-check")
+}
+
+func BenchmarkHandleLambdaEvent_HttpProxyV2(b *testing.B) {
+	benchmarkHandleLambdaEvent(b, "apigw-v2-check")
+}
+
+func BenchmarkHandleLambdaEvent_ALBTargetGroup(b *testing.B) {
+	benchmarkHandleLambdaEvent(b, "alb-check")
+}
+
+func benchmarkHandleLambdaEvent(b *testing.B, name string) {
+	event := readBenchmarkEvent(b, name)
+	setupMockRedis(b)
+	warmUp(context.Background())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = HandleLambdaEvent(context.Background(), event)
+	}
+}
+
+func readBenchmarkEvent(b *testing.B, name string) []byte {
+	event, err := os.ReadFile(filepath.Join("testdata", "events", name+".json"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return event
+}
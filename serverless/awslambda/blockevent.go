@@ -0,0 +1,194 @@
+package awslambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/divergentcodes/jwtblock/internal/audit"
+	"github.com/divergentcodes/jwtblock/internal/blocklist"
+	"github.com/divergentcodes/jwtblock/internal/cache"
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+)
+
+// Claims blocked by the subjects and session IDs of a block message.
+const (
+	claimSubject   = "sub"
+	claimSessionID = "sid"
+)
+
+var ErrEmptyBlockMessage = errors.New("block message has no tokens, hashes, subjects or session IDs")
+
+// A blockMessage requests blocks from an asynchronous event, e.g. an identity provider "user disabled" event.
+type blockMessage struct {
+	Tokens     []string `json:"tokens"`
+	Hashes     []string `json:"hashes"`
+	Subjects   []string `json:"subjects"`    // blocks every token with the "sub" claim.
+	SessionIDs []string `json:"session_ids"` // blocks every token with the "sid" claim.
+	TTL        *int     `json:"ttl_sec"`     // optional, the default TTL applies without it.
+	Reason     string   `json:"reason"`
+}
+
+// An eventEnvelope is an SNS notification or EventBridge event, delivered through SQS or SNS.
+type eventEnvelope struct {
+	Type       string          `json:"Type"`
+	Message    string          `json:"Message"`
+	DetailType string          `json:"detail-type"`
+	Detail     json.RawMessage `json:"detail"`
+}
+
+// Parse a block message, unwrapping SNS notifications and EventBridge events.
+func parseBlockMessage(body string) (blockMessage, error) {
+	var envelope eventEnvelope
+	if err := json.Unmarshal([]byte(body), &envelope); err != nil {
+		return blockMessage{}, err
+	}
+	if envelope.Type == "Notification" && envelope.Message != "" {
+		return parseBlockMessage(envelope.Message)
+	}
+	if envelope.DetailType != "" && len(envelope.Detail) > 0 {
+		return parseBlockMessage(string(envelope.Detail))
+	}
+
+	var message blockMessage
+	if err := json.Unmarshal([]byte(body), &message); err != nil {
+		return message, err
+	}
+	if len(message.Tokens)+len(message.Hashes)+len(message.Subjects)+len(message.SessionIDs) == 0 {
+		return message, ErrEmptyBlockMessage
+	}
+	return message, nil
+}
+
+// Apply the blocks of a message from an asynchronous event.
+//
+// Malformed messages and invalid values are logged and dropped, since
+// retrying cannot fix them. An error is returned when a block failed and the
+// message should be retried, e.g. when Redis is unavailable.
+func handleBlockMessage(body string, actor string) error {
+	logger := core.GetLogger()
+
+	message, err := parseBlockMessage(body)
+	if err != nil {
+		metrics.Int("event_blocks_invalid_total").Add(1)
+		logger.Errorw(
+			"dropping invalid block message",
+			"func", "awslambda.handleBlockMessage",
+			"actor", actor,
+			"err", err.Error(),
+		)
+		return nil
+	}
+
+	ttl := -1
+	if message.TTL != nil {
+		ttl = *message.TTL
+	}
+	redisClient := cache.GetRedisClient()
+	failed := 0
+	dropInvalid := func(hash string, err error) {
+		metrics.Int("event_blocks_invalid_total").Add(1)
+		logger.Errorw(
+			"dropping invalid value from block message",
+			"func", "awslambda.handleBlockMessage",
+			"actor", actor,
+			"hash", hash,
+			"err", err.Error(),
+		)
+	}
+	block := func(hash string, blockFn func() (*blocklist.BlockResult, error)) {
+		result, err := blockFn()
+		audit.Block(audit.Event{Hash: hash, Actor: actor, Reason: message.Reason}, result, err)
+		if errors.Is(err, blocklist.ErrClaimNotBlockable) || errors.Is(err, blocklist.ErrEmptyClaimValue) {
+			dropInvalid(hash, err)
+			return
+		}
+		if err != nil {
+			failed++
+			metrics.Int("event_blocks_failed_total").Add(1)
+			logger.Errorw(
+				"block from event failed",
+				"func", "awslambda.handleBlockMessage",
+				"actor", actor,
+				"hash", hash,
+				"err", err.Error(),
+			)
+			return
+		}
+		metrics.Int("event_blocks_total").Add(1)
+	}
+
+	for _, tokenString := range message.Tokens {
+		hash := crypto.TokenHash(tokenString)
+		if _, err := crypto.RunJwtChecks(tokenString); err != nil {
+			dropInvalid(hash, err)
+			continue
+		}
+		block(hash, func() (*blocklist.BlockResult, error) {
+			return blocklist.BlockWithTTL(redisClient, tokenString, ttl)
+		})
+	}
+	for _, hash := range message.Hashes {
+		if err := crypto.IsValidSha256(hash); err != nil {
+			dropInvalid(hash, err)
+			continue
+		}
+		block(hash, func() (*blocklist.BlockResult, error) {
+			return blocklist.BlockBySha256WithTTL(redisClient, hash, ttl)
+		})
+	}
+	for claim, values := range map[string][]string{claimSubject: message.Subjects, claimSessionID: message.SessionIDs} {
+		for _, value := range values {
+			hash := crypto.ClaimHash(claim, value)
+			if value == "" {
+				dropInvalid(hash, blocklist.ErrEmptyClaimValue)
+				continue
+			}
+			block(hash, func() (*blocklist.BlockResult, error) {
+				return blocklist.BlockClaimWithTTL(redisClient, claim, value, ttl)
+			})
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d blocks from the message failed", failed)
+	}
+	return nil
+}
+
+// Handle SQS events, reporting the records with failed blocks as partial batch failures to retry them.
+//
+// The event source mapping must have ReportBatchItemFailures enabled, or the
+// whole batch is considered processed.
+func handleSQSEvent(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+	response := events.SQSEventResponse{BatchItemFailures: []events.SQSBatchItemFailure{}}
+	for _, record := range event.Records {
+		if err := handleBlockMessage(record.Body, "sqs:"+record.EventSourceARN); err != nil {
+			response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: record.MessageId})
+		}
+	}
+	return response, nil
+}
+
+// Handle SNS events, failing the invocation when a block failed so Lambda retries it.
+//
+// Blocks are idempotent, so retrying the records that succeeded is harmless.
+func handleSNSEvent(ctx context.Context, event events.SNSEvent) (interface{}, error) {
+	var errs []error
+	for _, record := range event.Records {
+		if err := handleBlockMessage(record.SNS.Message, "sns:"+record.SNS.TopicArn); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Handle EventBridge events, failing the invocation when a block failed so Lambda retries it.
+func handleEventBridgeEvent(ctx context.Context, event events.CloudWatchEvent) (interface{}, error) {
+	return nil, handleBlockMessage(string(event.Detail), "eventbridge:"+event.Source)
+}
@@ -0,0 +1,124 @@
+package awslambda
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/blocklist"
+	"github.com/divergentcodes/jwtblock/internal/cache"
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+)
+
+func Test_HandleLambdaEvent_SQSSubjects_Blocked(t *testing.T) {
+	setupMockRedis(t)
+	setupBlockClaims(t)
+
+	event := `{"Records": [
+		{"messageId": "m1", "eventSource": "aws:sqs", "eventSourceARN": "arn:aws:sqs:us-east-1:123456789012:idp",
+		 "body": "{\"subjects\": [\"1234567890\"], \"reason\": \"user disabled\"}"},
+		{"messageId": "m2", "eventSource": "aws:sqs", "eventSourceARN": "arn:aws:sqs:us-east-1:123456789012:idp",
+		 "body": "not json"}
+	]}`
+	response, err := HandleLambdaEvent(context.Background(), json.RawMessage(event))
+	sqsResponse, ok := response.(events.SQSEventResponse)
+	if err != nil || !ok || len(sqsResponse.BatchItemFailures) != 0 {
+		t.Fatalf("Expected no batch failures, invalid messages are dropped: response=%+v, err=%v", response, err)
+	}
+
+	checkResult, err := blocklist.CheckByJwt(cache.GetRedisClient(), testTokenString)
+	if err != nil || !checkResult.IsBlocked {
+		t.Errorf("Expected the token of the subject to be blocked: result=%+v, err=%v", checkResult, err)
+	}
+}
+
+func Test_SQSEvent_StoreUnavailable_BatchItemFailures(t *testing.T) {
+	setupBlockClaims(t)
+	core.InitConfigDefaults()
+	redisServer := miniredis.RunT(t)
+	cache.SetRedisClient(redis.NewClient(&redis.Options{Addr: redisServer.Addr(), MaxRetries: -1}))
+	redisServer.Close()
+
+	event := events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "m1", EventSource: "aws:sqs", Body: `{"session_ids": ["session-1"]}`},
+	}}
+	response, err := handleSQSEvent(context.Background(), event)
+	if err != nil || len(response.BatchItemFailures) != 1 || response.BatchItemFailures[0].ItemIdentifier != "m1" {
+		t.Errorf("Expected the record to be retried: response=%+v, err=%v", response, err)
+	}
+}
+
+func Test_SQSEvent_ClaimNotBlockable_Dropped(t *testing.T) {
+	setupMockRedis(t)
+	viper.Set(core.OptStr_JwtBlockClaims, "")
+	t.Cleanup(func() { viper.Set(core.OptStr_JwtBlockClaims, nil) })
+
+	event := events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "m1", EventSource: "aws:sqs", Body: `{"subjects": ["1234567890"]}`},
+	}}
+	invalid := metrics.Int("event_blocks_invalid_total").Value()
+	response, err := handleSQSEvent(context.Background(), event)
+	if err != nil || len(response.BatchItemFailures) != 0 {
+		t.Errorf("Expected the record to be dropped, not retried: response=%+v, err=%v", response, err)
+	}
+	if actual := metrics.Int("event_blocks_invalid_total").Value(); actual != invalid+1 {
+		t.Errorf("Expected the value to be counted as invalid, got %d", actual-invalid)
+	}
+}
+
+func Test_HandleLambdaEvent_EventBridgeHashes_Blocked(t *testing.T) {
+	setupMockRedis(t)
+	hash := crypto.TokenHash(testTokenString)
+
+	event := `{"version": "0", "id": "e1", "detail-type": "Session Revoked", "source": "idp.example.com",
+		"detail": {"hashes": ["` + hash + `"], "ttl_sec": 60}}`
+	_, err := HandleLambdaEvent(context.Background(), json.RawMessage(event))
+	checkResult, _ := blocklist.CheckBySha256(cache.GetRedisClient(), hash)
+	if err != nil || !checkResult.IsBlocked || checkResult.TTL != 60 {
+		t.Errorf("Expected the hash to be blocked: result=%+v, err=%v", checkResult, err)
+	}
+}
+
+func Test_HandleLambdaEvent_SNSTokens_Blocked(t *testing.T) {
+	setupMockRedis(t)
+
+	event := `{"Records": [{"EventSource": "aws:sns", "Sns": {"TopicArn": "arn:aws:sns:us-east-1:123456789012:idp",
+		"Message": "{\"tokens\": [\"` + testTokenString + `\"]}"}}]}`
+	_, err := HandleLambdaEvent(context.Background(), json.RawMessage(event))
+	checkResult, _ := blocklist.CheckByJwt(cache.GetRedisClient(), testTokenString)
+	if err != nil || !checkResult.IsBlocked {
+		t.Errorf("Expected the token to be blocked: result=%+v, err=%v", checkResult, err)
+	}
+}
+
+func Test_parseBlockMessage_Envelopes_Success(t *testing.T) {
+	bodies := []string{
+		`{"subjects": ["user-1"]}`,
+		`{"Type": "Notification", "Message": "{\"subjects\": [\"user-1\"]}"}`,
+		`{"detail-type": "User Disabled", "source": "idp", "detail": {"subjects": ["user-1"]}}`,
+		`{"Type": "Notification", "Message": "{\"detail-type\": \"User Disabled\", \"detail\": {\"subjects\": [\"user-1\"]}}"}`,
+	}
+	for _, body := range bodies {
+		message, err := parseBlockMessage(body)
+		if err != nil || len(message.Subjects) != 1 || message.Subjects[0] != "user-1" {
+			t.Errorf("Expected the subject: body=%s, message=%+v, err=%v", body, message, err)
+		}
+	}
+	if _, err := parseBlockMessage(`{"reason": "nothing"}`); err != ErrEmptyBlockMessage {
+		t.Errorf("Expected an empty message error: err=%v", err)
+	}
+}
+
+func setupBlockClaims(t *testing.T) {
+	viper.Set(core.OptStr_JwtBlockClaims, "sub,sid")
+	t.Cleanup(func() {
+		viper.Set(core.OptStr_JwtBlockClaims, nil)
+	})
+}
@@ -0,0 +1,76 @@
+package awslambda
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Types of the Lambda events, also used as span names.
+const (
+	eventTypeUnknown           = ""
+	eventTypeAuthorizerRequest = "authorizerTypeRequestEvent"
+	eventTypeAuthorizerToken   = "authorizerTypeTokenEvent"
+	eventTypeALBTargetGroup    = "albTargetGroupEvent"
+	eventTypeHttpProxyV1       = "httpProxyRequestEventV1"
+	eventTypeFunctionURL       = "functionURLEvent"
+	eventTypeHttpProxyV2       = "httpProxyRequestEventV2"
+	eventTypeSQS               = "sqsEvent"
+	eventTypeSNS               = "snsEvent"
+	eventTypeEventBridge       = "eventBridgeEvent"
+)
+
+// An eventProbe has the fields that tell the Lambda event types apart.
+//
+// JSON keys are matched case-insensitively, preferring exact matches, so the
+// SQS "eventSource" and SNS "EventSource" keys need separate fields.
+type eventProbe struct {
+	Type           string `json:"type"`
+	Version        string `json:"version"`
+	HTTPMethod     string `json:"httpMethod"`
+	DetailType     string `json:"detail-type"`
+	Source         string `json:"source"`
+	RequestContext struct {
+		ELB struct {
+			TargetGroupArn string `json:"targetGroupArn"`
+		} `json:"elb"`
+		DomainName string `json:"domainName"`
+	} `json:"requestContext"`
+	Records []struct {
+		SQSEventSource string `json:"eventSource"`
+		SNSEventSource string `json:"EventSource"`
+	} `json:"Records"`
+}
+
+// Detect the type of a Lambda event, in a single pass over the JSON.
+//
+// ALB events have the fields of API Gateway V1 events, and Function URL
+// events the payload format of API Gateway V2 events, so they are told apart
+// first.
+func detectEventType(event []byte) string {
+	var probe eventProbe
+	if err := json.Unmarshal(event, &probe); err != nil {
+		return eventTypeUnknown
+	}
+
+	switch {
+	case strings.EqualFold(probe.Type, "request"):
+		return eventTypeAuthorizerRequest
+	case strings.EqualFold(probe.Type, "token"):
+		return eventTypeAuthorizerToken
+	case probe.RequestContext.ELB.TargetGroupArn != "":
+		return eventTypeALBTargetGroup
+	case probe.HTTPMethod != "":
+		return eventTypeHttpProxyV1
+	case probe.Version == "2.0" && strings.Contains(probe.RequestContext.DomainName, ".lambda-url."):
+		return eventTypeFunctionURL
+	case probe.Version == "2.0":
+		return eventTypeHttpProxyV2
+	case len(probe.Records) > 0 && probe.Records[0].SQSEventSource == "aws:sqs":
+		return eventTypeSQS
+	case len(probe.Records) > 0 && probe.Records[0].SNSEventSource == "aws:sns":
+		return eventTypeSNS
+	case probe.DetailType != "" && probe.Source != "":
+		return eventTypeEventBridge
+	}
+	return eventTypeUnknown
+}
@@ -0,0 +1,54 @@
+package awslambda
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_detectEventType_Events_Types(t *testing.T) {
+	tests := []struct {
+		event    string
+		expected string
+	}{
+		{"alb-check", eventTypeALBTargetGroup},
+		{"apigw-v1-check", eventTypeHttpProxyV1},
+		{"apigw-v2-block", eventTypeHttpProxyV2},
+		{"apigw-v2-check", eventTypeHttpProxyV2},
+		{"authorizer-request", eventTypeAuthorizerRequest},
+		{"authorizer-token", eventTypeAuthorizerToken},
+	}
+	for _, test := range tests {
+		t.Run(test.event, func(t *testing.T) {
+			event, err := os.ReadFile(filepath.Join("testdata", "events", test.event+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := detectEventType(event); actual != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_detectEventType_AsyncAndInvalidEvents_Types(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    string
+		expected string
+	}{
+		{"sqs", `{"Records":[{"messageId":"1","eventSource":"aws:sqs","body":"{}"}]}`, eventTypeSQS},
+		{"sns", `{"Records":[{"EventSource":"aws:sns","Sns":{"Message":"{}"}}]}`, eventTypeSNS},
+		{"eventbridge", `{"detail-type":"User Disabled","source":"idp","detail":{}}`, eventTypeEventBridge},
+		{"functionurl", `{"version":"2.0","requestContext":{"domainName":"abc.lambda-url.us-east-1.on.aws"}}`, eventTypeFunctionURL},
+		{"unknown", `{"foo":"bar"}`, eventTypeUnknown},
+		{"malformed", `{"type":`, eventTypeUnknown},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := detectEventType([]byte(test.event)); actual != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+}
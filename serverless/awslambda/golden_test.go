@@ -0,0 +1,95 @@
+package awslambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/blocklist"
+	"github.com/divergentcodes/jwtblock/internal/cache"
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+)
+
+var updateGolden = flag.Bool("update", false, "Update the golden files of the Lambda event tests")
+
+// Store states of the golden tests.
+const (
+	goldenStoreEmpty   = "empty"         // the token is not blocked.
+	goldenStoreBlocked = "blocked"       // the token is blocked for an hour.
+	goldenStoreFailure = "store_failure" // Redis is unreachable.
+)
+
+// Invoke the handler with the events in testdata/events, and compare the results with testdata/golden.
+//
+// Run with -update to regenerate the golden files after intended changes.
+func Test_Invoke_GoldenEvents_Match(t *testing.T) {
+	tests := []struct {
+		event string
+		store string
+	}{
+		{"apigw-v1-check", goldenStoreEmpty},
+		{"apigw-v1-check", goldenStoreBlocked},
+		{"apigw-v1-check-malformed", goldenStoreEmpty},
+		{"apigw-v2-block", goldenStoreEmpty},
+		{"apigw-v2-block", goldenStoreBlocked},
+		{"apigw-v2-check", goldenStoreFailure},
+		{"authorizer-token", goldenStoreEmpty},
+		{"authorizer-token", goldenStoreBlocked},
+		{"authorizer-token", goldenStoreFailure},
+		{"authorizer-token-malformed", goldenStoreEmpty},
+		{"authorizer-request", goldenStoreEmpty},
+		{"authorizer-request", goldenStoreBlocked},
+		{"alb-check", goldenStoreEmpty},
+		{"alb-check", goldenStoreBlocked},
+	}
+	for _, test := range tests {
+		name := test.event + "_" + test.store
+		t.Run(name, func(t *testing.T) {
+			event, err := os.ReadFile(filepath.Join("testdata", "events", test.event+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			setupGoldenStore(t, test.store)
+
+			actual, _ := json.MarshalIndent(Invoke(context.Background(), event), "", "  ")
+			actual = append(actual, '\n')
+			goldenPath := filepath.Join("testdata", "golden", name+".json")
+			if *updateGolden {
+				if err := os.WriteFile(goldenPath, actual, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(actual, expected) {
+				t.Errorf("Result differs from %s:\n%s", goldenPath, actual)
+			}
+		})
+	}
+}
+
+func setupGoldenStore(t *testing.T, store string) {
+	setupMockRedis(t)
+	switch store {
+	case goldenStoreBlocked:
+		if _, err := blocklist.BlockBySha256WithTTL(cache.GetRedisClient(), crypto.TokenHash(testTokenString), 3600); err != nil {
+			t.Fatal(err)
+		}
+	case goldenStoreFailure:
+		core.InitConfigDefaults()
+		cache.SetRedisClient(redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}))
+		// Keep the unreachable client, instead of reconnecting to the configured Redis.
+		viper.Set(core.OptStr_LambdaRedisValidateIdleSec, -1)
+		t.Cleanup(func() { viper.Set(core.OptStr_LambdaRedisValidateIdleSec, nil) })
+	}
+}
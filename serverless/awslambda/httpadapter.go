@@ -0,0 +1,115 @@
+package awslambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/web"
+)
+
+var webHandlerOnce sync.Once
+var webHandler http.Handler
+
+// Get a singleton of the web API handler, which serves HTTP proxy events.
+func getWebHandler() http.Handler {
+	webHandlerOnce.Do(func() {
+		webHandler = web.Handler()
+	})
+	return webHandler
+}
+
+// A responseBuffer collects the response of the web API handler.
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{
+		header: make(http.Header),
+		status: http.StatusOK,
+	}
+}
+
+func (w *responseBuffer) Header() http.Header {
+	return w.header
+}
+
+func (w *responseBuffer) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *responseBuffer) WriteHeader(status int) {
+	w.status = status
+}
+
+// Build an HTTP request from the fields of an HTTP proxy event.
+//
+// The API Gateway request ID is used as the request ID, unless the caller sent one.
+func newProxyRequest(ctx context.Context, method string, path string, rawQuery string, body string, isBase64Encoded bool) (*http.Request, error) {
+	var bodyBytes []byte
+	if isBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, err
+		}
+		bodyBytes = decoded
+	} else {
+		bodyBytes = []byte(body)
+	}
+
+	target := path
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+	r, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	r.RequestURI = target
+	return r, nil
+}
+
+// Serve an HTTP request with the web API handler, so Lambda exposes the same routes as the web service.
+func serveProxyRequest(r *http.Request, sourceIP string, requestID string) *responseBuffer {
+	logger := core.GetLogger()
+
+	// The source IP is the client, since API Gateway is the only proxy in front of Lambda.
+	r.RemoteAddr = sourceIP
+	requestIDHeader := core.GetConfig().HTTP.HeaderRequestID
+	if r.Header.Get(requestIDHeader) == "" && requestID != "" {
+		r.Header.Set(requestIDHeader, requestID)
+	}
+
+	w := newResponseBuffer()
+	getWebHandler().ServeHTTP(w, r)
+
+	logger.Debugw(
+		"Served HTTP proxy event",
+		"func", "awslambda.serveProxyRequest",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"status", w.status,
+	)
+	return w
+}
+
+// Remove the stage name from the path of an HTTP API event, for stages other than $default.
+func stripStage(path string, stage string) string {
+	if stage == "" || stage == "$default" {
+		return path
+	}
+	if trimmed := strings.TrimPrefix(path, "/"+stage); trimmed != path && (trimmed == "" || strings.HasPrefix(trimmed, "/")) {
+		if trimmed == "" {
+			return "/"
+		}
+		return trimmed
+	}
+	return path
+}
@@ -0,0 +1,79 @@
+package awslambda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Handle Application Load Balancer target group events, routed by path and method like the web service.
+//
+// With multi-value headers enabled on the target group, the request has
+// multi-value fields, and the response must use them too. The X-Amzn-Trace-Id
+// header set by the load balancer is used as the request ID.
+func handleALBTargetGroupEvent(ctx context.Context, event events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+	isMultiValue := event.MultiValueHeaders != nil || event.MultiValueQueryStringParameters != nil
+
+	// The load balancer passes query strings as sent, still URL-encoded.
+	var query []string
+	if isMultiValue {
+		for name, values := range event.MultiValueQueryStringParameters {
+			for _, value := range values {
+				query = append(query, name+"="+value)
+			}
+		}
+	} else {
+		for name, value := range event.QueryStringParameters {
+			query = append(query, name+"="+value)
+		}
+	}
+
+	r, err := newProxyRequest(ctx, event.HTTPMethod, event.Path, strings.Join(query, "&"), event.Body, event.IsBase64Encoded)
+	if err != nil {
+		return newALBTargetGroupResponse(http.StatusBadRequest, isMultiValue), nil
+	}
+	for name, value := range event.Headers {
+		r.Header.Set(name, value)
+	}
+	for name, values := range event.MultiValueHeaders {
+		r.Header.Del(name)
+		for _, value := range values {
+			r.Header.Add(name, value)
+		}
+	}
+
+	w := serveProxyRequest(r, albSourceIP(r.Header), r.Header.Get("X-Amzn-Trace-Id"))
+
+	response := newALBTargetGroupResponse(w.status, isMultiValue)
+	response.Body = w.body.String()
+	for name, values := range w.header {
+		if isMultiValue {
+			response.MultiValueHeaders[name] = values
+		} else {
+			response.Headers[name] = values[0]
+		}
+	}
+	return response, nil
+}
+
+func newALBTargetGroupResponse(status int, isMultiValue bool) events.ALBTargetGroupResponse {
+	response := events.ALBTargetGroupResponse{
+		StatusCode:        status,
+		StatusDescription: fmt.Sprintf("%d %s", status, http.StatusText(status)),
+	}
+	if isMultiValue {
+		response.MultiValueHeaders = make(map[string][]string)
+	} else {
+		response.Headers = make(map[string]string)
+	}
+	return response
+}
+
+// Get the client IP of an ALB event, which the load balancer appends to X-Forwarded-For.
+func albSourceIP(header http.Header) string {
+	forwarded := strings.Split(strings.Join(header.Values("X-Forwarded-For"), ","), ",")
+	return strings.TrimSpace(forwarded[len(forwarded)-1])
+}
@@ -0,0 +1,67 @@
+package awslambda
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+func Test_HandleLambdaEvent_ALBMultiValue_Success(t *testing.T) {
+	setupMockRedis(t)
+
+	event := `{
+		"requestContext": {"elb": {"targetGroupArn": "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/jwtblock/abc"}},
+		"httpMethod": "GET",
+		"path": "/blocklist/check",
+		"multiValueQueryStringParameters": {"debug": ["a%20b"]},
+		"multiValueHeaders": {
+			"authorization": ["Bearer ` + testTokenString + `"],
+			"x-forwarded-for": ["198.51.100.1, 203.0.113.7"]
+		},
+		"body": "",
+		"isBase64Encoded": false
+	}`
+	response, err := HandleLambdaEvent(context.Background(), json.RawMessage(event))
+	albResponse, ok := response.(events.ALBTargetGroupResponse)
+	if err != nil || !ok || albResponse.StatusCode != http.StatusOK || albResponse.StatusDescription != "200 OK" {
+		t.Fatalf("Expected an ALB response: response=%+v, err=%v", response, err)
+	}
+	if albResponse.Headers != nil || albResponse.MultiValueHeaders["Content-Type"][0] != "application/json" {
+		t.Errorf("Expected multi-value response headers: response=%+v", albResponse)
+	}
+}
+
+func Test_ALBTargetGroupEvent_CorsPreflight_Success(t *testing.T) {
+	setupMockRedis(t)
+	viper.Set(core.OptStr_HttpCorsAllowedOrigins, "https://app.example.com")
+	t.Cleanup(func() {
+		viper.Set(core.OptStr_HttpCorsAllowedOrigins, nil)
+	})
+
+	event := events.ALBTargetGroupRequest{
+		HTTPMethod:     http.MethodOptions,
+		Path:           "/blocklist/block",
+		Headers:        map[string]string{"origin": "https://app.example.com"},
+		RequestContext: events.ALBTargetGroupRequestContext{ELB: events.ELBContext{TargetGroupArn: "arn"}},
+	}
+	response, err := handleALBTargetGroupEvent(context.Background(), event)
+	if err != nil || response.StatusCode != http.StatusNoContent || response.Headers["Access-Control-Allow-Origin"] != "https://app.example.com" {
+		t.Errorf("Expected a CORS preflight response: response=%+v, err=%v", response, err)
+	}
+}
+
+func Test_albSourceIP_ForwardedFor_LastAddress(t *testing.T) {
+	header := http.Header{"X-Forwarded-For": []string{"198.51.100.1, 203.0.113.7"}}
+	if ip := albSourceIP(header); ip != "203.0.113.7" {
+		t.Errorf("Expected the address appended by the load balancer: ip=%s", ip)
+	}
+	if ip := albSourceIP(http.Header{}); ip != "" {
+		t.Errorf("Expected no address: ip=%s", ip)
+	}
+}
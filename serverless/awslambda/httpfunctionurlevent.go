@@ -0,0 +1,37 @@
+package awslambda
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Handle Lambda Function URL events, routed by path and method like the web service.
+//
+// Function URL events have the API Gateway V2 payload format, without stages.
+func handleFunctionURLEvent(ctx context.Context, event events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	response, err := handleHttpProxyEventV2(ctx, events.APIGatewayV2HTTPRequest{
+		Version:         event.Version,
+		RawPath:         event.RawPath,
+		RawQueryString:  event.RawQueryString,
+		Cookies:         event.Cookies,
+		Headers:         event.Headers,
+		Body:            event.Body,
+		IsBase64Encoded: event.IsBase64Encoded,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RequestID: event.RequestContext.RequestID,
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:   event.RequestContext.HTTP.Method,
+				Path:     event.RequestContext.HTTP.Path,
+				SourceIP: event.RequestContext.HTTP.SourceIP,
+			},
+		},
+	})
+	return events.LambdaFunctionURLResponse{
+		StatusCode:      response.StatusCode,
+		Headers:         response.Headers,
+		Body:            response.Body,
+		IsBase64Encoded: response.IsBase64Encoded,
+		Cookies:         response.Cookies,
+	}, err
+}
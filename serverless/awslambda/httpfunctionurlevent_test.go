@@ -0,0 +1,36 @@
+package awslambda
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func Test_HandleLambdaEvent_FunctionURL_Success(t *testing.T) {
+	setupMockRedis(t)
+
+	event := `{
+		"version": "2.0",
+		"routeKey": "$default",
+		"rawPath": "/blocklist/check",
+		"rawQueryString": "",
+		"headers": {"authorization": "Bearer ` + testTokenString + `"},
+		"requestContext": {
+			"domainName": "abcdefgh.lambda-url.us-east-1.on.aws",
+			"requestId": "request-1",
+			"http": {"method": "GET", "path": "/blocklist/check", "sourceIp": "203.0.113.7"}
+		},
+		"isBase64Encoded": false
+	}`
+	response, err := HandleLambdaEvent(context.Background(), json.RawMessage(event))
+	urlResponse, ok := response.(events.LambdaFunctionURLResponse)
+	if err != nil || !ok || urlResponse.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a Function URL response: response=%+v, err=%v", response, err)
+	}
+	if urlResponse.Headers["X-Request-Id"] != "request-1" {
+		t.Errorf("Expected the Function URL request ID: headers=%v", urlResponse.Headers)
+	}
+}
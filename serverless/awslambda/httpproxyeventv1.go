@@ -2,123 +2,48 @@ package awslambda
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
-	"strings"
+	"net/url"
 
 	"github.com/aws/aws-lambda-go/events"
-
-	"github.com/divergentcodes/jwtblock/internal/blocklist"
-	"github.com/divergentcodes/jwtblock/internal/cache"
-	"github.com/divergentcodes/jwtblock/internal/core"
 )
 
-// Handle API Gateway V1 (REST) request events
+// Handle API Gateway V1 (REST) request events, routed by path and method like the web service.
 func handleHttpProxyEventV1(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	logger := core.GetLogger()
-	var response events.APIGatewayProxyResponse
-	responseHeaders := make(map[string]string)
-	httpMethod := strings.ToUpper(event.HTTPMethod)
-	origin, _ := getHeaderValue(event.Headers, "origin")
-
-	// Handle CORS preflight requests.
-	if strings.EqualFold(httpMethod, http.MethodOptions) {
-		logger.Debugw(
-			"received OPTIONS preflight request",
-			"func", "awslambda.handleHttpProxyEventV1",
-		)
-		response = events.APIGatewayProxyResponse{
-			StatusCode: http.StatusOK,
-			Headers:    makeCorsPreflightHeaders(origin, responseHeaders),
-		}
-		return response, nil
-	} else {
-		responseHeaders["Content-Type"] = "application/json"
-	}
-
-	// Only allow POST.
-	if !strings.EqualFold(httpMethod, http.MethodPost) {
-		logger.Warnw(
-			"invalid HTTP method",
-			"func", "awslambda.handleHttpProxyEventV1",
-			"method", httpMethod,
-		)
-		response = events.APIGatewayProxyResponse{
-			StatusCode: http.StatusMethodNotAllowed,
-			Headers:    makeCorsPreflightHeaders(origin, responseHeaders),
-		}
-		return response, nil
+	// Multi-value fields are set by API Gateway, and preferred since they keep repeated values.
+	query := make(url.Values)
+	for name, value := range event.QueryStringParameters {
+		query.Set(name, value)
 	}
-
-	// Get token from headers.
-	tokenString, tokenErr := getBearerToken(event.Headers)
-
-	// No token found in headers. Unauthorized.
-	if tokenErr != nil || tokenString == "" {
-		msg := "failed to get token from request headers"
-		logger.Errorw(
-			msg,
-			"func", "awslambda.handleHttpProxyEventV1",
-			"tokenError", tokenErr.Error(),
-		)
-		response = events.APIGatewayProxyResponse{
-			StatusCode: http.StatusUnauthorized,
-			Headers:    responseHeaders,
-			Body:       tokenErr.Error(),
-		}
-		return response, tokenErr
+	for name, values := range event.MultiValueQueryStringParameters {
+		query[name] = values
 	}
-	logger.Debugw(
-		"Received value to add",
-		"func", "awslambda.handleHttpProxyEventV1",
-		"token", tokenString,
-	)
 
-	// Add value to the blocklist.
-	redisClient := cache.GetRedisClient()
-	blockResult, err := blocklist.Block(redisClient, tokenString)
+	r, err := newProxyRequest(ctx, event.HTTPMethod, event.Path, query.Encode(), event.Body, event.IsBase64Encoded)
 	if err != nil {
-		// Error is either token format (400), or server issue (500).
-		httpStatus := http.StatusBadRequest
-		if strings.Contains(strings.ToLower(err.Error()), "cache") {
-			httpStatus = http.StatusInternalServerError
-		}
-		logger.Errorw(
-			"Error adding token to blocklist",
-			"func", "awslambda.handleHttpProxyEventV1",
-			"result", blockResult,
-			"err", err,
-		)
-		jsonData, err := json.Marshal(blockResult)
-		response = events.APIGatewayProxyResponse{
-			StatusCode: httpStatus,
-			Headers:    responseHeaders,
-			Body:       string(jsonData),
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusBadRequest}, nil
+	}
+	for name, value := range event.Headers {
+		r.Header.Set(name, value)
+	}
+	for name, values := range event.MultiValueHeaders {
+		r.Header.Del(name)
+		for _, value := range values {
+			r.Header.Add(name, value)
 		}
-		return response, err
 	}
 
-	// Successful block
-	jsonData, err := json.Marshal(blockResult)
-	if err != nil {
-		errMsg := "Error marshaling blockResult to JSON"
-		logger.Errorw(
-			errMsg,
-			"func", "awslambda.handleHttpProxyEventV1",
-			"err", err,
-		)
-		response = events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Headers:    responseHeaders,
-			Body:       errMsg,
-		}
-		return response, err
+	w := serveProxyRequest(r, event.RequestContext.Identity.SourceIP, event.RequestContext.RequestID)
+
+	response := events.APIGatewayProxyResponse{
+		StatusCode:        w.status,
+		Headers:           make(map[string]string),
+		MultiValueHeaders: make(map[string][]string),
+		Body:              w.body.String(),
 	}
-	response = events.APIGatewayProxyResponse{
-		StatusCode: http.StatusOK,
-		Headers:    responseHeaders,
-		Body:       string(jsonData),
+	for name, values := range w.header {
+		response.Headers[name] = values[0]
+		response.MultiValueHeaders[name] = values
 	}
-
 	return response, nil
 }
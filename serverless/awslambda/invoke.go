@@ -0,0 +1,24 @@
+package awslambda
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// An InvokeResult is the outcome of a Lambda invocation.
+type InvokeResult struct {
+	Response interface{} `json:"response"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// Invoke handles a Lambda event locally, e.g. to test events without deploying the function.
+//
+// Like the Lambda runtime, the response is dropped when the handler returns
+// an error, e.g. "Unauthorized" for a denied authorizer event.
+func Invoke(ctx context.Context, event []byte) InvokeResult {
+	response, err := HandleLambdaEvent(ctx, json.RawMessage(event))
+	if err != nil {
+		return InvokeResult{Error: err.Error()}
+	}
+	return InvokeResult{Response: response}
+}
@@ -0,0 +1,180 @@
+package awslambda
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+// Resources of the authorizer policy.
+const (
+	policyResourceExact    = "exact"    // the invoked method or route only.
+	policyResourceWildcard = "wildcard" // every method and route of the stage, so a cached Allow serves all routes.
+)
+
+// Effects of an authorizer rule.
+const (
+	ruleEffectAllow = "allow" // the route requires one of the claim values.
+	ruleEffectDeny  = "deny"  // the route rejects any of the claim values.
+)
+
+var ErrInvalidAuthorizerRule = errors.New("invalid authorizer rule")
+
+// An authorizerRule restricts a route by a claim of the token.
+//
+// A rule without a claim applies to every token, so a deny rule without a
+// claim closes the route.
+type authorizerRule core.AuthorizerRule
+
+// Get the configured authorizer rules, validated with the configuration snapshot.
+func authorizerRules() ([]authorizerRule, error) {
+	config := core.GetConfig().Lambda
+	if config.AuthorizerRulesErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAuthorizerRule, config.AuthorizerRulesErr)
+	}
+
+	rules := make([]authorizerRule, 0, len(config.AuthorizerRules))
+	for _, rule := range config.AuthorizerRules {
+		rules = append(rules, authorizerRule(rule))
+	}
+	return rules, nil
+}
+
+// Get the method and path prefix of the rule route, where the method is "*" for any method.
+func (rule authorizerRule) methodAndPath() (string, string) {
+	route := strings.TrimSpace(rule.Route)
+	if method, path, found := strings.Cut(route, " "); found {
+		return strings.ToUpper(method), strings.TrimSpace(path)
+	}
+	return "*", route
+}
+
+// Check if the rule applies to a method and path.
+func (rule authorizerRule) matches(method string, path string) bool {
+	ruleMethod, rulePath := rule.methodAndPath()
+	if ruleMethod != "*" && !strings.EqualFold(ruleMethod, method) {
+		return false
+	}
+	return strings.HasPrefix(strings.ToLower(path), strings.ToLower(rulePath))
+}
+
+// Check if the rule denies a token.
+//
+// Claims of unverified tokens are never trusted, so rules with a claim deny
+// them.
+func (rule authorizerRule) denies(token jwt.Token) bool {
+	if rule.Claim == "" {
+		return rule.Effect == ruleEffectDeny
+	}
+	if token == nil || !core.GetConfig().JWT.VerifyEnabled {
+		return true
+	}
+
+	hasValue := false
+	if value, ok := token.Get(rule.Claim); ok {
+		claimValues := strings.Fields(claimString(value, " "))
+		for _, ruleValue := range rule.Values {
+			for _, claimValue := range claimValues {
+				if claimValue == ruleValue {
+					hasValue = true
+				}
+			}
+		}
+	}
+	if rule.Effect == ruleEffectAllow {
+		return !hasValue
+	}
+	return hasValue
+}
+
+// Get the resource of a rule, relative to the stage ARN of a method or route ARN.
+func (rule authorizerRule) resourceArn(arn string) string {
+	method, path := rule.methodAndPath()
+	return stageArn(arn) + "/" + method + path + "*"
+}
+
+// Check if a token is denied on a method and path by the rules.
+func deniedByRules(rules []authorizerRule, token jwt.Token, method string, path string) bool {
+	for _, rule := range rules {
+		if rule.matches(method, path) && rule.denies(token) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the ARN of the stage, i.e. arn:aws:execute-api:{region}:{account}:{apiId}/{stage}.
+func stageArn(arn string) string {
+	parts := strings.SplitN(arn, "/", 3)
+	if len(parts) < 2 {
+		return arn
+	}
+	return parts[0] + "/" + parts[1]
+}
+
+// Get the method from an API Gateway method or route ARN.
+func methodFromArn(arn string) string {
+	parts := strings.SplitN(arn, "/", 4)
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[2]
+}
+
+// Generate the statements of an IAM policy, scoped by "lambda.authorizer.policy_resource".
+//
+// A wildcard policy is cached by API Gateway for every route, so a token
+// denied by rules still gets the stage-wide Allow, and the rules that deny the
+// token are added as explicit Deny statements, which win over the Allow. Only
+// blocked and invalid tokens are denied on the whole stage.
+func generatePolicyStatements(decision authorizerDecision, arn string) []events.IAMPolicyStatement {
+	if core.GetConfig().Lambda.AuthorizerPolicyResource != policyResourceWildcard {
+		return []events.IAMPolicyStatement{newPolicyStatement(decision.Effect, arn)}
+	}
+	if decision.Effect != "Allow" && !decision.IsRouteDenied {
+		return []events.IAMPolicyStatement{newPolicyStatement(decision.Effect, stageArn(arn)+"/*")}
+	}
+
+	statements := []events.IAMPolicyStatement{newPolicyStatement("Allow", stageArn(arn)+"/*")}
+	for _, rule := range decision.rules {
+		if rule.denies(decision.token) {
+			statements = append(statements, newPolicyStatement("Deny", rule.resourceArn(arn)))
+		}
+	}
+	return statements
+}
+
+func newPolicyStatement(effect string, resourceArn string) events.IAMPolicyStatement {
+	return events.IAMPolicyStatement{
+		Action:   []string{"execute-api:Invoke"},
+		Effect:   effect,
+		Resource: []string{resourceArn},
+	}
+}
+
+// Get the TTL hint of a response, for how long API Gateway may cache it.
+//
+// The hint is "lambda.authorizer.cache_ttl_sec", the window after a block in
+// which a cached Allow is still served, capped by the remaining lifetime of
+// the token.
+func cacheTTLHint(token jwt.Token) int {
+	ttl := core.GetConfig().Lambda.AuthorizerCacheTTLSec
+	if token == nil || token.Expiration().IsZero() {
+		return ttl
+	}
+
+	remaining := int(time.Until(token.Expiration()).Seconds())
+	if remaining < 0 {
+		remaining = 0
+	}
+	if remaining < ttl {
+		return remaining
+	}
+	return ttl
+}
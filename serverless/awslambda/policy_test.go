@@ -0,0 +1,146 @@
+package awslambda
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+const testMethodArn = "arn:aws:execute-api:us-east-1:123456789012:api-id/main/GET/orders/42"
+
+func Test_AuthorizerTokenEvent_WildcardResource_DenyStatements(t *testing.T) {
+	setupMockRedis(t)
+	setupVerifiedClaims(t)
+	setupAuthorizerRules(t, `[
+		{"route": "/orders", "claim": "scp", "values": ["orders:read"], "effect": "allow"},
+		{"route": "DELETE /orders", "claim": "scp", "values": ["orders:admin"], "effect": "allow"}
+	]`)
+	viper.Set(core.OptStr_LambdaAuthorizerPolicyResource, policyResourceWildcard)
+
+	event := events.APIGatewayCustomAuthorizerRequest{Type: "TOKEN", AuthorizationToken: signTestToken(t), MethodArn: testMethodArn}
+	response, err := handleAuthorizerTypeTokenEvent(context.Background(), event)
+	statements := response.PolicyDocument.Statement
+	if err != nil || len(statements) != 2 {
+		t.Fatalf("Expected a wildcard Allow and one Deny: response=%+v, err=%v", response, err)
+	}
+	if statements[0].Effect != "Allow" || statements[0].Resource[0] != "arn:aws:execute-api:us-east-1:123456789012:api-id/main/*" {
+		t.Errorf("Unexpected Allow statement: statement=%+v", statements[0])
+	}
+	if statements[1].Effect != "Deny" || statements[1].Resource[0] != "arn:aws:execute-api:us-east-1:123456789012:api-id/main/DELETE/orders*" {
+		t.Errorf("Unexpected Deny statement: statement=%+v", statements[1])
+	}
+}
+
+func Test_AuthorizerTokenEvent_WildcardRouteDenied_OtherRoutesAllowed(t *testing.T) {
+	setupMockRedis(t)
+	setupVerifiedClaims(t)
+	setupAuthorizerRules(t, `[{"route": "GET /orders", "claim": "scp", "values": ["orders:admin"], "effect": "allow"}]`)
+	viper.Set(core.OptStr_LambdaAuthorizerPolicyResource, policyResourceWildcard)
+
+	event := events.APIGatewayCustomAuthorizerRequest{Type: "TOKEN", AuthorizationToken: signTestToken(t), MethodArn: testMethodArn}
+	response, err := handleAuthorizerTypeTokenEvent(context.Background(), event)
+	statements := response.PolicyDocument.Statement
+	if err != nil || len(statements) != 2 || response.Context["route_denied"] != true {
+		t.Fatalf("Expected a wildcard Allow and one Deny: response=%+v, err=%v", response, err)
+	}
+	if statements[0].Effect != "Allow" || statements[0].Resource[0] != "arn:aws:execute-api:us-east-1:123456789012:api-id/main/*" {
+		t.Errorf("Expected the other routes to be allowed: statement=%+v", statements[0])
+	}
+	if statements[1].Effect != "Deny" || statements[1].Resource[0] != "arn:aws:execute-api:us-east-1:123456789012:api-id/main/GET/orders*" {
+		t.Errorf("Expected the invoked route to be denied: statement=%+v", statements[1])
+	}
+}
+
+func Test_AuthorizerTokenEvent_WildcardBlocked_StageDenied(t *testing.T) {
+	setupMockRedis(t)
+	setupAuthorizerRules(t, `[]`)
+	viper.Set(core.OptStr_LambdaAuthorizerPolicyResource, policyResourceWildcard)
+
+	event := events.APIGatewayCustomAuthorizerRequest{Type: "TOKEN", AuthorizationToken: "foobar", MethodArn: testMethodArn}
+	response, _ := handleAuthorizerTypeTokenEvent(context.Background(), event)
+	statements := response.PolicyDocument.Statement
+	if len(statements) != 1 || statements[0].Effect != "Deny" || statements[0].Resource[0] != "arn:aws:execute-api:us-east-1:123456789012:api-id/main/*" {
+		t.Errorf("Expected the stage to be denied: response=%+v", response)
+	}
+}
+
+func Test_AuthorizerTokenEvent_MissingScope_RouteDenied(t *testing.T) {
+	setupMockRedis(t)
+	setupVerifiedClaims(t)
+	setupAuthorizerRules(t, `[{"route": "GET /orders", "claim": "scp", "values": ["orders:admin"], "effect": "allow"}]`)
+
+	event := events.APIGatewayCustomAuthorizerRequest{Type: "TOKEN", AuthorizationToken: signTestToken(t), MethodArn: testMethodArn}
+	response, err := handleAuthorizerTypeTokenEvent(context.Background(), event)
+	if err != nil || response.PolicyDocument.Statement[0].Effect != "Deny" || response.Context["route_denied"] != true {
+		t.Errorf("Expected a Deny without an error: response=%+v, err=%v", response, err)
+	}
+}
+
+func Test_AuthorizerTokenEvent_UnverifiedClaims_RouteDenied(t *testing.T) {
+	setupMockRedis(t)
+	setupAuthorizerRules(t, `[{"route": "/orders", "claim": "sub", "values": ["1234567890"], "effect": "allow"}]`)
+
+	event := events.APIGatewayCustomAuthorizerRequest{Type: "TOKEN", AuthorizationToken: testTokenString, MethodArn: testMethodArn}
+	response, err := handleAuthorizerTypeTokenEvent(context.Background(), event)
+	if err != nil || response.PolicyDocument.Statement[0].Effect != "Deny" {
+		t.Errorf("Expected unverified claims to be denied: response=%+v, err=%v", response, err)
+	}
+}
+
+func Test_AuthorizerTokenEvent_InvalidRules_Error(t *testing.T) {
+	setupMockRedis(t)
+	setupAuthorizerRules(t, `[{"route": "orders", "effect": "maybe"}]`)
+
+	event := events.APIGatewayCustomAuthorizerRequest{Type: "TOKEN", AuthorizationToken: testTokenString, MethodArn: testMethodArn}
+	_, err := handleAuthorizerTypeTokenEvent(context.Background(), event)
+	if err != ErrLambdaAuth500AuthConfig {
+		t.Errorf("Expected a configuration error: err=%v", err)
+	}
+}
+
+func Test_cacheTTLHint_TokenLifetime_Capped(t *testing.T) {
+	core.InitConfigDefaults()
+
+	token, _ := jwt.NewBuilder().Expiration(time.Now().Add(60 * time.Second)).Build()
+	if ttl := cacheTTLHint(token); ttl > 60 || ttl < 58 {
+		t.Errorf("Expected the remaining token lifetime: ttl=%d", ttl)
+	}
+	token, _ = jwt.NewBuilder().Expiration(time.Now().Add(time.Hour)).Build()
+	if ttl := cacheTTLHint(token); ttl != 300 {
+		t.Errorf("Expected the configured TTL: ttl=%d", ttl)
+	}
+	token, _ = jwt.NewBuilder().Expiration(time.Now().Add(-time.Hour)).Build()
+	if ttl := cacheTTLHint(token); ttl != 0 {
+		t.Errorf("Expected no caching of expired tokens: ttl=%d", ttl)
+	}
+}
+
+func setupAuthorizerRules(t *testing.T, rules string) {
+	viper.Set(core.OptStr_LambdaAuthorizerRules, rules)
+	t.Cleanup(func() {
+		viper.Set(core.OptStr_LambdaAuthorizerRules, nil)
+		viper.Set(core.OptStr_LambdaAuthorizerPolicyResource, nil)
+	})
+}
+
+func Test_authorizerRules_ConfigList_Success(t *testing.T) {
+	core.InitConfigDefaults()
+	setupAuthorizerRules(t, "")
+	viper.Set(core.OptStr_LambdaAuthorizerRules, []interface{}{
+		map[string]interface{}{"route": "GET /orders", "claim": "groups", "values": []interface{}{"admins"}, "effect": "Deny"},
+	})
+
+	rules, err := authorizerRules()
+	if err != nil || len(rules) != 1 || rules[0].Effect != ruleEffectDeny || rules[0].Values[0] != "admins" {
+		t.Fatalf("Expected the configured rule: rules=%+v, err=%v", rules, err)
+	}
+	if !rules[0].matches("get", "/Orders/42") || rules[0].matches("POST", "/orders") {
+		t.Errorf("Unexpected route matching: rule=%+v", rules[0])
+	}
+}
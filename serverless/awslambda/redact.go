@@ -0,0 +1,70 @@
+package awslambda
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+// Copies of the Lambda events for logging, with tokens and credentials masked.
+
+func redactAuthorizerTypeRequestEvent(event events.APIGatewayV2CustomAuthorizerV2Request) events.APIGatewayV2CustomAuthorizerV2Request {
+	event.Headers = core.RedactHeaderMap(event.Headers)
+	event.IdentitySource = redactValues(event.IdentitySource)
+	event.Cookies = redactValues(event.Cookies)
+	return event
+}
+
+func redactAuthorizerTypeTokenEvent(event events.APIGatewayCustomAuthorizerRequest) events.APIGatewayCustomAuthorizerRequest {
+	event.AuthorizationToken = core.RedactToken(event.AuthorizationToken)
+	return event
+}
+
+func redactHttpProxyEventV1(event events.APIGatewayProxyRequest) events.APIGatewayProxyRequest {
+	event.Headers = core.RedactHeaderMap(event.Headers)
+	event.MultiValueHeaders = redactMultiValueHeaders(event.MultiValueHeaders)
+	return event
+}
+
+func redactALBTargetGroupEvent(event events.ALBTargetGroupRequest) events.ALBTargetGroupRequest {
+	event.Headers = core.RedactHeaderMap(event.Headers)
+	event.MultiValueHeaders = redactMultiValueHeaders(event.MultiValueHeaders)
+	return event
+}
+
+func redactHttpProxyEventV2(event events.APIGatewayV2HTTPRequest) events.APIGatewayV2HTTPRequest {
+	event.Headers = core.RedactHeaderMap(event.Headers)
+	event.Cookies = redactValues(event.Cookies)
+	return event
+}
+
+func redactFunctionURLEvent(event events.LambdaFunctionURLRequest) events.LambdaFunctionURLRequest {
+	event.Headers = core.RedactHeaderMap(event.Headers)
+	event.Cookies = redactValues(event.Cookies)
+	return event
+}
+
+func redactMultiValueHeaders(headers map[string][]string) map[string][]string {
+	if headers == nil {
+		return nil
+	}
+	redacted := make(map[string][]string, len(headers))
+	for name, values := range headers {
+		if core.IsSecretHeader(name) {
+			values = redactValues(values)
+		}
+		redacted[name] = values
+	}
+	return redacted
+}
+
+func redactValues(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	redacted := make([]string, len(values))
+	for i := range values {
+		redacted[i] = core.RedactedValue
+	}
+	return redacted
+}
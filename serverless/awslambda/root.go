@@ -6,12 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 
 	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/secrets"
+	"github.com/divergentcodes/jwtblock/internal/tracing"
 )
 
 // General error messages returned by the web service.
@@ -30,68 +31,126 @@ var (
 )
 
 // Entry point to handle the incoming events.
+//
+// The event type is detected in a single pass, then the event is decoded once
+// into its type.
 func HandleLambdaEvent(ctx context.Context, event json.RawMessage) (interface{}, error) {
 	logger := core.GetLogger()
 
 	logger.Debugw(
 		"Lambda Event",
 		"func", "awslambda.HandleLambdaEvent",
-		"rawEvent", event,
+		"size", len(event),
 		"rawContext", ctx,
 	)
+	validateRedisClient(ctx)
 
-	// Check event type.
-	var authorizerTypeRequestEvent events.APIGatewayV2CustomAuthorizerV2Request
-	var authorizerTypeTokenEvent events.APIGatewayCustomAuthorizerRequest
-	var httpProxyRequestEventV1 events.APIGatewayProxyRequest
-	var httpProxyRequestEventV2 events.APIGatewayV2HTTPRequest
-	if err := json.Unmarshal(event, &authorizerTypeRequestEvent); err == nil && strings.ToLower(authorizerTypeRequestEvent.Type) == "request" {
-		logger.Debugw(
-			"Lambda Event",
-			"func", "awslambda.HandleLambdaEvent",
-			"type", "authorizerTypeRequestEvent",
-			"event", authorizerTypeRequestEvent,
-			"context", ctx,
-		)
-		return handleAuthorizerTypeRequestEvent(ctx, authorizerTypeRequestEvent)
-	} else if err := json.Unmarshal(event, &authorizerTypeTokenEvent); err == nil && strings.ToLower(authorizerTypeTokenEvent.Type) == "token" {
-		logger.Debugw(
-			"Lambda Event",
-			"func", "awslambda.HandleLambdaEvent",
-			"type", "authorizerTypeTokenEvent",
-			"event", authorizerTypeTokenEvent,
-			"context", ctx,
-		)
-		return handleAuthorizerTypeTokenEvent(ctx, authorizerTypeTokenEvent)
-	} else if err := json.Unmarshal(event, &httpProxyRequestEventV1); err == nil && httpProxyRequestEventV1.HTTPMethod != "" {
-		logger.Debugw(
-			"Lambda Event",
-			"func", "awslambda.HandleLambdaEvent",
-			"type", "httpProxyRequestEventV1",
-			"event", httpProxyRequestEventV1,
-			"context", ctx,
-		)
-		return handleHttpProxyEventV1(ctx, httpProxyRequestEventV1)
-	} else if err := json.Unmarshal(event, &httpProxyRequestEventV2); err == nil && httpProxyRequestEventV2.Version == "2.0" {
-		logger.Debugw(
-			"Lambda Event",
-			"func", "awslambda.HandleLambdaEvent",
-			"type", "httpProxyRequestEventV2",
-			"event", httpProxyRequestEventV2,
-			"context", ctx,
-		)
-		return handleHttpProxyEventV2(ctx, httpProxyRequestEventV2)
+	eventType := detectEventType(event)
+	switch eventType {
+	case eventTypeAuthorizerRequest:
+		var authorizerTypeRequestEvent events.APIGatewayV2CustomAuthorizerV2Request
+		if err := json.Unmarshal(event, &authorizerTypeRequestEvent); err != nil {
+			break
+		}
+		logEvent(ctx, eventType, redactAuthorizerTypeRequestEvent(authorizerTypeRequestEvent))
+		return traceEvent(ctx, eventType, authorizerTypeRequestEvent.Headers, func(ctx context.Context) (interface{}, error) {
+			return handleAuthorizerTypeRequestEvent(ctx, authorizerTypeRequestEvent)
+		})
+	case eventTypeAuthorizerToken:
+		var authorizerTypeTokenEvent events.APIGatewayCustomAuthorizerRequest
+		if err := json.Unmarshal(event, &authorizerTypeTokenEvent); err != nil {
+			break
+		}
+		logEvent(ctx, eventType, redactAuthorizerTypeTokenEvent(authorizerTypeTokenEvent))
+		return traceEvent(ctx, eventType, nil, func(ctx context.Context) (interface{}, error) {
+			return handleAuthorizerTypeTokenEvent(ctx, authorizerTypeTokenEvent)
+		})
+	case eventTypeALBTargetGroup:
+		var albTargetGroupEvent events.ALBTargetGroupRequest
+		if err := json.Unmarshal(event, &albTargetGroupEvent); err != nil {
+			break
+		}
+		logEvent(ctx, eventType, redactALBTargetGroupEvent(albTargetGroupEvent))
+		return traceEvent(ctx, eventType, albTargetGroupEvent.Headers, func(ctx context.Context) (interface{}, error) {
+			return handleALBTargetGroupEvent(ctx, albTargetGroupEvent)
+		})
+	case eventTypeHttpProxyV1:
+		var httpProxyRequestEventV1 events.APIGatewayProxyRequest
+		if err := json.Unmarshal(event, &httpProxyRequestEventV1); err != nil {
+			break
+		}
+		logEvent(ctx, eventType, redactHttpProxyEventV1(httpProxyRequestEventV1))
+		return traceEvent(ctx, eventType, httpProxyRequestEventV1.Headers, func(ctx context.Context) (interface{}, error) {
+			return handleHttpProxyEventV1(ctx, httpProxyRequestEventV1)
+		})
+	case eventTypeFunctionURL:
+		var functionURLEvent events.LambdaFunctionURLRequest
+		if err := json.Unmarshal(event, &functionURLEvent); err != nil {
+			break
+		}
+		logEvent(ctx, eventType, redactFunctionURLEvent(functionURLEvent))
+		return traceEvent(ctx, eventType, functionURLEvent.Headers, func(ctx context.Context) (interface{}, error) {
+			return handleFunctionURLEvent(ctx, functionURLEvent)
+		})
+	case eventTypeHttpProxyV2:
+		var httpProxyRequestEventV2 events.APIGatewayV2HTTPRequest
+		if err := json.Unmarshal(event, &httpProxyRequestEventV2); err != nil {
+			break
+		}
+		logEvent(ctx, eventType, redactHttpProxyEventV2(httpProxyRequestEventV2))
+		return traceEvent(ctx, eventType, httpProxyRequestEventV2.Headers, func(ctx context.Context) (interface{}, error) {
+			return handleHttpProxyEventV2(ctx, httpProxyRequestEventV2)
+		})
+	case eventTypeSQS:
+		var sqsEvent events.SQSEvent
+		if err := json.Unmarshal(event, &sqsEvent); err != nil {
+			break
+		}
+		// Messages may carry tokens, so only the number of records is logged.
+		logEvent(ctx, eventType, map[string]int{"records": len(sqsEvent.Records)})
+		return traceEvent(ctx, eventType, nil, func(ctx context.Context) (interface{}, error) {
+			return handleSQSEvent(ctx, sqsEvent)
+		})
+	case eventTypeSNS:
+		var snsEvent events.SNSEvent
+		if err := json.Unmarshal(event, &snsEvent); err != nil {
+			break
+		}
+		logEvent(ctx, eventType, map[string]int{"records": len(snsEvent.Records)})
+		return traceEvent(ctx, eventType, nil, func(ctx context.Context) (interface{}, error) {
+			return handleSNSEvent(ctx, snsEvent)
+		})
+	case eventTypeEventBridge:
+		var eventBridgeEvent events.CloudWatchEvent
+		if err := json.Unmarshal(event, &eventBridgeEvent); err != nil {
+			break
+		}
+		logEvent(ctx, eventType, map[string]string{"source": eventBridgeEvent.Source, "detailType": eventBridgeEvent.DetailType})
+		return traceEvent(ctx, eventType, nil, func(ctx context.Context) (interface{}, error) {
+			return handleEventBridgeEvent(ctx, eventBridgeEvent)
+		})
 	}
 
 	logger.Warnw(
 		"Unknown Lambda event type",
 		"func", "awslambda.HandleLambdaEvent",
-		"event", event,
+		"size", len(event),
 		"context", ctx,
 	)
 	return nil, fmt.Errorf("invalid Lambda event type")
 }
 
+// Log a redacted Lambda event.
+func logEvent(ctx context.Context, eventType string, event interface{}) {
+	core.GetLogger().Debugw(
+		"Lambda Event",
+		"func", "awslambda.HandleLambdaEvent",
+		"type", eventType,
+		"event", event,
+		"context", ctx,
+	)
+}
+
 // Check if the runtime environment is AWS Lambda.
 func IsAwsLambdaEnv() bool {
 	_, isLambda := os.LookupEnv("AWS_LAMBDA_FUNCTION_NAME")
@@ -100,5 +159,39 @@ func IsAwsLambdaEnv() bool {
 
 // Start the Lambda handler.
 func Start() {
+	logger := core.GetLogger()
+
+	if err := core.ReloadLogger(); err != nil {
+		logger.Errorw(
+			"failed to configure logging",
+			"func", "awslambda.Start",
+			"err", err.Error(),
+		)
+	}
+	logger = core.GetLogger()
+
+	if err := tracing.Init(context.Background()); err != nil {
+		logger.Errorw(
+			"failed to initialize tracing",
+			"func", "awslambda.Start",
+			"err", err.Error(),
+		)
+	}
+	// Unresolved references would be used as the secrets themselves, so fail the init phase.
+	if err := secrets.Init(context.Background()); err != nil {
+		logger.Fatalw(
+			"failed to resolve secrets",
+			"func", "awslambda.Start",
+			"err", err.Error(),
+		)
+	}
+	if err := core.ActivateConfig(); err != nil {
+		logger.Fatalw(
+			"invalid configuration",
+			"func", "awslambda.Start",
+			"err", err.Error(),
+		)
+	}
+	warmUp(context.Background())
 	lambda.Start(HandleLambdaEvent)
 }
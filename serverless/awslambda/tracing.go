@@ -0,0 +1,36 @@
+package awslambda
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/tracing"
+)
+
+// Handle a Lambda event in a span, continuing the W3C trace context from the event headers.
+//
+// Spans are exported before the invocation returns, since the execution
+// environment may be frozen afterwards.
+func traceEvent(ctx context.Context, eventType string, headers map[string]string, handle func(context.Context) (interface{}, error)) (interface{}, error) {
+	logger := core.GetLogger()
+
+	ctx = tracing.ExtractHeaderMap(ctx, headers)
+	ctx, span := tracing.Start(ctx, "lambda "+eventType,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("jwtblock.lambda.event", eventType)),
+	)
+	response, err := handle(ctx)
+	tracing.End(span, err)
+
+	if flushErr := tracing.Flush(ctx); flushErr != nil {
+		logger.Warnw(
+			"failed to export spans",
+			"func", "awslambda.traceEvent",
+			"err", flushErr.Error(),
+		)
+	}
+	return response, err
+}
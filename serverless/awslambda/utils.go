@@ -15,7 +15,7 @@ func getHeaderValue(headers map[string]string, headerName string) (string, bool)
 				"Found header",
 				"func", "awslambda.getHeaderValue",
 				"headerName", headerName,
-				"headers", headers,
+				"headers", core.RedactHeaderMap(headers),
 			)
 			return value, true
 		}
@@ -25,7 +25,7 @@ func getHeaderValue(headers map[string]string, headerName string) (string, bool)
 		"Did not find header",
 		"func", "awslambda.getHeaderValue",
 		"headerName", headerName,
-		"headers", headers,
+		"headers", core.RedactHeaderMap(headers),
 	)
 
 	return "", false
@@ -63,3 +63,14 @@ func getBearerToken(headers map[string]string) (string, error) {
 	}
 	return value, nil
 }
+
+// Get the resource path from an API Gateway method ARN.
+//
+// The ARN has the format arn:aws:execute-api:{region}:{account}:{apiId}/{stage}/{method}/{path}.
+func routeFromMethodArn(methodArn string) string {
+	parts := strings.SplitN(methodArn, "/", 4)
+	if len(parts) < 4 {
+		return ""
+	}
+	return "/" + parts[3]
+}
@@ -0,0 +1,79 @@
+package awslambda
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/divergentcodes/jwtblock/internal/cache"
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+)
+
+// Time of the last invocation, to detect when the execution environment was frozen.
+var (
+	lastInvocationMu sync.Mutex
+	lastInvocation   time.Time
+)
+
+// Initialize the handler eagerly during the Lambda init phase.
+//
+// The init phase runs with boosted CPU before the first invocation is billed,
+// so the logger, Redis client, Redis connection and web handler are created
+// there instead of during the first invocation.
+func warmUp(ctx context.Context) {
+	logger := core.GetLogger()
+
+	redisClient := cache.GetRedisClient()
+	if err := pingRedis(ctx, redisClient); err != nil {
+		logger.Warnw(
+			"failed to connect to Redis during init",
+			"func", "awslambda.warmUp",
+			"err", err.Error(),
+		)
+	}
+	getWebHandler()
+
+	lastInvocationMu.Lock()
+	lastInvocation = time.Now()
+	lastInvocationMu.Unlock()
+}
+
+// Validate the Redis connections before an invocation, after the execution environment was idle.
+//
+// Lambda freezes idle execution environments, and Redis or a NAT may drop
+// their connections meanwhile. The Redis client is replaced when a ping
+// fails, instead of failing the invocation on a stale connection.
+func validateRedisClient(ctx context.Context) {
+	lastInvocationMu.Lock()
+	idle := time.Since(lastInvocation)
+	lastInvocation = time.Now()
+	lastInvocationMu.Unlock()
+
+	validateIdle := core.GetConfig().Lambda.RedisValidateIdleSec
+	if validateIdle < 0 || idle < time.Duration(validateIdle)*time.Second {
+		return
+	}
+
+	redisClient := cache.GetRedisClient()
+	if err := pingRedis(ctx, redisClient); err != nil {
+		metrics.Int("lambda_redis_reconnects_total").Add(1)
+		core.GetLogger().Warnw(
+			"reconnecting stale Redis client",
+			"func", "awslambda.validateRedisClient",
+			"idle", idle.String(),
+			"err", err.Error(),
+		)
+		cache.ReconnectRedisClient()
+	}
+}
+
+// Ping Redis, with the configured timeout.
+func pingRedis(ctx context.Context, redisClient redis.UniversalClient) error {
+	timeout := time.Duration(core.GetConfig().Lambda.RedisPingTimeoutMs) * time.Millisecond
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return redisClient.Ping(ctx).Err()
+}
@@ -0,0 +1,92 @@
+package awslambda
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/cache"
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+func Test_validateRedisClient_StaleClient_Reconnects(t *testing.T) {
+	core.InitConfigDefaults()
+	redisServer := miniredis.RunT(t)
+	staleClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	cache.SetRedisClient(staleClient)
+
+	// The new client connects to the configured Redis.
+	viper.Set(core.OptStr_RedisHost, redisServer.Host())
+	viper.Set(core.OptStr_RedisPort, redisServer.Port())
+	t.Cleanup(func() {
+		viper.Set(core.OptStr_RedisHost, nil)
+		viper.Set(core.OptStr_RedisPort, nil)
+	})
+	setLastInvocation(t, time.Now().Add(-time.Hour))
+
+	validateRedisClient(context.Background())
+
+	redisClient := cache.GetRedisClient()
+	if redisClient == staleClient {
+		t.Fatal("Expected the stale client to be replaced")
+	}
+	if err := redisClient.Ping(context.Background()).Err(); err != nil {
+		t.Errorf("Expected the new client to connect, got %s", err.Error())
+	}
+}
+
+func Test_validateRedisClient_RecentInvocation_KeepsClient(t *testing.T) {
+	core.InitConfigDefaults()
+	staleClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	cache.SetRedisClient(staleClient)
+	setLastInvocation(t, time.Now())
+
+	validateRedisClient(context.Background())
+
+	if cache.GetRedisClient() != staleClient {
+		t.Error("Expected the client to be kept without validation")
+	}
+}
+
+func Test_validateRedisClient_Disabled_KeepsClient(t *testing.T) {
+	core.InitConfigDefaults()
+	staleClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	cache.SetRedisClient(staleClient)
+	viper.Set(core.OptStr_LambdaRedisValidateIdleSec, -1)
+	t.Cleanup(func() { viper.Set(core.OptStr_LambdaRedisValidateIdleSec, nil) })
+	setLastInvocation(t, time.Now().Add(-time.Hour))
+
+	validateRedisClient(context.Background())
+
+	if cache.GetRedisClient() != staleClient {
+		t.Error("Expected the client to be kept with validation disabled")
+	}
+}
+
+func Test_validateRedisClient_HealthyClient_KeepsClient(t *testing.T) {
+	setupMockRedis(t)
+	healthyClient := cache.GetRedisClient()
+	setLastInvocation(t, time.Now().Add(-time.Hour))
+
+	validateRedisClient(context.Background())
+
+	if cache.GetRedisClient() != healthyClient {
+		t.Error("Expected the healthy client to be kept")
+	}
+}
+
+func setLastInvocation(t *testing.T, at time.Time) {
+	lastInvocationMu.Lock()
+	previous := lastInvocation
+	lastInvocation = at
+	lastInvocationMu.Unlock()
+	t.Cleanup(func() {
+		lastInvocationMu.Lock()
+		lastInvocation = previous
+		lastInvocationMu.Unlock()
+	})
+}
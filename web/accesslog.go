@@ -0,0 +1,184 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+// Outcomes of a request, reported in the access log.
+const (
+	outcomeAllowed     = "allowed"
+	outcomeBlocked     = "blocked"
+	outcomeUnblocked   = "unblocked"
+	outcomeNotBlocked  = "not_blocked"
+	outcomeInvalid     = "invalid"
+	outcomeUnavailable = "unavailable"
+	outcomeOK          = "ok"
+	outcomeDenied      = "denied"
+	outcomeError       = "error"
+)
+
+// Length of the token hash prefix in the access log.
+const accessLogHashLen = 12
+
+// Incoming request IDs that are safe to propagate, including base64 API Gateway IDs and ALB trace IDs.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:=+/-]{1,128}$`)
+
+// A requestInfo collects details about a request for the access log.
+type requestInfo struct {
+	id        string
+	outcome   string
+	tokenHash string
+}
+
+type requestInfoKey struct{}
+
+// Get the details of the request, or nil outside of the web handler.
+func getRequestInfo(r *http.Request) *requestInfo {
+	info, _ := r.Context().Value(requestInfoKey{}).(*requestInfo)
+	return info
+}
+
+// Get the ID of the request, or an empty string outside of the web handler.
+func getRequestID(r *http.Request) string {
+	if info := getRequestInfo(r); info != nil {
+		return info.id
+	}
+	return ""
+}
+
+// Record the outcome of the request for the access log.
+func setRequestOutcome(r *http.Request, outcome string) {
+	if info := getRequestInfo(r); info != nil {
+		info.outcome = outcome
+	}
+}
+
+// Record the token of the request for the access log, as a hash prefix.
+func setRequestToken(r *http.Request, tokenString string) {
+	if info := getRequestInfo(r); info != nil {
+		info.tokenHash = core.RedactToken(tokenString)
+	}
+}
+
+// Record the token hash of the request for the access log, as a prefix.
+func setRequestHash(r *http.Request, hashString string) {
+	if info := getRequestInfo(r); info != nil && len(hashString) > accessLogHashLen {
+		info.tokenHash = "sha256:" + hashString[:accessLogHashLen] + "..."
+	}
+}
+
+// Log one line per request, and assign every request an ID.
+//
+// The request ID is propagated from the request ID header, or generated, and
+// echoed in the response header and in StandardResponse bodies.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		config := core.GetConfig().HTTP
+		requestIDHeader := config.HeaderRequestID
+
+		info := &requestInfo{id: r.Header.Get(requestIDHeader)}
+		if !validRequestID.MatchString(info.id) {
+			info.id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, info.id)
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info)))
+
+		if !config.AccessLogEnabled {
+			return
+		}
+		if info.outcome == "" {
+			info.outcome = outcomeFromStatus(recorder.status)
+		}
+		core.GetLogger().Infow(
+			"access",
+			"func", "web.logRequests",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", recorder.status,
+			"latency_ms", float64(time.Since(start).Microseconds())/1000,
+			"outcome", info.outcome,
+			"token_hash", info.tokenHash,
+			"client_ip", clientIP(r),
+			"request_id", info.id,
+		)
+	})
+}
+
+// Get a generic outcome from the status code, for requests without a specific outcome.
+func outcomeFromStatus(status int) string {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return outcomeError
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return outcomeDenied
+	case status >= http.StatusBadRequest:
+		return outcomeInvalid
+	}
+	return outcomeOK
+}
+
+// Generate a random request ID.
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// Get the IP address of the client.
+//
+// When the request comes from a trusted proxy, the X-Forwarded-For chain is
+// followed back to the first address that is not a trusted proxy.
+func clientIP(r *http.Request) string {
+	ip := peerIP(r)
+	proxies := core.GetConfig().HTTP.TrustedProxies
+	if !isTrustedProxy(ip, proxies) {
+		return ip
+	}
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		forwardedIP := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(forwardedIP) == nil {
+			break
+		}
+		ip = forwardedIP
+		if !isTrustedProxy(ip, proxies) {
+			break
+		}
+	}
+	return ip
+}
+
+// Get the IP address of the direct peer of the request, e.g. a proxy.
+func peerIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+func isTrustedProxy(ip string, proxies []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range proxies {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+func Test_Handler_AccessLog_Success(t *testing.T) {
+	setupMockRedis()
+	defer teardownMockRedis()
+	logs := setupAccessLog(t)
+
+	tokenString := generateTokenStringHS256(30)
+	request := httptest.NewRequest("GET", "/blocklist/check", nil)
+	request.RemoteAddr = "10.0.0.2:5000"
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	request.Header.Add("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	request.Header.Add("X-Request-Id", "req-123")
+	w := httptest.NewRecorder()
+
+	Handler().ServeHTTP(w, request)
+
+	if w.Header().Get("X-Request-Id") != "req-123" {
+		t.Errorf("Expected the request ID to be echoed: header=%s", w.Header().Get("X-Request-Id"))
+	}
+	entries := logs.FilterMessage("access").All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected one access log line: entries=%d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	expected := map[string]interface{}{
+		"method":     "GET",
+		"path":       "/blocklist/check",
+		"status":     int64(http.StatusOK),
+		"outcome":    outcomeAllowed,
+		"token_hash": core.RedactToken(tokenString),
+		"client_ip":  "203.0.113.7",
+		"request_id": "req-123",
+	}
+	for key, value := range expected {
+		if fields[key] != value {
+			t.Errorf("Unexpected access log field: key=%s, actual=%v, expected=%v", key, fields[key], value)
+		}
+	}
+	if _, ok := fields["latency_ms"]; !ok {
+		t.Errorf("Expected latency in the access log: fields=%v", fields)
+	}
+}
+
+func Test_Handler_RequestIDGenerated_InStandardResponse(t *testing.T) {
+	logs := setupAccessLog(t)
+
+	// An unsafe incoming request ID is replaced.
+	request := httptest.NewRequest("POST", "/blocklist/check", nil)
+	request.Header.Add("X-Request-Id", "bad id\n")
+	w := httptest.NewRecorder()
+
+	Handler().ServeHTTP(w, request)
+
+	var result StandardResponse
+	_ = json.NewDecoder(w.Body).Decode(&result)
+	requestID := w.Header().Get("X-Request-Id")
+	if len(requestID) != 32 || result.RequestID != requestID {
+		t.Errorf("Expected a generated request ID in the header and body: header=%s, body=%s", requestID, result.RequestID)
+	}
+	entries := logs.FilterMessage("access").All()
+	if len(entries) != 1 || entries[0].ContextMap()["outcome"] != outcomeInvalid {
+		t.Errorf("Expected an invalid outcome for the wrong method: entries=%v", entries)
+	}
+}
+
+func Test_clientIP_TrustedProxies_Success(t *testing.T) {
+	core.InitConfigDefaults()
+	viper.Set(core.OptStr_HttpTrustedProxies, "10.0.0.0/8, 192.168.1.1")
+	defer viper.Set(core.OptStr_HttpTrustedProxies, "")
+
+	cases := []struct {
+		remoteAddr string
+		forwarded  string
+		expected   string
+	}{
+		{"203.0.113.9:1234", "198.51.100.1", "203.0.113.9"},                       // untrusted peer, header ignored.
+		{"10.1.2.3:1234", "198.51.100.1, 192.168.1.1", "198.51.100.1"},            // chain of trusted proxies.
+		{"10.1.2.3:1234", "198.51.100.2, 198.51.100.1, 10.0.0.1", "198.51.100.1"}, // spoofed entries before the first untrusted address.
+		{"10.1.2.3:1234", "", "10.1.2.3"},                                         // no header.
+		{"10.1.2.3:1234", "foobar", "10.1.2.3"},                                   // malformed header.
+	}
+	for _, c := range cases {
+		request := httptest.NewRequest("GET", "/", nil)
+		request.RemoteAddr = c.remoteAddr
+		if c.forwarded != "" {
+			request.Header.Set("X-Forwarded-For", c.forwarded)
+		}
+		if actual := clientIP(request); actual != c.expected {
+			t.Errorf("Unexpected client IP: remote=%s, forwarded=%s, actual=%s, expected=%s", c.remoteAddr, c.forwarded, actual, c.expected)
+		}
+	}
+}
+
+// Capture logs, with the access log and trusted local proxies enabled.
+func setupAccessLog(t *testing.T) *observer.ObservedLogs {
+	core.InitConfigDefaults()
+	viper.Set(core.OptStr_HttpTrustedProxies, "10.0.0.0/8")
+	viper.Set(core.OptStr_JwtParseEnabled, true)
+	viper.Set(core.OptStr_JwtValidateEnabled, true)
+	viper.Set(core.OptStr_JwtVerifyEnabled, false)
+
+	observed, logs := observer.New(zap.InfoLevel)
+	previous := core.GetLogger()
+	core.SetLogger(zap.New(observed).Sugar())
+	t.Cleanup(func() {
+		core.SetLogger(previous)
+		viper.Set(core.OptStr_HttpTrustedProxies, "")
+	})
+	return logs
+}
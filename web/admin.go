@@ -0,0 +1,58 @@
+package web
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+// Check if the request is allowed to use the admin API.
+//
+// The admin API is disabled unless http.admin.enabled is set, and the request
+// must present one of the configured API keys in the API key header. Without
+// configured keys, every request is denied.
+func isAdminRequest(r *http.Request) (bool, error) {
+	config := core.GetConfig().HTTP
+	if !config.AdminEnabled {
+		return false, ErrAdminApiDisabled
+	}
+
+	headerName := http.CanonicalHeaderKey(config.HeaderApiKey)
+	apiKey := r.Header.Get(headerName)
+	if apiKey == "" || len(config.AdminApiKeys) == 0 {
+		return false, ErrMissingApiKey
+	}
+
+	for _, value := range config.AdminApiKeys {
+		if subtle.ConstantTimeCompare([]byte(value), []byte(apiKey)) == 1 {
+			return true, nil
+		}
+	}
+	return false, ErrInvalidApiKey
+}
+
+// Write an error response if the request is not allowed to use the admin API.
+//
+// Returns true if the request may continue.
+func requireAdminRequest(w http.ResponseWriter, r *http.Request) bool {
+	logger := core.GetLogger()
+
+	allowed, err := isAdminRequest(r)
+	if allowed {
+		return true
+	}
+
+	logger.Warnw(
+		"admin API request denied",
+		"func", "web.requireAdminRequest",
+		"path", r.URL.Path,
+		"err", err.Error(),
+	)
+	httpStatus := http.StatusUnauthorized
+	if err == ErrAdminApiDisabled {
+		httpStatus = http.StatusForbidden
+	}
+	WriteErrorResponse(r, w, err.Error(), httpStatus)
+	return false
+}
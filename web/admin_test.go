@@ -0,0 +1,229 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/blocklist"
+	"github.com/divergentcodes/jwtblock/internal/cache"
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+)
+
+func Test_Admin_Disabled_Error(t *testing.T) {
+	setupMockRedis()
+	setupAdminApi(false, "")
+
+	// Build the request.
+	request := httptest.NewRequest("GET", "/blocklist/status", nil)
+	w := httptest.NewRecorder()
+
+	// Issue HTTP request to handler.
+	jwtStatus(w, request)
+
+	// Process the result.
+	response := w.Result()
+	body, _ := io.ReadAll(response.Body)
+	var result StandardResponse
+	err := json.Unmarshal([]byte(body), &result)
+	if err != nil {
+		t.Errorf("Expected request to pass: err=%s", err)
+	}
+	expectedStatus := 403
+	if response.StatusCode != expectedStatus || result.Message != ErrAdminApiDisabled.Error() {
+		t.Errorf(
+			"Expected ErrAdminApiDisabled: status=%d, message='%s'",
+			response.StatusCode,
+			result.Message,
+		)
+	}
+
+	teardownMockRedis()
+}
+
+func Test_Admin_InvalidApiKey_Error(t *testing.T) {
+	setupMockRedis()
+	setupAdminApi(true, "foo,bar")
+
+	apiKeys := map[string]error{
+		"":    ErrMissingApiKey,
+		"baz": ErrInvalidApiKey,
+	}
+	for apiKey, expectedErr := range apiKeys {
+		// Build the request.
+		request := httptest.NewRequest("POST", "/blocklist/flush", nil)
+		if apiKey != "" {
+			request.Header.Add("X-Jwtblock-Api-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+
+		// Issue HTTP request to handler.
+		jwtFlush(w, request)
+
+		// Process the result.
+		response := w.Result()
+		body, _ := io.ReadAll(response.Body)
+		var result StandardResponse
+		err := json.Unmarshal([]byte(body), &result)
+		if err != nil {
+			t.Errorf("Expected request to pass: err=%s", err)
+		}
+		expectedStatus := 401
+		if response.StatusCode != expectedStatus || result.Message != expectedErr.Error() {
+			t.Errorf(
+				"Unexpected admin response: apiKey='%s', status=%d, message='%s'",
+				apiKey,
+				response.StatusCode,
+				result.Message,
+			)
+		}
+	}
+
+	teardownMockRedis()
+}
+
+func Test_Admin_NoApiKeys_Error(t *testing.T) {
+	setupMockRedis()
+	setupAdminApi(true, "")
+
+	for _, apiKey := range []string{"", "foo"} {
+		request := httptest.NewRequest("POST", "/blocklist/flush", nil)
+		if apiKey != "" {
+			request.Header.Add("X-Jwtblock-Api-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		jwtFlush(w, request)
+
+		var result StandardResponse
+		_ = json.NewDecoder(w.Result().Body).Decode(&result)
+		if w.Result().StatusCode != 401 || result.Message != ErrMissingApiKey.Error() {
+			t.Errorf(
+				"Expected ErrMissingApiKey: apiKey='%s', status=%d, message='%s'",
+				apiKey,
+				w.Result().StatusCode,
+				result.Message,
+			)
+		}
+	}
+
+	teardownMockRedis()
+}
+
+func Test_Admin_BlockHashUnblockList_Success(t *testing.T) {
+	setupMockRedis()
+	setupAdminApi(true, "foo,bar")
+
+	tokenString := generateTokenStringHS256(30)
+	hashString := crypto.Sha256FromString(tokenString)
+
+	// Block by hash with an explicit TTL.
+	request := httptest.NewRequest("POST", "/blocklist/block", nil)
+	request.Header.Add("X-Jwtblock-Api-Key", "bar")
+	request.Header.Add("X-Jwtblock-Sha256", hashString)
+	request.Header.Add("X-Jwtblock-Ttl", "60")
+	w := httptest.NewRecorder()
+	jwtBlock(w, request)
+
+	var blockResult blocklist.BlockResult
+	body, _ := io.ReadAll(w.Result().Body)
+	err := json.Unmarshal([]byte(body), &blockResult)
+	if err != nil || !blockResult.IsNew || blockResult.TTL != 60 {
+		t.Errorf(
+			"Expected hash to be blocked: status=%d, message='%s', ttl=%d",
+			w.Result().StatusCode,
+			blockResult.Message,
+			blockResult.TTL,
+		)
+	}
+
+	// List the blocked hashes.
+	request = httptest.NewRequest("GET", "/blocklist/list", nil)
+	request.Header.Add("X-Jwtblock-Api-Key", "foo")
+	w = httptest.NewRecorder()
+	jwtList(w, request)
+
+	var listResult blocklist.ListResult
+	body, _ = io.ReadAll(w.Result().Body)
+	err = json.Unmarshal([]byte(body), &listResult)
+	if err != nil || listResult.Size != 1 || listResult.TokenHashes[0] != hashString {
+		t.Errorf("Expected one listed hash: status=%d, size=%d", w.Result().StatusCode, listResult.Size)
+	}
+
+	// Unblock by token.
+	request = httptest.NewRequest("POST", "/blocklist/unblock", nil)
+	request.Header.Add("X-Jwtblock-Api-Key", "foo")
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	w = httptest.NewRecorder()
+	jwtUnblock(w, request)
+
+	var unblockResult blocklist.UnblockResult
+	body, _ = io.ReadAll(w.Result().Body)
+	err = json.Unmarshal([]byte(body), &unblockResult)
+	if err != nil || !unblockResult.IsUnblocked || unblockResult.Message != blocklist.SuccessTokenUnblocked {
+		t.Errorf(
+			"Expected token to be unblocked: status=%d, message='%s'",
+			w.Result().StatusCode,
+			unblockResult.Message,
+		)
+	}
+
+	teardownMockRedis()
+}
+
+func Test_Block_TTLWithoutAdmin_Error(t *testing.T) {
+	setupMockRedis()
+	setupAdminApi(false, "")
+
+	tokenString := generateTokenStringHS256(30)
+
+	// Build the request.
+	request := httptest.NewRequest("POST", "/blocklist/block", nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	request.Header.Add("X-Jwtblock-Ttl", "0")
+	w := httptest.NewRecorder()
+
+	// Issue HTTP request to handler.
+	jwtBlock(w, request)
+
+	// Process the result.
+	response := w.Result()
+	expectedStatus := 403
+	if response.StatusCode != expectedStatus {
+		t.Errorf(
+			"Unexpected status code: actual=%d, expected=%d",
+			response.StatusCode,
+			expectedStatus,
+		)
+	}
+
+	teardownMockRedis()
+}
+
+func Test_Admin_UnblockStoreUnavailable_Error(t *testing.T) {
+	setupMockRedis()
+	setupAdminApi(true, "foo")
+	cache.GetRedisClient().Close()
+
+	request := httptest.NewRequest("POST", "/blocklist/unblock", nil)
+	request.Header.Add("X-Jwtblock-Api-Key", "foo")
+	request.Header.Add("X-Jwtblock-Sha256", crypto.Sha256FromString("blocked"))
+	w := httptest.NewRecorder()
+	jwtUnblock(w, request)
+
+	var result StandardResponse
+	_ = json.NewDecoder(w.Result().Body).Decode(&result)
+	if w.Result().StatusCode != http.StatusServiceUnavailable || !result.IsError {
+		t.Errorf("Expected a store error: status=%d, message='%s'", w.Result().StatusCode, result.Message)
+	}
+}
+
+func setupAdminApi(enabled bool, apiKeys string) {
+	viper.Set(core.OptStr_HttpAdminEnabled, enabled)
+	viper.Set(core.OptStr_HttpAdminApiKeys, apiKeys)
+}
@@ -0,0 +1,46 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/divergentcodes/jwtblock/internal/audit"
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+// Maximum length of the reason recorded in the audit log.
+const auditReasonMaxLen = 256
+
+// Start an audit event for a mutation of the hash by the request.
+//
+// selfToken is the token of a request blocking itself, e.g. on logout, which
+// identifies the actor. It is empty for admin requests.
+func newAuditEvent(r *http.Request, hashString string, selfToken string) audit.Event {
+	reason := r.Header.Get(core.GetConfig().HTTP.HeaderReason)
+	if len(reason) > auditReasonMaxLen {
+		reason = reason[:auditReasonMaxLen]
+	}
+	return audit.Event{
+		Hash:      hashString,
+		Actor:     auditActor(r, selfToken),
+		SourceIP:  clientIP(r),
+		Reason:    reason,
+		RequestID: getRequestID(r),
+	}
+}
+
+// Get the identity of the caller for the audit log.
+//
+// Admin requests are identified by their API key, other requests by the
+// subject of their token, and anything else by "admin".
+func auditActor(r *http.Request, selfToken string) string {
+	apiKey := r.Header.Get(core.GetConfig().HTTP.HeaderApiKey)
+	if apiKey != "" {
+		if allowed, _ := isAdminRequest(r); allowed {
+			return audit.ApiKeyActor(apiKey)
+		}
+	}
+	if selfToken != "" {
+		return audit.TokenActor(selfToken)
+	}
+	return "admin"
+}
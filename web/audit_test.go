@@ -0,0 +1,77 @@
+package web
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/audit"
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+)
+
+func Test_Audit_BlockUnblock_Recorded(t *testing.T) {
+	setupMockRedis()
+	setupAdminApi(true, "foo")
+	sink := setupAuditSink(t)
+	tokenString := generateTokenStringHS256(30)
+
+	// A token blocking itself.
+	request := httptest.NewRequest("POST", "/blocklist/block", nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	request.Header.Add("X-Jwtblock-Reason", "logout")
+	jwtBlock(httptest.NewRecorder(), request)
+
+	// An admin unblocking it.
+	request = httptest.NewRequest("POST", "/blocklist/unblock", nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	request.Header.Add("X-Jwtblock-Api-Key", "foo")
+	request.Header.Add("X-Jwtblock-Reason", "mistake")
+	jwtUnblock(httptest.NewRecorder(), request)
+
+	if len(sink.events) != 2 {
+		t.Fatalf("Expected both mutations to be recorded: events=%+v", sink.events)
+	}
+	block, unblock := sink.events[0], sink.events[1]
+	tokenHash := crypto.TokenHash(tokenString)
+	if block.Operation != audit.OperationBlock || block.Result != audit.ResultBlocked || block.Hash != tokenHash ||
+		block.Actor != "token" || block.Reason != "logout" || block.SourceIP == "" {
+		t.Errorf("Unexpected block event: event=%+v", block)
+	}
+	if unblock.Operation != audit.OperationUnblock || unblock.Result != audit.ResultUnblocked || unblock.Hash != tokenHash ||
+		unblock.Actor != audit.ApiKeyActor("foo") || unblock.Reason != "mistake" {
+		t.Errorf("Unexpected unblock event: event=%+v", unblock)
+	}
+
+	teardownMockRedis()
+}
+
+// A testAuditSink keeps audit events in memory.
+type testAuditSink struct {
+	events []audit.Event
+}
+
+func (s *testAuditSink) Write(event audit.Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *testAuditSink) Query(filter audit.Filter) ([]audit.Event, error) {
+	return s.events, nil
+}
+
+func (s *testAuditSink) Close() error {
+	return nil
+}
+
+func setupAuditSink(t *testing.T) *testAuditSink {
+	sink := &testAuditSink{}
+	viper.Set(core.OptStr_AuditEnabled, true)
+	audit.SetSink(sink)
+	t.Cleanup(func() {
+		viper.Set(core.OptStr_AuditEnabled, nil)
+	})
+	return sink
+}
@@ -1,22 +1,26 @@
 package web
 
 import (
+	"errors"
 	"net/http"
-	"strings"
+	"strconv"
 
+	"github.com/swaggest/openapi-go"
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/divergentcodes/jwtblock/internal/audit"
 	"github.com/divergentcodes/jwtblock/internal/blocklist"
 	"github.com/divergentcodes/jwtblock/internal/cache"
 	"github.com/divergentcodes/jwtblock/internal/core"
-	"github.com/swaggest/openapi-go"
-	"github.com/swaggest/openapi-go/openapi3"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
 )
 
 // Handler for /blocklist/block
 func jwtBlock(w http.ResponseWriter, r *http.Request) {
 	logger := core.GetLogger()
 	var result *blocklist.BlockResult
-	var tokenString string
-	var err, tokenErr error
+	var tokenString, hashString string
+	var err, tokenErr, hashErr error
 
 	// Handle CORS preflight requests.
 	if r.Method == http.MethodOptions {
@@ -30,42 +34,91 @@ func jwtBlock(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get token from headers.
+	// Get token or hash from headers.
 	tokenString, tokenErr = parseTokenFromHeader(r)
+	if tokenErr != nil {
+		hashString, hashErr = parseHashFromHeader(r)
+	}
 
-	// No token found in headers. Unauthorized.
-	if tokenErr != nil || tokenString == "" {
+	// No token or hash found in headers. Unauthorized.
+	if tokenErr != nil && hashErr != nil {
 		msg := "failed to get token from request headers"
 		logger.Errorw(
 			msg,
-			"func", "web.jwtCheck",
+			"func", "web.jwtBlock",
 			"tokenError", tokenErr.Error(),
 		)
 		DebugLogIncomingRequest(r)
 		WriteErrorResponse(r, w, msg, http.StatusUnauthorized)
 		return
 	}
-	logger.Debugw(
-		"Received value to add",
-		"func", "web.jwtBlock",
-		"token", tokenString,
-	)
+
+	// Blocking by hash or with an explicit TTL is an admin operation.
+	ttl, hasTTL, err := parseTTLFromHeader(r)
+	if err != nil {
+		WriteErrorResponse(r, w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if (hashString != "" || hasTTL) && !requireAdminRequest(w, r) {
+		return
+	}
 
 	// Add value to the blocklist.
 	redisDB := cache.GetRedisClient()
-	result, err = blocklist.Block(redisDB, tokenString)
+	if tokenString != "" {
+		logger.Debugw(
+			"Received value to add",
+			"func", "web.jwtBlock",
+			"token", tokenString,
+		)
+		setRequestToken(r, tokenString)
+		result, err = blocklist.BlockWithTTL(redisDB, tokenString, ttl)
+		// Without an explicit TTL, the token blocks itself, e.g. on logout.
+		selfToken := tokenString
+		if hasTTL {
+			selfToken = ""
+		}
+		audit.Block(newAuditEvent(r, crypto.TokenHash(tokenString), selfToken), result, err)
+	} else {
+		logger.Debugw(
+			"Received hash to add",
+			"func", "web.jwtBlock",
+			"sha256", hashString,
+		)
+		setRequestHash(r, hashString)
+		result, err = blocklist.BlockBySha256WithTTL(redisDB, hashString, ttl)
+		audit.Block(newAuditEvent(r, hashString, ""), result, err)
+	}
 	if err != nil {
-		// Error is either token format (400), or server issue (500).
+		// Error is either token format (400), or an unavailable store (503).
 		httpStatus := http.StatusBadRequest
-		if strings.Contains(err.Error(), "Cache") {
-			httpStatus = http.StatusInternalServerError
+		if errors.Is(err, blocklist.ErrStoreUnavailable) {
+			httpStatus = http.StatusServiceUnavailable
 		}
 		WriteErrorResponse(r, w, err.Error(), httpStatus)
 		return
 	}
 
 	// Response.
-	WriteSuccessResponse(r, w, result.Message, http.StatusOK)
+	setRequestOutcome(r, outcomeBlocked)
+	WriteJSONResponse(r, w, result, http.StatusOK)
+}
+
+// Get an explicit TTL in seconds from the TTL header, if it exists.
+//
+// Returns -1 when the header is not set, to use the default TTL behavior.
+func parseTTLFromHeader(r *http.Request) (int, bool, error) {
+	ttlHeaderName := http.CanonicalHeaderKey(core.GetConfig().HTTP.HeaderTTL)
+	ttlHeaderValue := r.Header.Get(ttlHeaderName)
+	if ttlHeaderValue == "" {
+		return -1, false, nil
+	}
+
+	ttl, err := strconv.Atoi(ttlHeaderValue)
+	if err != nil || ttl < 0 {
+		return -1, false, ErrInvalidTTL
+	}
+	return ttl, true, nil
 }
 
 // OpenAPI documentation generation.
@@ -81,6 +134,7 @@ func blockGenerateOpenAPI(reflector *openapi3.Reflector) {
 	for _, status := range statusCodes {
 		blockOp.AddRespStructure(new(blocklist.BlockResult), func(cu *openapi.ContentUnit) { cu.HTTPStatus = status })
 	}
+	blockOp.AddRespStructure(new(StandardResponse), func(cu *openapi.ContentUnit) { cu.HTTPStatus = http.StatusServiceUnavailable })
 
 	err = reflector.AddOperation(blockOp)
 	if err != nil {
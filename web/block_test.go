@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
@@ -15,6 +16,7 @@ import (
 	"github.com/lestrrat-go/jwx/v2/jwt"
 
 	"github.com/divergentcodes/jwtblock/internal/blocklist"
+	"github.com/divergentcodes/jwtblock/internal/cache"
 )
 
 func Test_Block_ValidTokenAndHash_Success(t *testing.T) {
@@ -214,6 +216,22 @@ func Test_Block_InvalidHttpMethod_Error(t *testing.T) {
 	teardownMockRedis()
 }
 
+func Test_Block_StoreUnavailable_Error(t *testing.T) {
+	setupMockRedis()
+	cache.GetRedisClient().Close()
+
+	request := httptest.NewRequest("POST", "/blocklist/block", nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", generateTokenStringHS256(30)))
+	w := httptest.NewRecorder()
+	jwtBlock(w, request)
+
+	var result StandardResponse
+	_ = json.NewDecoder(w.Result().Body).Decode(&result)
+	if w.Result().StatusCode != http.StatusServiceUnavailable || !result.IsError {
+		t.Errorf("Expected a store error: status=%d, message='%s'", w.Result().StatusCode, result.Message)
+	}
+}
+
 func generateTokenStringHS256(ttlSeconds int) string {
 	// Generate the token headers.
 	tokenHeaders := jws.NewHeaders()
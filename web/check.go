@@ -2,11 +2,11 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"net/url"
 
-	"github.com/spf13/viper"
 	"github.com/swaggest/openapi-go"
 	"github.com/swaggest/openapi-go/openapi3"
 
@@ -21,8 +21,9 @@ func jwtCheck(w http.ResponseWriter, r *http.Request) {
 	var result blocklist.CheckResult
 	var err, tokenErr, hashErr error
 
-	httpStatusAllow := viper.GetInt(core.OptStr_HttpStatusOnAllowed)
-	httpStatusDeny := viper.GetInt(core.OptStr_HttpStatusOnBlocked)
+	config := core.GetConfig().HTTP
+	httpStatusAllow := config.StatusOnAllowed
+	httpStatusDeny := config.StatusOnBlocked
 
 	// Handle CORS preflight requests.
 	if r.Method == http.MethodOptions {
@@ -53,6 +54,7 @@ func jwtCheck(w http.ResponseWriter, r *http.Request) {
 			"hashError", hashErr.Error(),
 		)
 		DebugLogIncomingRequest(r)
+		setRequestOutcome(r, outcomeInvalid)
 		WriteErrorResponse(r, w, msg, httpStatusDeny)
 		return
 	}
@@ -66,47 +68,51 @@ func jwtCheck(w http.ResponseWriter, r *http.Request) {
 			"func", "web.jwtCheck",
 			"token", tokenString,
 		)
-		result, err = blocklist.CheckByJwt(redisClient, tokenString)
+		setRequestToken(r, tokenString)
+		result, err = blocklist.CheckByJwtForRoute(r.Context(), redisClient, tokenString, parseProtectedRoute(r))
 	} else if hashString != "" {
 		// Lookup by SHA256 hash.
-		hashHeaderName := viper.GetString(core.OptStr_HttpHeaderSha256)
+		hashHeaderName := config.HeaderSha256
 		msg := fmt.Sprintf("found sha256 hash in %s HTTP header", hashHeaderName)
 		logger.Debugw(
 			msg,
 			"func", "web.jwtCheck",
 			"sha256", hashString,
 		)
-		result, err = blocklist.CheckBySha256(redisClient, hashString)
+		setRequestHash(r, hashString)
+		result, err = blocklist.CheckBySha256ForRoute(r.Context(), redisClient, hashString, parseProtectedRoute(r))
 	}
 
 	// Handle lookup errors.
 	if err != nil {
-		if strings.Contains(err.Error(), "Cache") {
-			// Cache error.
+		if errors.Is(err, blocklist.ErrStoreUnavailable) {
+			// Store unavailable, and the fail-closed policy applies.
 			logger.Errorw(
 				"web token check error",
 				"func", "web.jwtCheck",
 				"err", err.Error(),
 			)
-			err = blocklist.ErrMisconfiguredCache
-			httpStatus := http.StatusInternalServerError
-			WriteErrorResponse(r, w, err.Error(), httpStatus)
+			setRequestOutcome(r, outcomeUnavailable)
+			WriteErrorResponse(r, w, err.Error(), http.StatusServiceUnavailable)
 		} else {
 			// Operational error.
+			setRequestOutcome(r, outcomeInvalid)
 			WriteErrorResponse(r, w, err.Error(), httpStatusDeny)
 		}
 		return
 	}
 
-	// Valid response.
+	// Valid response, possibly allowed by the fail-open policy.
 	allowed, allowedOrigin := isCorsRequestAllowed(r)
 	if allowed {
 		addCorsResponseHeaders(w, allowedOrigin)
 	}
 	w.Header().Set("Content-Type", "application/json")
 	if result.IsBlocked {
+		setRequestOutcome(r, outcomeBlocked)
 		w.WriteHeader(httpStatusDeny)
 	} else {
+		setRequestOutcome(r, outcomeAllowed)
 		w.WriteHeader(httpStatusAllow)
 	}
 	err = json.NewEncoder(w).Encode(result)
@@ -126,6 +132,25 @@ func jwtCheck(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Get the route protected by a forward auth check, from the proxy headers.
+//
+// The proxy headers are only read from "http.trusted_proxies", since a client
+// could otherwise pick the store failure policy of another route. Falls back
+// to the request path when the check is called directly.
+func parseProtectedRoute(r *http.Request) string {
+	if !isTrustedProxy(peerIP(r), core.GetConfig().HTTP.TrustedProxies) {
+		return r.URL.Path
+	}
+	for _, header := range []string{"X-Forwarded-Uri", "X-Original-Uri", "X-Original-Url"} {
+		if value := r.Header.Get(header); value != "" {
+			if u, err := url.Parse(value); err == nil {
+				return u.Path
+			}
+		}
+	}
+	return r.URL.Path
+}
+
 // OpenAPI documentation generation.
 func checkGenerateOpenAPI(reflector *openapi3.Reflector) {
 	logger := core.GetLogger()
@@ -139,6 +164,7 @@ func checkGenerateOpenAPI(reflector *openapi3.Reflector) {
 	for _, status := range statusCodes {
 		checkOp.AddRespStructure(new(blocklist.CheckResult), func(cu *openapi.ContentUnit) { cu.HTTPStatus = status })
 	}
+	checkOp.AddRespStructure(new(StandardResponse), func(cu *openapi.ContentUnit) { cu.HTTPStatus = http.StatusServiceUnavailable })
 
 	err = reflector.AddOperation(checkOp)
 	if err != nil {
@@ -4,10 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/spf13/viper"
+
 	"github.com/divergentcodes/jwtblock/internal/blocklist"
+	"github.com/divergentcodes/jwtblock/internal/cache"
+	"github.com/divergentcodes/jwtblock/internal/core"
 	"github.com/divergentcodes/jwtblock/internal/crypto"
 )
 
@@ -102,3 +107,74 @@ func Test_Check_ValidHashAllowed_Success(t *testing.T) {
 
 	teardownMockRedis()
 }
+
+func Test_Check_StoreUnavailable_FailPolicy(t *testing.T) {
+	setupMockRedis()
+	tokenString := generateTokenStringHS256(30)
+
+	// Succeed once, so the stale-allowed window applies.
+	request := httptest.NewRequest("GET", "/blocklist/check", nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	w := httptest.NewRecorder()
+	jwtCheck(w, request)
+	cache.GetRedisClient().Close()
+
+	// The httptest peer forwards the protected route.
+	viper.Set(core.OptStr_HttpTrustedProxies, "192.0.2.1")
+	defer viper.Set(core.OptStr_HttpTrustedProxies, "")
+
+	policies := map[string]int{
+		"closed": http.StatusServiceUnavailable,
+		"open":   http.StatusOK,
+	}
+	for policy, expectedStatus := range policies {
+		viper.Set(core.OptStr_StoreFailurePolicy, "closed")
+		viper.Set(core.OptStr_StoreFailureRoutes, map[string]string{"/public": policy})
+
+		request := httptest.NewRequest("GET", "/blocklist/check", nil)
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+		request.Header.Add("X-Forwarded-Uri", "/public/page?foo=bar")
+		w := httptest.NewRecorder()
+		jwtCheck(w, request)
+
+		if w.Result().StatusCode != expectedStatus {
+			t.Errorf(
+				"Unexpected status code: policy=%s, actual=%d, expected=%d",
+				policy,
+				w.Result().StatusCode,
+				expectedStatus,
+			)
+		}
+	}
+	viper.Set(core.OptStr_StoreFailureRoutes, map[string]string{})
+}
+
+func Test_Check_SpoofedForwardedUri_FailClosed(t *testing.T) {
+	setupMockRedis()
+	tokenString := generateTokenStringHS256(30)
+
+	// Succeed once, so the stale-allowed window applies.
+	request := httptest.NewRequest("GET", "/blocklist/check", nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	jwtCheck(httptest.NewRecorder(), request)
+	cache.GetRedisClient().Close()
+
+	viper.Set(core.OptStr_HttpTrustedProxies, "10.0.0.0/8")
+	viper.Set(core.OptStr_StoreFailurePolicy, "closed")
+	viper.Set(core.OptStr_StoreFailureRoutes, map[string]string{"/public": "open"})
+	defer viper.Set(core.OptStr_HttpTrustedProxies, "")
+	defer viper.Set(core.OptStr_StoreFailureRoutes, map[string]string{})
+
+	for _, header := range []string{"X-Forwarded-Uri", "X-Original-Uri", "X-Original-Url"} {
+		request := httptest.NewRequest("GET", "/blocklist/check", nil)
+		request.RemoteAddr = "203.0.113.9:1234"
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+		request.Header.Add(header, "/public/page")
+		w := httptest.NewRecorder()
+		jwtCheck(w, request)
+
+		if w.Result().StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Expected the %s header of an untrusted client to be ignored, got status %d", header, w.Result().StatusCode)
+		}
+	}
+}
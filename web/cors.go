@@ -7,7 +7,6 @@ import (
 	"strings"
 
 	"github.com/divergentcodes/jwtblock/internal/core"
-	"github.com/spf13/viper"
 )
 
 // Write a CORS preflight response depending on the Origin header.
@@ -34,7 +33,7 @@ func WriteCorsPreflightResponse(r *http.Request, w http.ResponseWriter) {
 
 // Add CORS allow response headers for given origin.
 func addCorsResponseHeaders(w http.ResponseWriter, origin string) {
-	corsMaxSeconds := viper.GetViper().GetInt(core.OptStr_HttpCorsMaxSeconds)
+	corsMaxSeconds := core.GetConfig().HTTP.CorsMaxSeconds
 
 	w.Header().Set("Access-Control-Allow-Origin", origin)
 	w.Header().Set("Access-Control-Allow-Credentials", "true")
@@ -123,8 +122,5 @@ func isCorsAllowedOrigin(origin string) string {
 
 // Get the list of allowed CORS origins.
 func getCorsAllowedOrigins() []string {
-	allowedOrigins := viper.GetString(core.OptStr_HttpCorsAllowedOrigins)
-	allowedOriginList := strings.Split(allowedOrigins, ",")
-
-	return allowedOriginList
+	return core.GetConfig().HTTP.CorsAllowedOrigins
 }
@@ -0,0 +1,71 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/swaggest/openapi-go"
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/divergentcodes/jwtblock/internal/audit"
+	"github.com/divergentcodes/jwtblock/internal/blocklist"
+	"github.com/divergentcodes/jwtblock/internal/cache"
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+// Handler for /blocklist/flush
+func jwtFlush(w http.ResponseWriter, r *http.Request) {
+	logger := core.GetLogger()
+
+	// Handle CORS preflight requests.
+	if r.Method == http.MethodOptions {
+		WriteCorsPreflightResponse(r, w)
+		return
+	}
+
+	// Only allow POST.
+	if r.Method != http.MethodPost {
+		WriteErrorResponse(r, w, ErrHttpMethodOnlyPost.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !requireAdminRequest(w, r) {
+		return
+	}
+
+	redisDB := cache.GetRedisClient()
+	result, err := blocklist.Flush(redisDB)
+	audit.Flush(newAuditEvent(r, "", ""), result, err)
+	if err != nil {
+		logger.Errorw(
+			"web flush error",
+			"func", "web.jwtFlush",
+			"err", err.Error(),
+		)
+		WriteErrorResponse(r, w, blocklist.ErrMisconfiguredCache.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	WriteJSONResponse(r, w, result, http.StatusOK)
+}
+
+// OpenAPI documentation generation.
+func flushGenerateOpenAPI(reflector *openapi3.Reflector) {
+	logger := core.GetLogger()
+
+	flushOp, err := reflector.NewOperationContext(http.MethodPost, "/blocklist/flush")
+	if err != nil {
+		logger.Fatalw(err.Error())
+	}
+
+	flushOp.AddRespStructure(new(blocklist.FlushResult), func(cu *openapi.ContentUnit) { cu.HTTPStatus = http.StatusOK })
+	flushOp.AddSecurity(adminSecurityName)
+	statusCodes := []int{http.StatusUnauthorized, http.StatusForbidden}
+	for _, status := range statusCodes {
+		flushOp.AddRespStructure(new(StandardResponse), func(cu *openapi.ContentUnit) { cu.HTTPStatus = status })
+	}
+
+	err = reflector.AddOperation(flushOp)
+	if err != nil {
+		logger.Fatalw(err.Error())
+	}
+}
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/swaggest/openapi-go"
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+// A LogLevelRequest changes the log level at runtime.
+type LogLevelRequest struct {
+	Level string `json:"level" enum:"debug,info,warn,error"` // the new log level.
+}
+
+// A LogLevelResult contains the current log level.
+type LogLevelResult struct {
+	Level string `json:"level"` // the current log level.
+}
+
+// Handler for /log/level
+func logLevel(w http.ResponseWriter, r *http.Request) {
+	logger := core.GetLogger()
+
+	// Only allow GET and POST.
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		WriteErrorResponse(r, w, ErrHttpMethodOnlyGetPost.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !requireAdminRequest(w, r) {
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var request LogLevelRequest
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err == nil {
+			err = core.SetLogLevel(request.Level)
+		}
+		if err != nil {
+			WriteErrorResponse(r, w, core.ErrInvalidLogLevel.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.Infow(
+			"log level changed",
+			"func", "web.logLevel",
+			"level", core.LogLevel(),
+		)
+	}
+
+	WriteJSONResponse(r, w, LogLevelResult{Level: core.LogLevel()}, http.StatusOK)
+}
+
+// OpenAPI documentation generation.
+func logLevelGenerateOpenAPI(reflector *openapi3.Reflector) {
+	logger := core.GetLogger()
+
+	getOp, err := reflector.NewOperationContext(http.MethodGet, "/log/level")
+	if err != nil {
+		logger.Fatalw(err.Error())
+	}
+	getOp.AddRespStructure(new(LogLevelResult), func(cu *openapi.ContentUnit) { cu.HTTPStatus = http.StatusOK })
+	getOp.AddSecurity(adminSecurityName)
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		getOp.AddRespStructure(new(StandardResponse), func(cu *openapi.ContentUnit) { cu.HTTPStatus = status })
+	}
+	err = reflector.AddOperation(getOp)
+	if err != nil {
+		logger.Fatalw(err.Error())
+	}
+
+	setOp, err := reflector.NewOperationContext(http.MethodPost, "/log/level")
+	if err != nil {
+		logger.Fatalw(err.Error())
+	}
+	setOp.AddReqStructure(new(LogLevelRequest))
+	setOp.AddRespStructure(new(LogLevelResult), func(cu *openapi.ContentUnit) { cu.HTTPStatus = http.StatusOK })
+	setOp.AddSecurity(adminSecurityName)
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden} {
+		setOp.AddRespStructure(new(StandardResponse), func(cu *openapi.ContentUnit) { cu.HTTPStatus = status })
+	}
+	err = reflector.AddOperation(setOp)
+	if err != nil {
+		logger.Fatalw(err.Error())
+	}
+}
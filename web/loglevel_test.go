@@ -0,0 +1,93 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+func Test_LogLevel_Set_Success(t *testing.T) {
+	setupAdminApi(true, "foo")
+	defer core.SetLogLevel("info")
+
+	// Build the request.
+	request := httptest.NewRequest("POST", "/log/level", strings.NewReader(`{"level":"warn"}`))
+	request.Header.Add("X-Jwtblock-Api-Key", "foo")
+	w := httptest.NewRecorder()
+
+	// Issue HTTP request to handler.
+	logLevel(w, request)
+
+	// Process the result.
+	response := w.Result()
+	body, _ := io.ReadAll(response.Body)
+	var result LogLevelResult
+	err := json.Unmarshal([]byte(body), &result)
+	if err != nil {
+		t.Errorf("Expected request to pass: err=%s", err)
+	}
+	if response.StatusCode != 200 || result.Level != "warn" || core.LogLevel() != "warn" {
+		t.Errorf(
+			"Expected log level to change: status=%d, level=%s, current=%s",
+			response.StatusCode,
+			result.Level,
+			core.LogLevel(),
+		)
+	}
+
+	// The level can be read back.
+	request = httptest.NewRequest("GET", "/log/level", nil)
+	request.Header.Add("X-Jwtblock-Api-Key", "foo")
+	w = httptest.NewRecorder()
+	logLevel(w, request)
+	body, _ = io.ReadAll(w.Result().Body)
+	_ = json.Unmarshal([]byte(body), &result)
+	if result.Level != "warn" {
+		t.Errorf("Expected log level to be read back: level=%s", result.Level)
+	}
+}
+
+func Test_LogLevel_InvalidLevel_Error(t *testing.T) {
+	setupAdminApi(true, "foo")
+
+	// Build the request.
+	request := httptest.NewRequest("POST", "/log/level", strings.NewReader(`{"level":"verbose"}`))
+	request.Header.Add("X-Jwtblock-Api-Key", "foo")
+	w := httptest.NewRecorder()
+
+	// Issue HTTP request to handler.
+	logLevel(w, request)
+
+	// Process the result.
+	response := w.Result()
+	body, _ := io.ReadAll(response.Body)
+	var result StandardResponse
+	err := json.Unmarshal([]byte(body), &result)
+	if err != nil {
+		t.Errorf("Expected request to pass: err=%s", err)
+	}
+	if response.StatusCode != 400 || result.Message != core.ErrInvalidLogLevel.Error() || core.LogLevel() != "info" {
+		t.Errorf(
+			"Expected ErrInvalidLogLevel: status=%d, message='%s', level=%s",
+			response.StatusCode,
+			result.Message,
+			core.LogLevel(),
+		)
+	}
+}
+
+func Test_LogLevel_MissingApiKey_Error(t *testing.T) {
+	setupAdminApi(true, "foo")
+
+	request := httptest.NewRequest("POST", "/log/level", strings.NewReader(`{"level":"debug"}`))
+	w := httptest.NewRecorder()
+	logLevel(w, request)
+
+	if response := w.Result(); response.StatusCode != 401 || core.LogLevel() != "info" {
+		t.Errorf("Expected unauthenticated request to be rejected: status=%d, level=%s", response.StatusCode, core.LogLevel())
+	}
+}
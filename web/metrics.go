@@ -0,0 +1,45 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/swaggest/openapi-go"
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+)
+
+// Handler for /metrics
+func serviceMetrics(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET.
+	if r.Method != http.MethodGet {
+		WriteErrorResponse(r, w, ErrHttpMethodOnlyGet.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !core.GetConfig().HTTP.MetricsEnabled {
+		WriteErrorResponse(r, w, ErrMetricsDisabled.Error(), http.StatusNotFound)
+		return
+	}
+
+	metrics.Handler().ServeHTTP(w, r)
+}
+
+// OpenAPI documentation generation.
+func metricsGenerateOpenAPI(reflector *openapi3.Reflector) {
+	logger := core.GetLogger()
+
+	metricsOp, err := reflector.NewOperationContext(http.MethodGet, "/metrics")
+	if err != nil {
+		logger.Fatalw(err.Error())
+	}
+
+	metricsOp.AddRespStructure(new(map[string]interface{}), func(cu *openapi.ContentUnit) { cu.HTTPStatus = http.StatusOK })
+	metricsOp.AddRespStructure(new(StandardResponse), func(cu *openapi.ContentUnit) { cu.HTTPStatus = http.StatusNotFound })
+
+	err = reflector.AddOperation(metricsOp)
+	if err != nil {
+		logger.Fatalw(err.Error())
+	}
+}
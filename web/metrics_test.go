@@ -0,0 +1,46 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/metrics"
+)
+
+func Test_Metrics_Enabled_Success(t *testing.T) {
+	viper.Set(core.OptStr_HttpMetricsEnabled, true)
+	metrics.Int("test_total").Add(1)
+
+	// Issue HTTP request to handler.
+	request := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	serviceMetrics(w, request)
+
+	// Process the result.
+	response := w.Result()
+	body, _ := io.ReadAll(response.Body)
+	var result map[string]interface{}
+	err := json.Unmarshal(body, &result)
+	if err != nil || response.StatusCode != 200 || result["test_total"] == nil {
+		t.Errorf("Expected metrics: status=%d, body='%s'", response.StatusCode, body)
+	}
+}
+
+func Test_Metrics_Disabled_Error(t *testing.T) {
+	viper.Set(core.OptStr_HttpMetricsEnabled, false)
+	defer viper.Set(core.OptStr_HttpMetricsEnabled, true)
+
+	// Issue HTTP request to handler.
+	request := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	serviceMetrics(w, request)
+
+	if w.Result().StatusCode != 404 {
+		t.Errorf("Unexpected status code: actual=%d, expected=%d", w.Result().StatusCode, 404)
+	}
+}
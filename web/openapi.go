@@ -4,9 +4,13 @@ import (
 	"fmt"
 
 	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/swaggest/openapi-go"
 	"github.com/swaggest/openapi-go/openapi3"
 )
 
+// Name of the security scheme for admin API operations.
+const adminSecurityName = "adminApiKey"
+
 // Generate the OpenAPI spec for the service.
 func GenerateOpenAPI(format string) (string, error) {
 	reflector := openapi3.Reflector{}
@@ -30,10 +34,18 @@ func GenerateOpenAPI(format string) (string, error) {
 	securityName := "bearerToken"
 	reflector.Spec.SetHTTPBearerTokenSecurity(securityName, "JWT", "Access token")
 	reflector.Spec.WithSecurity(map[string][]string{securityName: {}})
+	reflector.Spec.SetAPIKeySecurity(adminSecurityName, core.GetConfig().HTTP.HeaderApiKey, openapi.InHeader, "Admin API key")
 
 	// Endpoints.
 	blockGenerateOpenAPI(&reflector)
 	checkGenerateOpenAPI(&reflector)
+	unblockGenerateOpenAPI(&reflector)
+	listGenerateOpenAPI(&reflector)
+	flushGenerateOpenAPI(&reflector)
+	statusGenerateOpenAPI(&reflector)
+	metricsGenerateOpenAPI(&reflector)
+	readyGenerateOpenAPI(&reflector)
+	logLevelGenerateOpenAPI(&reflector)
 
 	// Dump the schema.
 	var schema []byte
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/swaggest/openapi-go"
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/divergentcodes/jwtblock/internal/cache"
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+// A ReadinessResult contains whether the service is ready to handle requests.
+type ReadinessResult struct {
+	Message string `json:"message"` // message summarizing the result.
+	IsReady bool   `json:"ready"`   // whether or not the blocklist store can be reached.
+	Breaker string `json:"breaker"` // state of the store circuit breaker (closed, open, half-open or disabled).
+	IsError bool   `json:"error"`   // whether or not the result was an error.
+}
+
+// Handler for /health/ready
+func serviceReady(w http.ResponseWriter, r *http.Request) {
+	logger := core.GetLogger()
+
+	// Only allow GET.
+	if r.Method != http.MethodGet {
+		WriteErrorResponse(r, w, ErrHttpMethodOnlyGet.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+
+	result := ReadinessResult{
+		Message: "ready",
+		IsReady: true,
+	}
+	httpStatus := http.StatusOK
+
+	// The ping is rejected while the circuit breaker is open, and probes it while half-open.
+	_, err := cache.IsRedisReady()
+	result.Breaker = cache.BreakerState()
+	if err != nil {
+		logger.Warnw(
+			"service not ready",
+			"func", "web.serviceReady",
+			"breaker", result.Breaker,
+			"err", err.Error(),
+		)
+		result.Message = err.Error()
+		result.IsReady = false
+		result.IsError = true
+		httpStatus = http.StatusServiceUnavailable
+	}
+
+	WriteJSONResponse(r, w, result, httpStatus)
+}
+
+// OpenAPI documentation generation.
+func readyGenerateOpenAPI(reflector *openapi3.Reflector) {
+	logger := core.GetLogger()
+
+	readyOp, err := reflector.NewOperationContext(http.MethodGet, "/health/ready")
+	if err != nil {
+		logger.Fatalw(err.Error())
+	}
+
+	statusCodes := []int{http.StatusOK, http.StatusServiceUnavailable}
+	for _, status := range statusCodes {
+		readyOp.AddRespStructure(new(ReadinessResult), func(cu *openapi.ContentUnit) { cu.HTTPStatus = status })
+	}
+
+	err = reflector.AddOperation(readyOp)
+	if err != nil {
+		logger.Fatalw(err.Error())
+	}
+}
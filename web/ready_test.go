@@ -0,0 +1,49 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/divergentcodes/jwtblock/internal/cache"
+)
+
+func Test_Ready_RedisAvailable_Success(t *testing.T) {
+	setupMockRedis()
+
+	// Issue HTTP request to handler.
+	request := httptest.NewRequest("GET", "/health/ready", nil)
+	w := httptest.NewRecorder()
+	serviceReady(w, request)
+
+	// Process the result.
+	response := w.Result()
+	body, _ := io.ReadAll(response.Body)
+	var result ReadinessResult
+	err := json.Unmarshal(body, &result)
+	if err != nil || response.StatusCode != 200 || !result.IsReady {
+		t.Errorf("Expected service to be ready: status=%d, body='%s'", response.StatusCode, body)
+	}
+
+	teardownMockRedis()
+}
+
+func Test_Ready_RedisUnavailable_Error(t *testing.T) {
+	setupMockRedis()
+	cache.GetRedisClient().Close()
+
+	// Issue HTTP request to handler.
+	request := httptest.NewRequest("GET", "/health/ready", nil)
+	w := httptest.NewRecorder()
+	serviceReady(w, request)
+
+	// Process the result.
+	response := w.Result()
+	body, _ := io.ReadAll(response.Body)
+	var result ReadinessResult
+	err := json.Unmarshal(body, &result)
+	if err != nil || response.StatusCode != 503 || result.IsReady {
+		t.Errorf("Expected service to not be ready: status=%d, body='%s'", response.StatusCode, body)
+	}
+}
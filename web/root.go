@@ -11,7 +11,6 @@ import (
 	"time"
 
 	"github.com/divergentcodes/jwtblock/internal/core"
-	"github.com/spf13/viper"
 )
 
 // General error messages returned by the web service.
@@ -19,6 +18,8 @@ var (
 	ErrHttpMethodOnlyGet  = errors.New("invalid HTTP method. Only GET is allowed")
 	ErrHttpMethodOnlyPost = errors.New("invalid HTTP method. Only POST is allowed")
 
+	ErrHttpMethodOnlyGetPost = errors.New("invalid HTTP method. Only GET and POST are allowed")
+
 	ErrMissingTokenHeader = errors.New("missing HTTP header with token")
 	ErrMissingHashHeader  = errors.New("missing HTTP header with hash")
 
@@ -26,12 +27,21 @@ var (
 	ErrMissingInvalidHash  = errors.New("missing or invalid hash value in request")
 
 	ErrMalformedBearerTokenFormat = errors.New("malformed bearer token format")
+
+	ErrInvalidTTL = errors.New("invalid TTL value in request")
+
+	ErrAdminApiDisabled = errors.New("admin API is disabled")
+	ErrMissingApiKey    = errors.New("missing HTTP header with API key")
+	ErrInvalidApiKey    = errors.New("invalid API key")
+
+	ErrMetricsDisabled = errors.New("metrics are disabled")
 )
 
 // A StandardResponse has the expected fields in a API response body.
 type StandardResponse struct {
-	Message string `json:"message"` // the response message.
-	IsError bool   `json:"error"`   // whether the request resulted in an error.
+	Message   string `json:"message"`              // the response message.
+	IsError   bool   `json:"error"`                // whether the request resulted in an error.
+	RequestID string `json:"request_id,omitempty"` // the ID of the request, also in the request ID response header.
 }
 
 // WriteSuccessResponse writes a HTTP success response with a StandardResponse JSON body.
@@ -39,8 +49,9 @@ func WriteSuccessResponse(r *http.Request, w http.ResponseWriter, message string
 	logger := core.GetLogger()
 
 	data := StandardResponse{
-		Message: message,
-		IsError: false,
+		Message:   message,
+		IsError:   false,
+		RequestID: getRequestID(r),
 	}
 
 	allowed, allowedOrigin := isCorsRequestAllowed(r)
@@ -65,8 +76,9 @@ func WriteErrorResponse(r *http.Request, w http.ResponseWriter, errorMessage str
 	logger := core.GetLogger()
 
 	data := StandardResponse{
-		Message: errorMessage,
-		IsError: true,
+		Message:   errorMessage,
+		IsError:   true,
+		RequestID: getRequestID(r),
 	}
 
 	corsAllowed, allowedOrigin := isCorsRequestAllowed(r)
@@ -86,14 +98,49 @@ func WriteErrorResponse(r *http.Request, w http.ResponseWriter, errorMessage str
 	}
 }
 
+// WriteJSONResponse writes a HTTP response with the given data as the JSON body.
+func WriteJSONResponse(r *http.Request, w http.ResponseWriter, data interface{}, httpStatus int) {
+	logger := core.GetLogger()
+
+	allowed, allowedOrigin := isCorsRequestAllowed(r)
+	if allowed {
+		addCorsResponseHeaders(w, allowedOrigin)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	err := json.NewEncoder(w).Encode(data)
+	if err != nil {
+		logger.Errorw(
+			"failed to JSON encode response data",
+			"func", "WriteJSONResponse",
+			"data", data,
+		)
+	}
+}
+
+// Handler returns the HTTP handler that routes requests to individual handler functions.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/", index)
+	mux.HandleFunc("/blocklist/block", jwtBlock)
+	mux.HandleFunc("/blocklist/check", jwtCheck)
+	mux.HandleFunc("/blocklist/unblock", jwtUnblock)
+	mux.HandleFunc("/blocklist/list", jwtList)
+	mux.HandleFunc("/blocklist/flush", jwtFlush)
+	mux.HandleFunc("/blocklist/status", jwtStatus)
+	mux.HandleFunc("/metrics", serviceMetrics)
+	mux.HandleFunc("/health/ready", serviceReady)
+	mux.HandleFunc("/log/level", logLevel)
+
+	return logRequests(traceRequests(mux))
+}
+
 // HandleRequests starts the HTTP service and routes requests to individual handler functions.
 func HandleRequests(host string, port int) {
 	logger := core.GetLogger()
 
-	http.HandleFunc("/", index)
-	http.HandleFunc("/blocklist/block", jwtBlock)
-	http.HandleFunc("/blocklist/check", jwtCheck)
-
 	logger.Infow(
 		"Serving web API",
 		"func", "HandleRequests",
@@ -104,17 +151,21 @@ func HandleRequests(host string, port int) {
 	var netaddr = fmt.Sprintf("%s:%d", host, port)
 	server := &http.Server{
 		Addr:              netaddr,
+		Handler:           Handler(),
 		ReadHeaderTimeout: 3 * time.Second,
 	}
 
 	logger.Fatal(server.ListenAndServe())
 }
 
-// DebugLogIncomingRequest pretty prints the HTTP request to debug logging
+// DebugLogIncomingRequest pretty prints the HTTP request to debug logging, with credential headers masked.
 func DebugLogIncomingRequest(r *http.Request) {
 	logger := core.GetLogger()
 
+	headers := r.Header
+	r.Header = core.RedactHeaders(headers)
 	prettyReq, err := httputil.DumpRequest(r, true)
+	r.Header = headers
 	if err != nil {
 		logger.Debug(err.Error())
 	} else {
@@ -149,7 +200,7 @@ func parseHashFromHeader(r *http.Request) (string, error) {
 	var hashString string
 
 	// Get the header with the hash.
-	hashHeaderName := http.CanonicalHeaderKey(viper.GetString(core.OptStr_HttpHeaderSha256))
+	hashHeaderName := http.CanonicalHeaderKey(core.GetConfig().HTTP.HeaderSha256)
 	hashHeaderValueList, ok := r.Header[hashHeaderName]
 	if !ok {
 		return hashString, ErrMissingTokenHeader
@@ -1,10 +1,16 @@
 package web
 
 import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
 	"github.com/alicebob/miniredis/v2"
 	"github.com/redis/go-redis/v9"
 
 	"github.com/divergentcodes/jwtblock/internal/cache"
+	"github.com/divergentcodes/jwtblock/internal/core"
 )
 
 func setupMockRedis() {
@@ -20,3 +26,16 @@ func teardownMockRedis() {
 	rc := cache.GetRedisClient()
 	rc.Close()
 }
+
+func Test_DebugLogIncomingRequest_RequestUnchanged_Success(t *testing.T) {
+	core.InitConfigDefaults()
+	req := httptest.NewRequest("POST", "/blocklist/block", strings.NewReader("body"))
+	req.Header.Set("Authorization", "Bearer foobar")
+
+	DebugLogIncomingRequest(req)
+
+	body, _ := io.ReadAll(req.Body)
+	if req.Header.Get("Authorization") != "Bearer foobar" || string(body) != "body" {
+		t.Errorf("Expected the request headers and body to be unchanged: headers=%v, body=%s", req.Header, body)
+	}
+}
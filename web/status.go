@@ -0,0 +1,69 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/swaggest/openapi-go"
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/divergentcodes/jwtblock/internal/blocklist"
+	"github.com/divergentcodes/jwtblock/internal/cache"
+	"github.com/divergentcodes/jwtblock/internal/core"
+)
+
+// Handler for /blocklist/status
+func jwtStatus(w http.ResponseWriter, r *http.Request) {
+	logger := core.GetLogger()
+
+	// Handle CORS preflight requests.
+	if r.Method == http.MethodOptions {
+		WriteCorsPreflightResponse(r, w)
+		return
+	}
+
+	// Only allow GET.
+	if r.Method != http.MethodGet {
+		WriteErrorResponse(r, w, ErrHttpMethodOnlyGet.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !requireAdminRequest(w, r) {
+		return
+	}
+
+	redisDB := cache.GetRedisClient()
+	result, err := blocklist.Status(redisDB)
+	if err != nil {
+		logger.Errorw(
+			"web status error",
+			"func", "web.jwtStatus",
+			"err", err.Error(),
+		)
+		WriteErrorResponse(r, w, blocklist.ErrMisconfiguredCache.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	WriteJSONResponse(r, w, result, http.StatusOK)
+}
+
+// OpenAPI documentation generation.
+func statusGenerateOpenAPI(reflector *openapi3.Reflector) {
+	logger := core.GetLogger()
+
+	statusOp, err := reflector.NewOperationContext(http.MethodGet, "/blocklist/status")
+	if err != nil {
+		logger.Fatalw(err.Error())
+	}
+
+	statusOp.AddRespStructure(new(blocklist.StatusResult), func(cu *openapi.ContentUnit) { cu.HTTPStatus = http.StatusOK })
+	statusOp.AddSecurity(adminSecurityName)
+	statusCodes := []int{http.StatusUnauthorized, http.StatusForbidden}
+	for _, status := range statusCodes {
+		statusOp.AddRespStructure(new(StandardResponse), func(cu *openapi.ContentUnit) { cu.HTTPStatus = status })
+	}
+
+	err = reflector.AddOperation(statusOp)
+	if err != nil {
+		logger.Fatalw(err.Error())
+	}
+}
@@ -0,0 +1,52 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func Test_Handler_TraceContext_Parented(t *testing.T) {
+	setupMockRedis()
+	defer teardownMockRedis()
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	request := httptest.NewRequest("GET", "/blocklist/check", nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", generateTokenStringHS256(30)))
+	request.Header.Add("Traceparent", fmt.Sprintf("00-%s-00f067aa0ba902b7-01", traceID))
+	w := httptest.NewRecorder()
+
+	Handler().ServeHTTP(w, request)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Unexpected status code: actual=%d", w.Code)
+	}
+
+	spans := exporter.GetSpans()
+	names := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range spans.Snapshots() {
+		names[span.Name()] = span
+		if span.SpanContext().TraceID().String() != traceID {
+			t.Errorf("Expected span in the caller's trace: name=%s, traceID=%s", span.Name(), span.SpanContext().TraceID())
+		}
+	}
+	server, check, jwt := names["GET /blocklist/check"], names["blocklist.check"], names["jwt.checks"]
+	if server == nil || check == nil || jwt == nil {
+		t.Fatalf("Expected server, check and JWT spans: spans=%v", names)
+	}
+	if server.SpanKind() != trace.SpanKindServer || check.Parent().SpanID() != server.SpanContext().SpanID() || jwt.Parent().SpanID() != check.SpanContext().SpanID() {
+		t.Errorf("Expected spans to be nested: server=%s, check.parent=%s, jwt.parent=%s", server.SpanContext().SpanID(), check.Parent().SpanID(), jwt.Parent().SpanID())
+	}
+}
@@ -0,0 +1,109 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/swaggest/openapi-go"
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/divergentcodes/jwtblock/internal/audit"
+	"github.com/divergentcodes/jwtblock/internal/blocklist"
+	"github.com/divergentcodes/jwtblock/internal/cache"
+	"github.com/divergentcodes/jwtblock/internal/core"
+	"github.com/divergentcodes/jwtblock/internal/crypto"
+)
+
+// Handler for /blocklist/unblock
+func jwtUnblock(w http.ResponseWriter, r *http.Request) {
+	logger := core.GetLogger()
+	var result *blocklist.UnblockResult
+	var err, tokenErr, hashErr error
+
+	// Handle CORS preflight requests.
+	if r.Method == http.MethodOptions {
+		WriteCorsPreflightResponse(r, w)
+		return
+	}
+
+	// Only allow POST.
+	if r.Method != http.MethodPost {
+		WriteErrorResponse(r, w, ErrHttpMethodOnlyPost.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !requireAdminRequest(w, r) {
+		return
+	}
+
+	// Get token or hash from headers.
+	var tokenString, hashString string
+	tokenString, tokenErr = parseTokenFromHeader(r)
+	if tokenErr != nil {
+		hashString, hashErr = parseHashFromHeader(r)
+	}
+
+	// No token or hash found in headers.
+	if tokenErr != nil && hashErr != nil {
+		msg := "failed to get token or hash from request headers"
+		logger.Errorw(
+			msg,
+			"func", "web.jwtUnblock",
+			"tokenError", tokenErr.Error(),
+			"hashError", hashErr.Error(),
+		)
+		WriteErrorResponse(r, w, msg, http.StatusBadRequest)
+		return
+	}
+
+	// Remove value from the blocklist.
+	redisDB := cache.GetRedisClient()
+	if tokenString != "" {
+		setRequestToken(r, tokenString)
+		result, err = blocklist.UnblockByJwt(redisDB, tokenString)
+		audit.Unblock(newAuditEvent(r, crypto.TokenHash(tokenString), ""), result, err)
+	} else {
+		setRequestHash(r, hashString)
+		result, err = blocklist.UnblockBySha256(redisDB, hashString)
+		audit.Unblock(newAuditEvent(r, hashString, ""), result, err)
+	}
+	if err != nil {
+		// Error is either token format (400), or an unavailable store (503).
+		httpStatus := http.StatusBadRequest
+		if errors.Is(err, blocklist.ErrStoreUnavailable) {
+			httpStatus = http.StatusServiceUnavailable
+		}
+		WriteErrorResponse(r, w, err.Error(), httpStatus)
+		return
+	}
+
+	// Response.
+	if result.IsUnblocked {
+		setRequestOutcome(r, outcomeUnblocked)
+	} else {
+		setRequestOutcome(r, outcomeNotBlocked)
+	}
+	WriteJSONResponse(r, w, result, http.StatusOK)
+}
+
+// OpenAPI documentation generation.
+func unblockGenerateOpenAPI(reflector *openapi3.Reflector) {
+	logger := core.GetLogger()
+
+	unblockOp, err := reflector.NewOperationContext(http.MethodPost, "/blocklist/unblock")
+	if err != nil {
+		logger.Fatalw(err.Error())
+	}
+
+	unblockOp.AddRespStructure(new(blocklist.UnblockResult), func(cu *openapi.ContentUnit) { cu.HTTPStatus = http.StatusOK })
+	unblockOp.AddSecurity(adminSecurityName)
+	statusCodes := []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusServiceUnavailable}
+	for _, status := range statusCodes {
+		unblockOp.AddRespStructure(new(StandardResponse), func(cu *openapi.ContentUnit) { cu.HTTPStatus = status })
+	}
+
+	err = reflector.AddOperation(unblockOp)
+	if err != nil {
+		logger.Fatalw(err.Error())
+	}
+}